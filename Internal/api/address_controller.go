@@ -0,0 +1,241 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// AddressController manages the caller's address book.
+type AddressController struct {
+	db *gorm.DB
+}
+
+func NewAddressController(db *gorm.DB) *AddressController {
+	return &AddressController{db: db}
+}
+
+type PublicAddress struct {
+	ID    uint   `json:"id"`
+	Label string `json:"label"`
+	PublicPostalAddress
+	IsDefaultShipping bool      `json:"is_default_shipping"`
+	IsDefaultBilling  bool      `json:"is_default_billing"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func toPublicAddress(address models.Address) PublicAddress {
+	return PublicAddress{
+		ID:                  address.ID,
+		Label:               address.Label,
+		PublicPostalAddress: toPublicPostalAddress(address.PostalAddress),
+		IsDefaultShipping:   address.IsDefaultShipping,
+		IsDefaultBilling:    address.IsDefaultBilling,
+		CreatedAt:           address.CreatedAt,
+	}
+}
+
+func (c *AddressController) handleGetAddresses(ctx *gin.Context) {
+	userID, _ := ctx.Get("userID")
+
+	var addresses []models.Address
+	if err := c.db.Where("user_id = ?", userID).Order("id").Find(&addresses).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch addresses"})
+		return
+	}
+
+	publicAddresses := make([]PublicAddress, len(addresses))
+	for i, address := range addresses {
+		publicAddresses[i] = toPublicAddress(address)
+	}
+	ctx.JSON(http.StatusOK, publicAddresses)
+}
+
+func (c *AddressController) handleGetAddress(ctx *gin.Context) {
+	address, ok := c.loadAddress(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicAddress(*address))
+}
+
+// handleCreateAddress adds an address. The first address in the book becomes
+// the default for both shipping and billing.
+func (c *AddressController) handleCreateAddress(ctx *gin.Context) {
+	payload, ok := bindAddressPayload(ctx)
+	if !ok {
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	address := models.Address{UserID: userID.(uint)}
+	applyAddressPayload(&address, payload)
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Address{}).Where("user_id = ?", address.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			address.IsDefaultShipping = true
+			address.IsDefaultBilling = true
+		}
+		if err := tx.Create(&address).Error; err != nil {
+			return err
+		}
+		return clearOtherDefaults(tx, &address)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, toPublicAddress(address))
+}
+
+// handleUpdateAddress replaces an address. Defaults can be moved to this
+// address but not cleared; they move when another address takes them.
+// Orders keep the snapshot they were placed with.
+func (c *AddressController) handleUpdateAddress(ctx *gin.Context) {
+	address, ok := c.loadAddress(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := bindAddressPayload(ctx)
+	if !ok {
+		return
+	}
+
+	isDefaultShipping, isDefaultBilling := address.IsDefaultShipping, address.IsDefaultBilling
+	applyAddressPayload(address, payload)
+	address.IsDefaultShipping = address.IsDefaultShipping || isDefaultShipping
+	address.IsDefaultBilling = address.IsDefaultBilling || isDefaultBilling
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(address).Error; err != nil {
+			return err
+		}
+		return clearOtherDefaults(tx, address)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update address"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicAddress(*address))
+}
+
+// handleDeleteAddress removes an address. A default it held passes to the
+// oldest remaining address.
+func (c *AddressController) handleDeleteAddress(ctx *gin.Context) {
+	address, ok := c.loadAddress(ctx)
+	if !ok {
+		return
+	}
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(address).Error; err != nil {
+			return err
+		}
+		for column, wasDefault := range map[string]bool{
+			"is_default_shipping": address.IsDefaultShipping,
+			"is_default_billing":  address.IsDefaultBilling,
+		} {
+			if !wasDefault {
+				continue
+			}
+			var next models.Address
+			err := tx.Where("user_id = ?", address.UserID).Order("id").First(&next).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&next).Update(column, true).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete address"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Address deleted successfully"})
+}
+
+// loadAddress fetches the :id address from the caller's book. On failure it
+// writes the response and returns false.
+func (c *AddressController) loadAddress(ctx *gin.Context) (*models.Address, bool) {
+	addressID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || addressID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return nil, false
+	}
+
+	userID, _ := ctx.Get("userID")
+	var address models.Address
+	if err := c.db.Where("user_id = ?", userID).First(&address, addressID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch address"})
+		return nil, false
+	}
+	return &address, true
+}
+
+// bindAddressPayload binds the payload and checks it against the country's
+// address rules. On failure it writes the response and returns false.
+func bindAddressPayload(ctx *gin.Context) (models.AddressPayload, bool) {
+	var payload models.AddressPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return payload, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return payload, false
+	}
+
+	if problems := services.ValidatePostalAddress(payload.PostalAddressPayload.PostalAddress()); problems != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": problems})
+		return payload, false
+	}
+	return payload, true
+}
+
+func applyAddressPayload(address *models.Address, payload models.AddressPayload) {
+	address.Label = payload.Label
+	address.PostalAddress = payload.PostalAddressPayload.PostalAddress()
+	address.IsDefaultShipping = payload.IsDefaultShipping
+	address.IsDefaultBilling = payload.IsDefaultBilling
+}
+
+// clearOtherDefaults unsets the defaults held by address on the rest of the
+// user's book, so each user has at most one of each.
+func clearOtherDefaults(tx *gorm.DB, address *models.Address) error {
+	others := tx.Model(&models.Address{}).Where("user_id = ? AND id <> ?", address.UserID, address.ID)
+	if address.IsDefaultShipping {
+		if err := others.Session(&gorm.Session{}).Update("is_default_shipping", false).Error; err != nil {
+			return err
+		}
+	}
+	if address.IsDefaultBilling {
+		if err := others.Session(&gorm.Session{}).Update("is_default_billing", false).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
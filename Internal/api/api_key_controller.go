@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type APIKeyController struct {
+	service *services.APIKeyService
+}
+
+func NewAPIKeyController(service *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{service: service}
+}
+
+type PublicAPIKey struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func toPublicAPIKey(key models.APIKey) PublicAPIKey {
+	return PublicAPIKey{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		LastUsedAt: key.LastUsedAt,
+		ExpiresAt:  key.ExpiresAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+func (c *APIKeyController) handleGetAPIKeys(ctx *gin.Context) {
+	userID, _ := ctx.Get("userID")
+	keys, err := c.service.ListKeys(userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	publicKeys := make([]PublicAPIKey, len(keys))
+	for i, key := range keys {
+		publicKeys[i] = toPublicAPIKey(key)
+	}
+	ctx.JSON(http.StatusOK, publicKeys)
+}
+
+// handleCreateAPIKey returns the full key exactly once.
+func (c *APIKeyController) handleCreateAPIKey(ctx *gin.Context) {
+	if rejectImpersonation(ctx) {
+		return
+	}
+	var payload models.APIKeyPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	key, plain, err := c.service.CreateKey(ctx, userID.(uint), payload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Store this key now, it will not be shown again",
+		"key":     plain,
+		"api_key": toPublicAPIKey(*key),
+	})
+}
+
+func (c *APIKeyController) handleRevokeAPIKey(ctx *gin.Context) {
+	keyID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || keyID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	if err := c.service.RevokeKey(ctx, userID.(uint), uint(keyID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
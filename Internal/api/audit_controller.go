@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+type AuditController struct {
+	db *gorm.DB
+}
+
+func NewAuditController(db *gorm.DB) *AuditController {
+	return &AuditController{db: db}
+}
+
+type PublicAuditEvent struct {
+	ID             uint            `json:"id"`
+	ActorID        *uint           `json:"actor_id"`
+	ImpersonatorID *uint           `json:"impersonator_id,omitempty"`
+	Action         string          `json:"action"`
+	EntityType     string          `json:"entity_type"`
+	EntityID       uint            `json:"entity_id"`
+	Before         json.RawMessage `json:"before,omitempty"`
+	After          json.RawMessage `json:"after,omitempty"`
+	IPAddress      string          `json:"ip_address"`
+	RequestID      string          `json:"request_id"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// handleGetAuditEvents lists audit events, newest first. Supported filters:
+// actor_id, action, entity_type, entity_id, request_id, from and to (RFC 3339),
+// plus limit/offset for paging.
+func (c *AuditController) handleGetAuditEvents(ctx *gin.Context) {
+	query := c.db.Model(&models.AuditEvent{})
+
+	for param, column := range map[string]string{
+		"actor_id":    "actor_id",
+		"action":      "action",
+		"entity_type": "entity_type",
+		"entity_id":   "entity_id",
+		"request_id":  "request_id",
+	} {
+		if value := ctx.Query(param); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+
+	if from := ctx.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' timestamp, expected RFC 3339"})
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := ctx.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' timestamp, expected RFC 3339"})
+			return
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultAuditPageSize)))
+	if err != nil || limit <= 0 || limit > maxAuditPageSize {
+		limit = defaultAuditPageSize
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	var events []models.AuditEvent
+	result := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events)
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+		return
+	}
+
+	publicEvents := make([]PublicAuditEvent, len(events))
+	for i, event := range events {
+		publicEvents[i] = PublicAuditEvent{
+			ID:             event.ID,
+			ActorID:        event.ActorID,
+			ImpersonatorID: event.ImpersonatorID,
+			Action:         event.Action,
+			EntityType:     event.EntityType,
+			EntityID:       event.EntityID,
+			IPAddress:      event.IPAddress,
+			RequestID:      event.RequestID,
+			CreatedAt:      event.CreatedAt,
+		}
+		if event.Before != "" {
+			publicEvents[i].Before = json.RawMessage(event.Before)
+		}
+		if event.After != "" {
+			publicEvents[i].After = json.RawMessage(event.After)
+		}
+	}
+	ctx.JSON(http.StatusOK, publicEvents)
+}
@@ -1,11 +1,16 @@
 package api
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -17,6 +22,36 @@ var jwtKey = []byte("my_super_secret_key")
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Role   string `json:"role"`
+	// ImpersonatorID is the real admin behind an impersonation token.
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+	ReadOnly       bool `json:"read_only,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// impersonationTTL is how long an impersonation token stays valid.
+const impersonationTTL = 10 * time.Minute
+
+// Guest tokens are signed with their own key, so AuthMiddleware never
+// mistakes one for a signed-in user. They are sent in the X-Guest-Token
+// header or the guest_token cookie.
+var guestTokenKey = append([]byte("guest:"), jwtKey...)
+
+const (
+	guestTokenHeader = "X-Guest-Token"
+	guestTokenCookie = "guest_token"
+	guestTokenTTL    = 30 * 24 * time.Hour
+)
+
+// Order claim codes are mailed to a guest order's email and prove that the
+// account claiming the order can read that mailbox.
+var orderClaimKey = append([]byte("order-claim:"), jwtKey...)
+
+const orderClaimTTL = time.Hour
+
+// OrderClaimClaims lets UserID attach the guest order OrderID to their account.
+type OrderClaimClaims struct {
+	OrderID uint `json:"order_id"`
+	UserID  uint `json:"user_id"`
 	jwt.RegisteredClaims
 }
 
@@ -51,9 +86,124 @@ func GenerateRefreshToken(userID uint, role string) (string, error) {
 	return token.SignedString(jwtKey)
 }
 
-// AuthMiddleware is a Gin middleware for validating JWTs.
-func AuthMiddleware() gin.HandlerFunc {
+// GenerateImpersonationToken creates a short-lived access token that acts as
+// userID while remembering the admin who requested it.
+func GenerateImpersonationToken(userID uint, role string, adminID uint, readOnly bool) (string, time.Time, error) {
+	expirationTime := time.Now().Add(impersonationTTL)
+	claims := &Claims{
+		UserID:         userID,
+		Role:           role,
+		ImpersonatorID: adminID,
+		ReadOnly:       readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(jwtKey)
+	return signed, expirationTime, err
+}
+
+// GenerateGuestToken creates a token for shopping without an account. The
+// guest ID in its subject is what the guest's carts belong to; an empty
+// guestID starts a new guest.
+func GenerateGuestToken(guestID string) (string, string, time.Time, error) {
+	if guestID == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return "", "", time.Time{}, err
+		}
+		guestID = hex.EncodeToString(buf)
+	}
+	expirationTime := time.Now().Add(guestTokenTTL)
+	claims := &jwt.RegisteredClaims{
+		Subject:   guestID,
+		ExpiresAt: jwt.NewNumericDate(expirationTime),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(guestTokenKey)
+	return signed, guestID, expirationTime, err
+}
+
+// GenerateOrderClaimCode creates the code that lets userID claim the guest
+// order orderID.
+func GenerateOrderClaimCode(orderID, userID uint) (string, error) {
+	claims := &OrderClaimClaims{
+		OrderID: orderID,
+		UserID:  userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(orderClaimTTL)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(orderClaimKey)
+}
+
+// parseOrderClaimCode returns the order a valid claim code is for, if it
+// was issued to userID.
+func parseOrderClaimCode(code string, userID uint) (uint, bool) {
+	claims := &OrderClaimClaims{}
+	token, err := jwt.ParseWithClaims(code, claims, func(token *jwt.Token) (interface{}, error) {
+		return orderClaimKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || claims.UserID != userID {
+		return 0, false
+	}
+	return claims.OrderID, true
+}
+
+// guestFromRequest returns the guest ID of a valid guest token sent with the
+// request, or "" if there is none.
+func guestFromRequest(c *gin.Context) string {
+	tokenString := c.GetHeader(guestTokenHeader)
+	if tokenString == "" {
+		tokenString, _ = c.Cookie(guestTokenCookie)
+	}
+	if tokenString == "" {
+		return ""
+	}
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return guestTokenKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return ""
+	}
+	return claims.Subject
+}
+
+// apiKeyResources lists the route prefixes API keys may reach and the scope
+// needed to read or write them. Every other route is closed to API keys.
+var apiKeyResources = []struct {
+	prefix string
+	read   models.APIKeyScope
+	write  models.APIKeyScope
+}{
+	{"/api/products", models.CatalogReadScope, models.CatalogWriteScope},
+	{"/api/product_images", models.CatalogReadScope, models.CatalogWriteScope},
+	// Shop orders and money are matched before the rest of the shop routes.
+	{"/api/shops/:id/orders", models.OrdersReadScope, models.OrdersWriteScope},
+	{"/api/shops/:id/balance", models.OrdersReadScope, models.OrdersWriteScope},
+	{"/api/shops/:id/statement", models.OrdersReadScope, models.OrdersWriteScope},
+	{"/api/shops/:id/payouts", models.OrdersReadScope, models.OrdersWriteScope},
+	{"/api/shops", models.CatalogReadScope, models.CatalogWriteScope},
+	{"/api/coupons", models.CatalogReadScope, models.CatalogWriteScope},
+	{"/api/price-rules", models.CatalogReadScope, models.CatalogWriteScope},
+	{"/api/orders", models.OrdersReadScope, models.OrdersWriteScope},
+	{"/api/returns", models.OrdersReadScope, models.OrdersWriteScope},
+}
+
+// AuthMiddleware is a Gin middleware for validating JWTs. It also accepts
+// API keys, either in the X-API-Key header or as a Bearer token.
+func AuthMiddleware(apiKeys *services.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, apiKeys, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -68,6 +218,11 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(tokenString, services.APIKeyPrefix) {
+			authenticateAPIKey(c, apiKeys, tokenString)
+			return
+		}
+
 		claims := &Claims{}
 		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 			return jwtKey, nil
@@ -79,6 +234,22 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if claims.ImpersonatorID != 0 {
+			// Flag every impersonated response so it can't be mistaken for the real user.
+			c.Header("X-Impersonated-By", strconv.FormatUint(uint64(claims.ImpersonatorID), 10))
+			if claims.ReadOnly && !isSafeMethod(c.Request.Method) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Impersonation session is read-only"})
+				c.Abort()
+				return
+			}
+			slog.Info("impersonated request",
+				"admin_id", claims.ImpersonatorID,
+				"user_id", claims.UserID,
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path)
+			c.Set("impersonatorID", claims.ImpersonatorID)
+		}
+
 		// Set the user ID in the context for downstream handlers to use.
 		c.Set("userID", claims.UserID)
 		c.Set("role", claims.Role)
@@ -86,6 +257,98 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// rejectImpersonation refuses account actions an impersonating admin must not
+// take for the user, such as minting credentials that outlive the session.
+// It writes the 403 itself and reports whether it did.
+func rejectImpersonation(c *gin.Context) bool {
+	if _, impersonated := c.Get("impersonatorID"); !impersonated {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a user"})
+	return true
+}
+
+// OptionalAuthMiddleware authenticates the caller like AuthMiddleware when
+// credentials are sent and lets anonymous requests through otherwise.
+func OptionalAuthMiddleware(apiKeys *services.APIKeyService) gin.HandlerFunc {
+	authenticate := AuthMiddleware(apiKeys)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && c.GetHeader("X-API-Key") == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// CustomerMiddleware lets guests through as well as signed-in users. A caller
+// with credentials is authenticated like AuthMiddleware; otherwise a guest
+// token is required and its guest ID is set as guestID, with no userID or
+// role.
+func CustomerMiddleware(apiKeys *services.APIKeyService) gin.HandlerFunc {
+	authenticate := AuthMiddleware(apiKeys)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" || c.GetHeader("X-API-Key") != "" {
+			authenticate(c)
+			return
+		}
+		guestID := guestFromRequest(c)
+		if guestID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in or start a guest session"})
+			c.Abort()
+			return
+		}
+		c.Set("guestID", guestID)
+		c.Next()
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys *services.APIKeyService, plain string) {
+	if apiKeys == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API keys are not accepted here"})
+		c.Abort()
+		return
+	}
+
+	key, user, err := apiKeys.Authenticate(plain)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return
+	}
+
+	scope, allowed := requiredAPIKeyScope(c)
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot access this endpoint"})
+		c.Abort()
+		return
+	}
+	if !key.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing the " + string(scope) + " scope"})
+		c.Abort()
+		return
+	}
+
+	// API keys act as their owner with the owner's current role.
+	c.Set("userID", user.ID)
+	c.Set("role", user.Role)
+	c.Set("apiKeyID", key.ID)
+	c.Next()
+}
+
+func requiredAPIKeyScope(c *gin.Context) (models.APIKeyScope, bool) {
+	path := c.FullPath()
+	for _, resource := range apiKeyResources {
+		if strings.HasPrefix(path, resource.prefix) {
+			if isSafeMethod(c.Request.Method) {
+				return resource.read, true
+			}
+			return resource.write, true
+		}
+	}
+	return "", false
+}
+
 func RoleMiddleware(requiredRole models.Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
@@ -104,3 +367,7 @@ func RoleMiddleware(requiredRole models.Role) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
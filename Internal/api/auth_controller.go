@@ -1,6 +1,7 @@
 package api
 
 import (
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -14,10 +15,11 @@ import (
 
 type AuthController struct {
 	service *services.UserService
+	orders  *services.OrderService
 }
 
-func NewAuthController(db *services.UserService) *AuthController {
-	return &AuthController{service: db}
+func NewAuthController(db *services.UserService, orders *services.OrderService) *AuthController {
+	return &AuthController{service: db, orders: orders}
 }
 
 // handleRegisterUser now takes a *gin.Context.
@@ -31,7 +33,7 @@ func (c *AuthController) handleRegisterUser(ctx *gin.Context) {
 		return
 	}
 
-	user, err := c.service.RegisterUser(payload)
+	user, err := c.service.RegisterUser(ctx, payload)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
@@ -43,8 +45,15 @@ func (c *AuthController) handleRegisterUser(ctx *gin.Context) {
 		return
 	}
 
+	// A guest registering takes their guest orders along. The account exists
+	// by now, so a failure here only leaves the orders to be claimed later.
+	claimed, err := claimGuestSession(ctx, c.orders, user.ID)
+	if err != nil {
+		slog.Error("failed to claim guest orders", "user_id", user.ID, "error", err)
+	}
+
 	// Use ctx.JSON() to send a response. gin.H is a shortcut for map[string]interface{}.
-	ctx.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "user": user})
+	ctx.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "user": user, "claimed_orders": claimed})
 }
 
 func (c *AuthController) handleLogin(ctx *gin.Context) {
@@ -73,7 +82,7 @@ func (c *AuthController) handleLogin(ctx *gin.Context) {
 		return
 	}
 
-	c.service.SetRefreshToken(user, string(refreshToken))
+	c.service.SetRefreshToken(ctx, user, string(refreshToken))
 
 	ctx.JSON(http.StatusOK, gin.H{"access_token": tokenString, "refresh_token": refreshToken})
 }
@@ -127,7 +136,7 @@ func (c *AuthController) handleRefreshToken(ctx *gin.Context) {
 	}
 
 	// Update the database with the new refresh token.
-	c.service.SetRefreshToken(user, newRefreshToken)
+	c.service.SetRefreshToken(ctx, user, newRefreshToken)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"access_token":  newAccessToken,
@@ -150,6 +159,6 @@ func (c *AuthController) handleLogout(ctx *gin.Context) {
 		return
 	}
 
-	c.service.SetRefreshToken(user, "")
+	c.service.SetRefreshToken(ctx, user, "")
 	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully logged out"})
 }
@@ -49,7 +49,7 @@ func (c *CategoryController) handleCreateCategory(ctx *gin.Context) {
 		Description: payload.Description,
 	}
 
-	if result := c.db.Create(&category); result.Error != nil {
+	if result := c.db.WithContext(ctx).Create(&category); result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
 		return
 	}
@@ -85,7 +85,7 @@ func (c *CategoryController) handleUpdateCategory(ctx *gin.Context) {
 		category.Description = payload.Description
 	}
 
-	if result := c.db.Save(&category); result.Error != nil {
+	if result := c.db.WithContext(ctx).Save(&category); result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
 		return
 	}
@@ -102,7 +102,7 @@ func (c *CategoryController) handleUpdateCategory(ctx *gin.Context) {
 // handleDeleteCategory deletes a category from the database.
 func (c *CategoryController) handleDeleteCategory(ctx *gin.Context) {
 	categoryID := ctx.Param("id")
-	if result := c.db.Delete(&models.Category{}, categoryID); result.Error != nil {
+	if result := c.db.WithContext(ctx).Delete(&models.Category{}, categoryID); result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
 		return
 	}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// CommissionRateController lets admins set the platform's commission per
+// shop, per category or per shop and category.
+type CommissionRateController struct {
+	db *gorm.DB
+}
+
+func NewCommissionRateController(db *gorm.DB) *CommissionRateController {
+	return &CommissionRateController{db: db}
+}
+
+type PublicCommissionRate struct {
+	ID         uint    `json:"id"`
+	ShopID     *uint   `json:"shop_id"`
+	CategoryID *uint   `json:"category_id"`
+	Percent    float64 `json:"percent"`
+}
+
+func toPublicCommissionRate(rate models.CommissionRate) PublicCommissionRate {
+	return PublicCommissionRate{
+		ID:         rate.ID,
+		ShopID:     rate.ShopID,
+		CategoryID: rate.CategoryID,
+		Percent:    rate.Percent,
+	}
+}
+
+func (c *CommissionRateController) handleGetCommissionRates(ctx *gin.Context) {
+	var rates []models.CommissionRate
+	if err := c.db.Order("shop_id NULLS FIRST, category_id NULLS FIRST").Find(&rates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch commission rates"})
+		return
+	}
+
+	publicRates := make([]PublicCommissionRate, len(rates))
+	for i, rate := range rates {
+		publicRates[i] = toPublicCommissionRate(rate)
+	}
+	ctx.JSON(http.StatusOK, publicRates)
+}
+
+// handleCreateCommissionRate adds a rate. Orders captured from then on are
+// charged it; posted commission is not recalculated.
+func (c *CommissionRateController) handleCreateCommissionRate(ctx *gin.Context) {
+	payload, ok := c.bindCommissionRatePayload(ctx, 0)
+	if !ok {
+		return
+	}
+
+	rate := models.CommissionRate{ShopID: payload.ShopID, CategoryID: payload.CategoryID, Percent: payload.Percent}
+	if err := c.db.WithContext(ctx).Create(&rate).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create commission rate"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, toPublicCommissionRate(rate))
+}
+
+func (c *CommissionRateController) handleUpdateCommissionRate(ctx *gin.Context) {
+	rate, ok := c.loadCommissionRate(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := c.bindCommissionRatePayload(ctx, rate.ID)
+	if !ok {
+		return
+	}
+
+	rate.ShopID = payload.ShopID
+	rate.CategoryID = payload.CategoryID
+	rate.Percent = payload.Percent
+	if err := c.db.WithContext(ctx).Save(rate).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update commission rate"})
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicCommissionRate(*rate))
+}
+
+func (c *CommissionRateController) handleDeleteCommissionRate(ctx *gin.Context) {
+	rate, ok := c.loadCommissionRate(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.db.WithContext(ctx).Delete(rate).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete commission rate"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Commission rate deleted successfully"})
+}
+
+func (c *CommissionRateController) loadCommissionRate(ctx *gin.Context) (*models.CommissionRate, bool) {
+	rateID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || rateID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid commission rate ID"})
+		return nil, false
+	}
+
+	var rate models.CommissionRate
+	if err := c.db.First(&rate, rateID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Commission rate not found"})
+		return nil, false
+	}
+	return &rate, true
+}
+
+// bindCommissionRatePayload binds a rate, checks the shop and category exist
+// and makes sure there is at most one rate per shop and category. A rate
+// with neither would shadow the platform default, which is set in the
+// environment instead.
+func (c *CommissionRateController) bindCommissionRatePayload(ctx *gin.Context, rateID uint) (*models.CommissionRatePayload, bool) {
+	var payload models.CommissionRatePayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return nil, false
+	}
+	if payload.ShopID == nil && payload.CategoryID == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A commission rate needs a shop, a category or both"})
+		return nil, false
+	}
+	if payload.ShopID != nil {
+		if err := c.db.First(&models.Shop{}, *payload.ShopID).Error; err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Shop not found"})
+			return nil, false
+		}
+	}
+	if payload.CategoryID != nil {
+		if err := c.db.First(&models.Category{}, *payload.CategoryID).Error; err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
+			return nil, false
+		}
+	}
+
+	query := c.db.Model(&models.CommissionRate{}).Where("id <> ?", rateID)
+	if payload.ShopID != nil {
+		query = query.Where("shop_id = ?", *payload.ShopID)
+	} else {
+		query = query.Where("shop_id IS NULL")
+	}
+	if payload.CategoryID != nil {
+		query = query.Where("category_id = ?", *payload.CategoryID)
+	} else {
+		query = query.Where("category_id IS NULL")
+	}
+	var count int64
+	query.Count(&count)
+	if count > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A commission rate for this shop and category already exists"})
+		return nil, false
+	}
+	return &payload, true
+}
@@ -0,0 +1,336 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type CouponController struct {
+	db *gorm.DB
+}
+
+func NewCouponController(db *gorm.DB) *CouponController {
+	return &CouponController{db: db}
+}
+
+type PublicCoupon struct {
+	ID            uint       `json:"id"`
+	Code          string     `json:"code"`
+	Description   string     `json:"description"`
+	Type          string     `json:"type"`
+	Value         float64    `json:"value"`
+	Scope         string     `json:"scope"`
+	ScopeID       *uint      `json:"scope_id"`
+	ShopID        *uint      `json:"shop_id"`
+	MinOrderValue float64    `json:"min_order_value"`
+	UsageLimit    *int       `json:"usage_limit"`
+	PerUserLimit  *int       `json:"per_user_limit"`
+	TimesUsed     int64      `json:"times_used"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	Stackable     bool       `json:"stackable"`
+	Active        bool       `json:"active"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func toPublicCoupon(coupon models.Coupon, timesUsed int64) PublicCoupon {
+	return PublicCoupon{
+		ID:            coupon.ID,
+		Code:          coupon.Code,
+		Description:   coupon.Description,
+		Type:          coupon.Type,
+		Value:         coupon.Value,
+		Scope:         coupon.Scope,
+		ScopeID:       coupon.ScopeID,
+		ShopID:        coupon.ShopID,
+		MinOrderValue: coupon.MinOrderValue,
+		UsageLimit:    coupon.UsageLimit,
+		PerUserLimit:  coupon.PerUserLimit,
+		TimesUsed:     timesUsed,
+		StartsAt:      coupon.StartsAt,
+		EndsAt:        coupon.EndsAt,
+		Stackable:     coupon.Stackable,
+		Active:        coupon.Active,
+		CreatedAt:     coupon.CreatedAt,
+	}
+}
+
+// handleGetCoupons lists every coupon for admins and the shop's own coupons
+// for sellers.
+func (c *CouponController) handleGetCoupons(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	query := c.db.Order("id DESC")
+	if models.Role(user.Role) != models.AdminRole {
+		query = query.Where("shop_id = ?", user.Shop.ID)
+	}
+
+	var coupons []models.Coupon
+	if err := query.Find(&coupons).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coupons"})
+		return
+	}
+
+	var usage []struct {
+		CouponID uint
+		Count    int64
+	}
+	c.db.Model(&models.CouponRedemption{}).Select("coupon_id, COUNT(*) AS count").
+		Where("pending = ?", false).Group("coupon_id").Scan(&usage)
+	timesUsed := make(map[uint]int64, len(usage))
+	for _, row := range usage {
+		timesUsed[row.CouponID] = row.Count
+	}
+
+	publicCoupons := make([]PublicCoupon, len(coupons))
+	for i, coupon := range coupons {
+		publicCoupons[i] = toPublicCoupon(coupon, timesUsed[coupon.ID])
+	}
+	ctx.JSON(http.StatusOK, publicCoupons)
+}
+
+func (c *CouponController) handleGetCoupon(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+	coupon, ok := c.loadCoupon(ctx, user)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicCoupon(*coupon, c.timesUsed(coupon.ID)))
+}
+
+func (c *CouponController) handleCreateCoupon(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	payload, ok := c.bindCouponPayload(ctx, user, 0)
+	if !ok {
+		return
+	}
+
+	coupon := models.Coupon{}
+	applyCouponPayload(&coupon, payload)
+	if models.Role(user.Role) != models.AdminRole {
+		coupon.ShopID = &user.Shop.ID
+	}
+
+	if err := c.db.WithContext(ctx).Create(&coupon).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, toPublicCoupon(coupon, 0))
+}
+
+// handleUpdateCoupon replaces the coupon's settings. Orders already using the
+// coupon pick up the change the next time they are repriced.
+func (c *CouponController) handleUpdateCoupon(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+	coupon, ok := c.loadCoupon(ctx, user)
+	if !ok {
+		return
+	}
+
+	payload, ok := c.bindCouponPayload(ctx, user, coupon.ID)
+	if !ok {
+		return
+	}
+
+	applyCouponPayload(coupon, payload)
+	if err := c.db.WithContext(ctx).Save(coupon).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coupon"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicCoupon(*coupon, c.timesUsed(coupon.ID)))
+}
+
+func (c *CouponController) handleDeleteCoupon(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+	coupon, ok := c.loadCoupon(ctx, user)
+	if !ok {
+		return
+	}
+
+	if err := c.db.WithContext(ctx).Delete(coupon).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Coupon deleted successfully"})
+}
+
+// loadPromotionManager returns the caller if they may manage coupons and
+// price rules, i.e. they are an admin or own a shop. It writes the error
+// response itself.
+func loadPromotionManager(ctx *gin.Context, db *gorm.DB) (*models.User, bool) {
+	var user models.User
+	userID, _ := ctx.Get("userID")
+	if err := db.Preload("Shop").First(&user, userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if models.Role(user.Role) != models.AdminRole && user.Shop.ID == 0 {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only admins and shop owners can manage promotions"})
+		return nil, false
+	}
+	return &user, true
+}
+
+func (c *CouponController) loadCoupon(ctx *gin.Context, user *models.User) (*models.Coupon, bool) {
+	couponID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || couponID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return nil, false
+	}
+
+	var coupon models.Coupon
+	if err := c.db.First(&coupon, couponID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return nil, false
+	}
+	if models.Role(user.Role) != models.AdminRole && (coupon.ShopID == nil || *coupon.ShopID != user.Shop.ID) {
+		// Don't reveal other shops' coupons.
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return nil, false
+	}
+	return &coupon, true
+}
+
+// bindCouponPayload binds and checks a coupon payload.
+func (c *CouponController) bindCouponPayload(ctx *gin.Context, user *models.User, couponID uint) (*models.CouponPayload, bool) {
+	var payload models.CouponPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return nil, false
+	}
+	payload.Code = services.NormalizeCouponCode(payload.Code)
+
+	fail := func(message string) (*models.CouponPayload, bool) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return nil, false
+	}
+
+	switch models.CouponType(payload.Type) {
+	case models.PercentageCoupon:
+		if payload.Value <= 0 || payload.Value > 100 {
+			return fail("Percentage coupons need a value between 0 and 100")
+		}
+	case models.FixedCoupon:
+		if payload.Value <= 0 {
+			return fail("Fixed coupons need a value greater than 0")
+		}
+	case models.FreeShippingCoupon:
+		payload.Value = 0
+	}
+
+	if payload.StartsAt != nil && payload.EndsAt != nil && !payload.EndsAt.After(*payload.StartsAt) {
+		return fail("ends_at must be after starts_at")
+	}
+
+	if message := checkPromotionScope(c.db, user, payload.Scope, &payload.ScopeID); message != "" {
+		return fail(message)
+	}
+
+	// Soft-deleted coupons keep their code reserved.
+	var count int64
+	c.db.Unscoped().Model(&models.Coupon{}).Where("code = ? AND id <> ?", payload.Code, couponID).Count(&count)
+	if count > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A coupon with this code already exists"})
+		return nil, false
+	}
+
+	return &payload, true
+}
+
+func (c *CouponController) timesUsed(couponID uint) int64 {
+	var count int64
+	c.db.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND pending = ?", couponID, false).Count(&count)
+	return count
+}
+
+func applyCouponPayload(coupon *models.Coupon, payload *models.CouponPayload) {
+	coupon.Code = payload.Code
+	coupon.Description = payload.Description
+	coupon.Type = payload.Type
+	coupon.Value = payload.Value
+	coupon.Scope = payload.Scope
+	coupon.ScopeID = payload.ScopeID
+	coupon.MinOrderValue = payload.MinOrderValue
+	coupon.UsageLimit = payload.UsageLimit
+	coupon.PerUserLimit = payload.PerUserLimit
+	coupon.StartsAt = payload.StartsAt
+	coupon.EndsAt = payload.EndsAt
+	coupon.Stackable = payload.Stackable
+	coupon.Active = payload.Active == nil || *payload.Active
+}
+
+// checkPromotionScope validates the scope of a coupon or price rule and
+// returns an error message if it is not allowed. Sellers can only target their
+// own shop, its products or a category, which then covers only their shop's
+// products. The store scope clears scopeID.
+func checkPromotionScope(db *gorm.DB, user *models.User, scope string, scopeID **uint) string {
+	isAdmin := models.Role(user.Role) == models.AdminRole
+
+	if models.CouponScope(scope) == models.StoreScope {
+		if !isAdmin {
+			return "Shop promotions must use the shop, category or product scope"
+		}
+		*scopeID = nil
+		return ""
+	}
+	if *scopeID == nil {
+		return "scope_id is required for this scope"
+	}
+	id := **scopeID
+
+	switch models.CouponScope(scope) {
+	case models.ShopScope:
+		if !isAdmin && id != user.Shop.ID {
+			return "You can only create promotions for your own shop"
+		}
+		if err := db.First(&models.Shop{}, id).Error; err != nil {
+			return "Shop not found"
+		}
+	case models.CategoryScope:
+		if err := db.First(&models.Category{}, id).Error; err != nil {
+			return "Category not found"
+		}
+	case models.ProductScope:
+		var product models.Product
+		if err := db.First(&product, id).Error; err != nil {
+			return "Product not found"
+		}
+		if !isAdmin && product.ShopID != user.Shop.ID {
+			return "You can only create promotions for your own products"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/notify"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// GuestController lets customers shop without an account and later attach
+// their guest orders to one.
+type GuestController struct {
+	db       *gorm.DB
+	orders   *services.OrderService
+	notifier notify.Notifier
+}
+
+func NewGuestController(db *gorm.DB, orders *services.OrderService, notifier notify.Notifier) *GuestController {
+	return &GuestController{db: db, orders: orders, notifier: notifier}
+}
+
+// handleCreateGuestSession issues a guest token, both in the body for API
+// clients and as a cookie for browsers. A caller that already holds a valid
+// token keeps its guest ID, so its carts carry over to the new token.
+func (c *GuestController) handleCreateGuestSession(ctx *gin.Context) {
+	token, _, expires, err := GenerateGuestToken(guestFromRequest(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start guest session"})
+		return
+	}
+	setGuestCookie(ctx, token, int(time.Until(expires).Seconds()))
+	ctx.JSON(http.StatusCreated, gin.H{"guest_token": token, "expires_at": expires})
+}
+
+// handleClaimOrders attaches guest orders to the caller's account: those of
+// the guest token sent with the request, and the one a claim code is for.
+// An order number and email instead mail a claim code for that order to the
+// order's email, so only someone who can read it can take the order.
+func (c *GuestController) handleClaimOrders(ctx *gin.Context) {
+	var payload models.ClaimOrdersPayload
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&payload); err != nil {
+			var errs validator.ValidationErrors
+			if errors.As(err, &errs) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+				return
+			}
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+	}
+
+	userID, _ := ctx.Get("userID")
+	claimed, err := claimGuestSession(ctx, c.orders, userID.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim orders"})
+		return
+	}
+
+	switch {
+	case payload.ClaimCode != "":
+		orderID, ok := parseOrderClaimCode(payload.ClaimCode, userID.(uint))
+		if !ok {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "The claim code is invalid or has expired"})
+			return
+		}
+		n, err := c.orders.ClaimGuestOrder(ctx, userID.(uint), orderID)
+		switch {
+		case errors.Is(err, services.ErrGuestOrderNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim orders"})
+			return
+		}
+		claimed += n
+	case payload.OrderNumber != "":
+		if err := c.sendClaimCode(ctx, userID.(uint), payload.OrderNumber, payload.Email); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send claim code"})
+			return
+		}
+		// The answer is the same whether or not the order exists, so it
+		// cannot be used to find out who ordered what.
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"claimed_orders": claimed,
+			"message":        "If a guest order matches, a claim code was sent to its email address",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"claimed_orders": claimed})
+}
+
+// sendClaimCode mails a claim code for the guest order with the number and
+// email to that email. Nothing is sent if there is no such order.
+func (c *GuestController) sendClaimCode(ctx *gin.Context, userID uint, number, email string) error {
+	order, err := c.orders.FindGuestOrder(number, email)
+	if errors.Is(err, services.ErrGuestOrderNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	code, err := GenerateOrderClaimCode(order.ID, userID)
+	if err != nil {
+		return err
+	}
+	return c.notifier.Send(ctx, notify.Notification{
+		To:      order.Email,
+		Subject: fmt.Sprintf("Add order %s to your account", *order.Number),
+		Body: fmt.Sprintf("Someone asked to add your order %s to their account. If that was you, "+
+			"send this claim code to /api/me/orders/claim within an hour:\n\n%s\n\n"+
+			"If it was not you, ignore this email and the order stays as it is.", *order.Number, code),
+	})
+}
+
+// claimGuestSession moves the orders of the request's guest token to the
+// user and ends the guest session, whose carts now belong to the account.
+func claimGuestSession(ctx *gin.Context, orders *services.OrderService, userID uint) (int, error) {
+	guestID := guestFromRequest(ctx)
+	if guestID == "" {
+		return 0, nil
+	}
+	claimed, err := orders.ClaimGuestOrders(ctx, userID, guestID)
+	if err != nil {
+		return 0, err
+	}
+	setGuestCookie(ctx, "", -1)
+	return claimed, nil
+}
+
+// setGuestCookie stores the guest token for browsers; a negative maxAge
+// deletes it.
+func setGuestCookie(ctx *gin.Context, token string, maxAge int) {
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(guestTokenCookie, token, maxAge, "/api", "", ctx.Request.TLS != nil, true)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// IdempotencyMiddleware makes a route safe to retry. When the client sends an
+// Idempotency-Key header, the first response is stored and replayed for any
+// retry of the same request with the same key; reusing the key for a
+// different request is rejected. Server errors are not stored, so those
+// requests can be retried for real. It must run after AuthMiddleware or
+// CustomerMiddleware, as keys are scoped to the caller.
+func IdempotencyMiddleware(service *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.GetHeader(idempotencyKeyHeader)
+		if name == "" {
+			c.Next()
+			return
+		}
+		if len(name) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID, _ := c.Get("userID")
+		guestID, _ := c.Get("guestID")
+		owner, _ := userID.(uint)
+		guest, _ := guestID.(string)
+		key, replay, err := service.Begin(c, owner, guest, name, c.Request.Method, c.Request.URL.RequestURI(), body)
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case err != nil:
+			slog.Error("failed to reserve idempotency key", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			c.Abort()
+			return
+		case replay:
+			c.Header(idempotentReplayedHeader, "true")
+			c.Data(key.ResponseStatus, key.ContentType, key.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// The response is already sent; failing to store it only means a
+		// retry is handled again, so errors are logged.
+		if status := recorder.Status(); status >= http.StatusInternalServerError {
+			err = service.Release(c, key)
+		} else {
+			err = service.Complete(c, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			slog.Error("failed to store idempotent response", "key_id", key.ID, "error", err)
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body as it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
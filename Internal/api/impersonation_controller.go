@@ -0,0 +1,123 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImpersonationController struct {
+	db *gorm.DB
+}
+
+func NewImpersonationController(db *gorm.DB) *ImpersonationController {
+	return &ImpersonationController{db: db}
+}
+
+type PublicImpersonationLog struct {
+	ID        uint      `json:"id"`
+	AdminID   uint      `json:"admin_id"`
+	UserID    uint      `json:"user_id"`
+	Reason    string    `json:"reason"`
+	ReadOnly  bool      `json:"read_only"`
+	IPAddress string    `json:"ip_address"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleImpersonate issues a short-lived access token for the target user.
+// The token is read-only unless the admin explicitly asks for write access.
+func (c *ImpersonationController) handleImpersonate(ctx *gin.Context) {
+	targetUserID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil || targetUserID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var payload models.ImpersonationPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A reason for impersonating is required"})
+		return
+	}
+
+	adminID, _ := ctx.Get("userID")
+
+	var target models.User
+	if result := c.db.First(&target, targetUserID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if models.Role(target.Role) == models.AdminRole {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Admins cannot be impersonated"})
+		return
+	}
+
+	readOnly := !payload.AllowWrites
+	token, expiresAt, err := GenerateImpersonationToken(target.ID, target.Role, adminID.(uint), readOnly)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate impersonation token"})
+		return
+	}
+
+	entry := models.ImpersonationLog{
+		AdminID:   adminID.(uint),
+		UserID:    target.ID,
+		Reason:    payload.Reason,
+		ReadOnly:  readOnly,
+		IPAddress: ctx.ClientIP(),
+		ExpiresAt: expiresAt,
+	}
+	// Refuse to hand out a token we could not record.
+	if result := c.db.WithContext(ctx).Create(&entry); result.Error != nil {
+		slog.Error("failed to record impersonation", "error", result.Error)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record impersonation"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"access_token":    token,
+		"expires_at":      expiresAt,
+		"read_only":       readOnly,
+		"impersonating":   target.ID,
+		"impersonator_id": adminID,
+	})
+}
+
+// handleGetImpersonationLogs lists issued impersonation tokens, newest first.
+// It can be narrowed with ?admin_id= and ?user_id=.
+func (c *ImpersonationController) handleGetImpersonationLogs(ctx *gin.Context) {
+	query := c.db.Order("created_at DESC")
+	if adminID := ctx.Query("admin_id"); adminID != "" {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if userID := ctx.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var entries []models.ImpersonationLog
+	if result := query.Find(&entries); result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch impersonation logs"})
+		return
+	}
+
+	publicEntries := make([]PublicImpersonationLog, len(entries))
+	for i, entry := range entries {
+		publicEntries[i] = PublicImpersonationLog{
+			ID:        entry.ID,
+			AdminID:   entry.AdminID,
+			UserID:    entry.UserID,
+			Reason:    entry.Reason,
+			ReadOnly:  entry.ReadOnly,
+			IPAddress: entry.IPAddress,
+			ExpiresAt: entry.ExpiresAt,
+			CreatedAt: entry.CreatedAt,
+		}
+	}
+	ctx.JSON(http.StatusOK, publicEntries)
+}
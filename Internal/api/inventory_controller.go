@@ -0,0 +1,182 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMovementPageSize = 50
+	maxMovementPageSize     = 200
+)
+
+type InventoryController struct {
+	db      *gorm.DB
+	service *services.InventoryService
+}
+
+func NewInventoryController(db *gorm.DB, service *services.InventoryService) *InventoryController {
+	return &InventoryController{db: db, service: service}
+}
+
+type PublicInventoryMovement struct {
+	ID         uint      `json:"id"`
+	Quantity   int       `json:"quantity"`
+	StockAfter int       `json:"stock_after"`
+	Reason     string    `json:"reason"`
+	Note       string    `json:"note,omitempty"`
+	ActorID    *uint     `json:"actor_id"`
+	OrderID    *uint     `json:"order_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func toPublicInventoryMovement(movement models.InventoryMovement) PublicInventoryMovement {
+	return PublicInventoryMovement{
+		ID:         movement.ID,
+		Quantity:   movement.Quantity,
+		StockAfter: movement.StockAfter,
+		Reason:     movement.Reason,
+		Note:       movement.Note,
+		ActorID:    movement.ActorID,
+		OrderID:    movement.OrderID,
+		CreatedAt:  movement.CreatedAt,
+	}
+}
+
+// handleAdjustStock records a manual restock, correction or return. The
+// quantity is signed; stock can never drop below zero.
+func (c *InventoryController) handleAdjustStock(ctx *gin.Context) {
+	productID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || productID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	product, ok := loadEditableProduct(ctx, c.db, uint(productID))
+	if !ok {
+		return
+	}
+
+	var payload models.StockAdjustmentPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	movement, err := c.service.AdjustStock(ctx, product.ID, userID.(uint), payload)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInsufficientStock) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Stock cannot go below zero"})
+			return
+		}
+		slog.Error("failed to adjust stock", "product_id", product.ID, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust stock"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"stock": movement.StockAfter, "movement": toPublicInventoryMovement(*movement)})
+}
+
+// handleGetStockMovements lists a product's stock movements, newest first.
+func (c *InventoryController) handleGetStockMovements(ctx *gin.Context) {
+	productID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || productID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	product, ok := loadEditableProduct(ctx, c.db, uint(productID))
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultMovementPageSize)))
+	if err != nil || limit <= 0 || limit > maxMovementPageSize {
+		limit = defaultMovementPageSize
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	movements, total, err := c.service.ListMovements(product.ID, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock movements"})
+		return
+	}
+
+	publicMovements := make([]PublicInventoryMovement, len(movements))
+	for i, movement := range movements {
+		publicMovements[i] = toPublicInventoryMovement(movement)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"stock": product.Stock, "total": total, "movements": publicMovements})
+}
+
+// handleSubscribeStock signs the caller up for a one-off notice when the
+// out-of-stock product is restocked.
+func (c *InventoryController) handleSubscribeStock(ctx *gin.Context) {
+	productID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || productID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var product models.Product
+	if err := c.db.Where("status = ?", models.ProductPublished).First(&product, productID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	subscription, err := c.service.Subscribe(ctx, &product, userID.(uint))
+	if err != nil {
+		if errors.Is(err, services.ErrProductInStock) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Product is in stock"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message":    "You will be notified when the product is back in stock",
+		"product_id": subscription.ProductID,
+		"created_at": subscription.CreatedAt,
+	})
+}
+
+func (c *InventoryController) handleUnsubscribeStock(ctx *gin.Context) {
+	productID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || productID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	if err := c.service.Unsubscribe(ctx, uint(productID), userID.(uint)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No pending subscription for this product"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Subscription cancelled"})
+}
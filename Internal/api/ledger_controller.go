@@ -0,0 +1,341 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPayoutBatchPageSize = 20
+	maxPayoutBatchPageSize     = 100
+)
+
+// LedgerController exposes the seller ledger: balances, statements and
+// payouts for shop owners, payout batches and the trial balance for admins.
+type LedgerController struct {
+	db      *gorm.DB
+	service *services.LedgerService
+}
+
+func NewLedgerController(db *gorm.DB, service *services.LedgerService) *LedgerController {
+	return &LedgerController{db: db, service: service}
+}
+
+type PublicShopBalance struct {
+	ShopID         uint    `json:"shop_id"`
+	Balance        float64 `json:"balance"`
+	Held           float64 `json:"held"`
+	Available      float64 `json:"available"`
+	PendingPayouts float64 `json:"pending_payouts"`
+}
+
+type PublicLedgerLine struct {
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"`
+	Reference   string    `json:"reference"`
+	Description string    `json:"description"`
+	OrderID     *uint     `json:"order_id"`
+	PayoutID    *uint     `json:"payout_id"`
+	// Amount is positive when the platform owes the shop more.
+	Amount float64 `json:"amount"`
+}
+
+type PublicStatement struct {
+	ShopID  uint               `json:"shop_id"`
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Opening float64            `json:"opening_balance"`
+	Closing float64            `json:"closing_balance"`
+	Lines   []PublicLedgerLine `json:"lines"`
+}
+
+type PublicPayout struct {
+	ID            uint       `json:"id"`
+	BatchID       uint       `json:"batch_id"`
+	ShopID        uint       `json:"shop_id"`
+	Amount        float64    `json:"amount"`
+	Status        string     `json:"status"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+	PaidAt        *time.Time `json:"paid_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type PublicPayoutBatch struct {
+	ID        uint           `json:"id"`
+	Total     float64        `json:"total"`
+	CreatedAt time.Time      `json:"created_at"`
+	Payouts   []PublicPayout `json:"payouts,omitempty"`
+}
+
+func toPublicPayout(payout models.Payout) PublicPayout {
+	return PublicPayout{
+		ID:            payout.ID,
+		BatchID:       payout.BatchID,
+		ShopID:        payout.ShopID,
+		Amount:        payout.Amount,
+		Status:        payout.Status,
+		FailureReason: payout.FailureReason,
+		PaidAt:        payout.PaidAt,
+		CreatedAt:     payout.CreatedAt,
+	}
+}
+
+func toPublicPayoutBatch(batch models.PayoutBatch) PublicPayoutBatch {
+	publicBatch := PublicPayoutBatch{
+		ID:        batch.ID,
+		Total:     batch.Total,
+		CreatedAt: batch.CreatedAt,
+		Payouts:   make([]PublicPayout, len(batch.Payouts)),
+	}
+	for i, payout := range batch.Payouts {
+		publicBatch.Payouts[i] = toPublicPayout(payout)
+	}
+	return publicBatch
+}
+
+func (c *LedgerController) handleGetShopBalance(ctx *gin.Context) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	balance, err := c.service.Balance(shop.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch balance"})
+		return
+	}
+	ctx.JSON(http.StatusOK, PublicShopBalance{
+		ShopID:         shop.ID,
+		Balance:        balance.Balance,
+		Held:           balance.Held,
+		Available:      balance.Available,
+		PendingPayouts: balance.PendingPayouts,
+	})
+}
+
+// handleGetShopStatement returns the shop's postings between from and to
+// (RFC 3339, by default the current month so far) as CSV, or as JSON with
+// ?format=json.
+func (c *LedgerController) handleGetShopStatement(ctx *gin.Context) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	to := now
+	if value := ctx.Query("from"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' timestamp, expected RFC 3339"})
+			return
+		}
+		from = t
+	}
+	if value := ctx.Query("to"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' timestamp, expected RFC 3339"})
+			return
+		}
+		to = t
+	}
+	format := ctx.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected csv or json"})
+		return
+	}
+
+	statement, err := c.service.Statement(shop.ID, from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build statement"})
+		return
+	}
+
+	if format == "json" {
+		publicStatement := PublicStatement{
+			ShopID:  statement.ShopID,
+			From:    statement.From,
+			To:      statement.To,
+			Opening: statement.Opening,
+			Closing: statement.Closing,
+			Lines:   make([]PublicLedgerLine, len(statement.Entries)),
+		}
+		for i, entry := range statement.Entries {
+			publicStatement.Lines[i] = PublicLedgerLine{
+				Date:        entry.CreatedAt,
+				Type:        entry.Transaction.Kind,
+				Reference:   entry.Transaction.Reference,
+				Description: entry.Transaction.Description,
+				OrderID:     entry.Transaction.OrderID,
+				PayoutID:    entry.Transaction.PayoutID,
+				Amount:      models.RoundMoney(-entry.Amount),
+			}
+		}
+		ctx.JSON(http.StatusOK, publicStatement)
+		return
+	}
+
+	filename := fmt.Sprintf("shop-%d-statement-%s-%s.csv", shop.ID, from.Format("20060102"), to.Format("20060102"))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	if err := services.WriteStatementCSV(ctx.Writer, statement); err != nil {
+		// Headers are already sent, so all we can do is log and cut the stream short.
+		slog.Error("failed to write statement", "shop_id", shop.ID, "error", err)
+	}
+}
+
+func (c *LedgerController) handleGetShopPayouts(ctx *gin.Context) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	payouts, err := c.service.ListShopPayouts(shop.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payouts"})
+		return
+	}
+	publicPayouts := make([]PublicPayout, len(payouts))
+	for i, payout := range payouts {
+		publicPayouts[i] = toPublicPayout(payout)
+	}
+	ctx.JSON(http.StatusOK, publicPayouts)
+}
+
+func (c *LedgerController) handleGetPayoutBatches(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultPayoutBatchPageSize)))
+	if err != nil || limit <= 0 || limit > maxPayoutBatchPageSize {
+		limit = defaultPayoutBatchPageSize
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	batches, total, err := c.service.ListPayoutBatches(limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payout batches"})
+		return
+	}
+	publicBatches := make([]PublicPayoutBatch, len(batches))
+	for i, batch := range batches {
+		publicBatches[i] = toPublicPayoutBatch(batch)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"total": total, "batches": publicBatches})
+}
+
+func (c *LedgerController) handleGetPayoutBatch(ctx *gin.Context) {
+	batchID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || batchID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch ID"})
+		return
+	}
+
+	batch, err := c.service.GetPayoutBatch(uint(batchID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payout batch not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicPayoutBatch(*batch))
+}
+
+// handleCreatePayoutBatch runs a payout batch now instead of waiting for the
+// scheduler.
+func (c *LedgerController) handleCreatePayoutBatch(ctx *gin.Context) {
+	batch, err := c.service.CreatePayoutBatch(ctx)
+	if err != nil {
+		slog.Error("failed to create payout batch", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payout batch"})
+		return
+	}
+	if batch == nil {
+		ctx.JSON(http.StatusOK, gin.H{"message": "No shop is due a payout"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, toPublicPayoutBatch(*batch))
+}
+
+func (c *LedgerController) handleMarkPayoutPaid(ctx *gin.Context) {
+	payout, ok := c.loadPayout(ctx)
+	if !ok {
+		return
+	}
+	c.writePayoutResult(ctx, payout, c.service.MarkPayoutPaid(ctx, payout))
+}
+
+func (c *LedgerController) handleMarkPayoutFailed(ctx *gin.Context) {
+	payout, ok := c.loadPayout(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.FailPayoutPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	c.writePayoutResult(ctx, payout, c.service.MarkPayoutFailed(ctx, payout, payload.Reason))
+}
+
+// handleGetTrialBalance sums every ledger account; the balances add up to
+// zero when the ledger is consistent.
+func (c *LedgerController) handleGetTrialBalance(ctx *gin.Context) {
+	balances, err := c.service.TrialBalance()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trial balance"})
+		return
+	}
+
+	accounts := make([]gin.H, len(balances))
+	var total float64
+	for i, balance := range balances {
+		accounts[i] = gin.H{"account": balance.Account, "balance": models.RoundMoney(balance.Balance)}
+		total += balance.Balance
+	}
+	ctx.JSON(http.StatusOK, gin.H{"accounts": accounts, "total": models.RoundMoney(total)})
+}
+
+func (c *LedgerController) loadPayout(ctx *gin.Context) (*models.Payout, bool) {
+	payoutID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || payoutID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payout ID"})
+		return nil, false
+	}
+
+	payout, err := c.service.GetPayout(uint(payoutID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payout not found"})
+		return nil, false
+	}
+	return payout, true
+}
+
+func (c *LedgerController) writePayoutResult(ctx *gin.Context, payout *models.Payout, err error) {
+	switch {
+	case errors.Is(err, services.ErrPayoutState):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		slog.Error("failed to settle payout", "payout_id", payout.ID, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payout"})
+	default:
+		ctx.JSON(http.StatusOK, toPublicPayout(*payout))
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/Archnick/go-ecommerce/Internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+type MediaController struct {
+	images *services.ImageService
+}
+
+func NewMediaController(images *services.ImageService) *MediaController {
+	return &MediaController{images: images}
+}
+
+// handleGetMedia serves a file from the blob store.
+func (c *MediaController) handleGetMedia(ctx *gin.Context) {
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+	blob, contentType, err := c.images.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer blob.Close()
+
+	// Keys are random and never reused, so the content can be cached for good.
+	ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Content-Type", contentType)
+	ctx.Status(http.StatusOK)
+	io.Copy(ctx.Writer, blob)
+}
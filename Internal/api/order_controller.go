@@ -1,44 +1,301 @@
 package api
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Archnick/go-ecommerce/Internal/invoice"
 	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/Archnick/go-ecommerce/Internal/services"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderController struct {
-	db *gorm.DB
+	db       *gorm.DB
+	orders   *services.OrderService
+	invoices *services.InvoiceService
 }
 
-func NewOrderController(db *gorm.DB) *OrderController {
-	return &OrderController{db: db}
+func NewOrderController(db *gorm.DB, orders *services.OrderService, invoices *services.InvoiceService) *OrderController {
+	return &OrderController{db: db, orders: orders, invoices: invoices}
 }
 
 type PublicOrder struct {
-	ID              uint              `json:"id"`
-	TotalAmount     float64           `json:"total_amount"`
-	Status          string            `json:"status"`
-	ShippingAddress string            `json:"shipping_address"`
-	UserID          uint              `json:"user_id"`
-	OrderItems      []PublicOrderItem `json:"order_items"`
+	ID               uint                  `json:"id"`
+	Number           *string               `json:"number"`
+	Subtotal         float64               `json:"subtotal"`
+	DiscountTotal    float64               `json:"discount_total"`
+	TaxTotal         float64               `json:"tax_total"`
+	ShippingTotal    float64               `json:"shipping_total"`
+	FreeShipping     bool                  `json:"free_shipping"`
+	TotalAmount      float64               `json:"total_amount"`
+	PricesIncludeTax bool                  `json:"prices_include_tax"`
+	Status           string                `json:"status"`
+	ShippingAddress  PublicPostalAddress   `json:"shipping_address"`
+	BillingAddress   PublicPostalAddress   `json:"billing_address"`
+	UserID           *uint                 `json:"user_id"`
+	Email            string                `json:"email"`
+	OrderItems       []PublicOrderItem     `json:"order_items"`
+	Discounts        []PublicOrderDiscount `json:"discounts"`
+	ShippingLines    []PublicShippingLine  `json:"shipping_lines"`
+	Payments         []PublicPayment       `json:"payments"`
+	ShopOrders       []PublicShopOrder     `json:"shop_orders"`
+	Shipments        []PublicShipment      `json:"shipments"`
+}
+
+type PublicPayment struct {
+	ID             uint      `json:"id"`
+	Provider       string    `json:"provider"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+	Status         string    `json:"status"`
+	CapturedAmount float64   `json:"captured_amount"`
+	RefundedAmount float64   `json:"refunded_amount"`
+	ActionURL      string    `json:"action_url,omitempty"`
+	DeclineReason  string    `json:"decline_reason,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func toPublicPayment(payment models.Payment) PublicPayment {
+	return PublicPayment{
+		ID:             payment.ID,
+		Provider:       payment.Provider,
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		Status:         payment.Status,
+		CapturedAmount: payment.CapturedAmount,
+		RefundedAmount: payment.RefundedAmount,
+		ActionURL:      payment.ActionURL,
+		DeclineReason:  payment.DeclineReason,
+		CreatedAt:      payment.CreatedAt,
+	}
+}
+
+type PublicPostalAddress struct {
+	FullName   string `json:"full_name"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone"`
+	// Formatted is the address as printed on a label.
+	Formatted string `json:"formatted"`
+}
+
+func toPublicPostalAddress(address models.PostalAddress) PublicPostalAddress {
+	return PublicPostalAddress{
+		FullName:   address.FullName,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		Region:     address.Region,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Phone:      address.Phone,
+		Formatted:  address.Format(),
+	}
 }
 
 type PublicOrderItem struct {
-	ID        uint    `json:"id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
-	ProductID uint    `json:"product_id"`
+	ID          uint                   `json:"id"`
+	Quantity    int                    `json:"quantity"`
+	Price       float64                `json:"price"`
+	Discount    float64                `json:"discount"`
+	Tax         float64                `json:"tax"`
+	LineTotal   float64                `json:"line_total"`
+	ProductID   uint                   `json:"product_id"`
+	ShopOrderID *uint                  `json:"shop_order_id"`
+	Adjustments []PublicItemAdjustment `json:"adjustments"`
+	Taxes       []PublicItemTax        `json:"taxes"`
+}
+
+type PublicItemTax struct {
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+	Amount  float64 `json:"amount"`
+}
+
+// PublicItemAdjustment explains a price rule applied to an order item.
+type PublicItemAdjustment struct {
+	PriceRuleID *uint   `json:"price_rule_id"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+type PublicShippingLine struct {
+	ShopID  uint    `json:"shop_id"`
+	QuoteID string  `json:"quote_id"`
+	Name    string  `json:"name"`
+	Carrier string  `json:"carrier,omitempty"`
+	Amount  float64 `json:"amount"`
+	Waived  bool    `json:"waived"`
+	MinDays int     `json:"min_days"`
+	MaxDays int     `json:"max_days"`
+}
+
+type PublicShippingQuote struct {
+	ID      string  `json:"id"`
+	ShopID  uint    `json:"shop_id"`
+	Name    string  `json:"name"`
+	Carrier string  `json:"carrier,omitempty"`
+	Amount  float64 `json:"amount"`
+	MinDays int     `json:"min_days"`
+	MaxDays int     `json:"max_days"`
 }
 
-// Additional methods for OrderController can be added here.
+type PublicOrderDiscount struct {
+	Code         string  `json:"code"`
+	Description  string  `json:"description"`
+	Amount       float64 `json:"amount"`
+	FreeShipping bool    `json:"free_shipping"`
+}
 
+// orderDetails preloads everything toPublicOrder shows.
+func orderDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("OrderItems.Adjustments").Preload("OrderItems.Taxes").
+		Preload("Discounts").Preload("ShippingLines").Preload("Payments").Preload("ShopOrders").
+		Preload("Shipments.Items")
+}
+
+func toPublicOrder(order models.Order) PublicOrder {
+	publicOrder := PublicOrder{
+		ID:               order.ID,
+		Number:           order.Number,
+		Subtotal:         order.Subtotal,
+		DiscountTotal:    order.DiscountTotal,
+		TaxTotal:         order.TaxTotal,
+		ShippingTotal:    order.ShippingTotal,
+		FreeShipping:     order.FreeShipping,
+		TotalAmount:      order.TotalAmount,
+		PricesIncludeTax: order.PricesIncludeTax,
+		Status:           order.Status,
+		ShippingAddress:  toPublicPostalAddress(order.Shipping),
+		BillingAddress:   toPublicPostalAddress(order.Billing),
+		UserID:           order.UserID,
+		Email:            order.Email,
+		OrderItems:       make([]PublicOrderItem, len(order.OrderItems)),
+		Discounts:        make([]PublicOrderDiscount, len(order.Discounts)),
+		ShippingLines:    make([]PublicShippingLine, len(order.ShippingLines)),
+		Payments:         make([]PublicPayment, len(order.Payments)),
+		ShopOrders:       make([]PublicShopOrder, len(order.ShopOrders)),
+		Shipments:        make([]PublicShipment, len(order.Shipments)),
+	}
+	for i, shipment := range order.Shipments {
+		publicOrder.Shipments[i] = toPublicShipment(shipment)
+	}
+	for i, shopOrder := range order.ShopOrders {
+		publicOrder.ShopOrders[i] = toPublicShopOrder(shopOrder)
+	}
+	for i, payment := range order.Payments {
+		publicOrder.Payments[i] = toPublicPayment(payment)
+	}
+	for i, line := range order.ShippingLines {
+		publicOrder.ShippingLines[i] = PublicShippingLine{
+			ShopID:  line.ShopID,
+			QuoteID: line.QuoteID,
+			Name:    line.Name,
+			Carrier: line.Carrier,
+			Amount:  line.Amount,
+			Waived:  line.Waived,
+			MinDays: line.MinDays,
+			MaxDays: line.MaxDays,
+		}
+	}
+	for i, item := range order.OrderItems {
+		publicOrder.OrderItems[i] = toPublicOrderItem(item)
+	}
+	for i, line := range order.Discounts {
+		publicOrder.Discounts[i] = PublicOrderDiscount{
+			Code:         line.Code,
+			Description:  line.Description,
+			Amount:       line.Amount,
+			FreeShipping: line.FreeShipping,
+		}
+	}
+	// Orders from before structured addresses only have the label text.
+	if publicOrder.ShippingAddress.Formatted == "" {
+		publicOrder.ShippingAddress.Formatted = order.ShippingAddress
+	}
+	return publicOrder
+}
+
+func toPublicOrderItem(item models.OrderItem) PublicOrderItem {
+	publicItem := PublicOrderItem{
+		ID:          item.ID,
+		Quantity:    item.Quantity,
+		Price:       item.Price,
+		Discount:    item.Discount,
+		Tax:         item.Tax,
+		LineTotal:   models.RoundMoney(item.LineTotal()),
+		ProductID:   item.ProductID,
+		ShopOrderID: item.ShopOrderID,
+		Adjustments: make([]PublicItemAdjustment, len(item.Adjustments)),
+		Taxes:       make([]PublicItemTax, len(item.Taxes)),
+	}
+	for i, line := range item.Taxes {
+		publicItem.Taxes[i] = PublicItemTax{
+			Name:    line.Name,
+			Percent: line.Percent,
+			Amount:  line.Amount,
+		}
+	}
+	for i, adjustment := range item.Adjustments {
+		publicItem.Adjustments[i] = PublicItemAdjustment{
+			PriceRuleID: adjustment.PriceRuleID,
+			Description: adjustment.Description,
+			Amount:      adjustment.Amount,
+		}
+	}
+	return publicItem
+}
+
+// resolveAddress returns the address picked from the user's address book by
+// id, or the inline one, checked against the country's rules. It returns nil
+// when neither is given. On failure it writes the response and returns false.
+func resolveAddress(ctx *gin.Context, db *gorm.DB, userID uint, id *uint, inline *models.PostalAddressPayload) (*models.PostalAddress, bool) {
+	switch {
+	case id != nil:
+		var address models.Address
+		if err := db.Where("user_id = ?", userID).First(&address, *id).Error; err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return nil, false
+		}
+		return &address.PostalAddress, true
+	case inline != nil:
+		address := inline.PostalAddress()
+		if problems := services.ValidatePostalAddress(address); problems != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": problems})
+			return nil, false
+		}
+		return &address, true
+	}
+	return nil, true
+}
+
+// handleGetOrders lists the caller's orders, or every order for admins.
 func (c *OrderController) handleGetOrders(ctx *gin.Context) {
 	var orders []models.Order
 
-	result := c.db.Preload("OrderItems").Find(&orders)
+	query := c.db.Scopes(orderDetails)
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+	if models.Role(role.(string)) != models.AdminRole {
+		query = query.Where("user_id = ?", userID)
+	}
+	result := query.Find(&orders)
 	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
 		return
@@ -46,22 +303,7 @@ func (c *OrderController) handleGetOrders(ctx *gin.Context) {
 
 	publicOrders := make([]PublicOrder, len(orders))
 	for i, order := range orders {
-		publicOrders[i] = PublicOrder{
-			ID:              order.ID,
-			TotalAmount:     order.TotalAmount,
-			Status:          order.Status,
-			ShippingAddress: order.ShippingAddress,
-			UserID:          order.UserID,
-			OrderItems:      make([]PublicOrderItem, len(order.OrderItems)),
-		}
-		for j, item := range order.OrderItems {
-			publicOrders[i].OrderItems[j] = PublicOrderItem{
-				ID:        item.ID,
-				Quantity:  item.Quantity,
-				Price:     item.Price,
-				ProductID: item.ProductID,
-			}
-		}
+		publicOrders[i] = toPublicOrder(order)
 	}
 
 	ctx.JSON(http.StatusOK, publicOrders)
@@ -75,95 +317,185 @@ func (c *OrderController) handleGetOrder(ctx *gin.Context) {
 	}
 
 	var order models.Order
-	result := c.db.Preload("OrderItems").First(&order, orderID)
-	if result.Error != nil {
+	result := c.db.Scopes(orderDetails).First(&order, orderID)
+	if result.Error != nil || !ownsOrder(ctx, &order) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
 	}
 
-	publicOrder := PublicOrder{
-		ID:              order.ID,
-		TotalAmount:     order.TotalAmount,
-		Status:          order.Status,
-		ShippingAddress: order.ShippingAddress,
-		UserID:          order.UserID,
-		OrderItems:      make([]PublicOrderItem, len(order.OrderItems)),
-	}
-	for i, item := range order.OrderItems {
-		publicOrder.OrderItems[i] = PublicOrderItem{
-			ID:        item.ID,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
-			ProductID: item.ProductID,
+	ctx.JSON(http.StatusOK, toPublicOrder(order))
+}
+
+// handleLookupOrder finds a placed order by its number and the email it was
+// placed with, so guests can follow their orders without an account.
+func (c *OrderController) handleLookupOrder(ctx *gin.Context) {
+	var payload models.OrderLookupPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
 		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	var order models.Order
+	err := c.db.Scopes(orderDetails).
+		Where("number = ? AND lower(email) = lower(?)", services.NormalizeOrderNumber(payload.OrderNumber), strings.TrimSpace(payload.Email)).
+		First(&order).Error
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No order matches this number and email"})
+		return
 	}
-	ctx.JSON(http.StatusOK, publicOrder)
+	ctx.JSON(http.StatusOK, toPublicOrder(order))
 }
 
 func (c *OrderController) handleCreateOrder(ctx *gin.Context) {
 	var order models.OrderPayload
 	if err := ctx.ShouldBindJSON(&order); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order payload"})
 		return
 	}
 
+	// The order belongs to the caller; only admins may place one for another
+	// user. Guests have no address book, only the addresses they type in.
+	guestID, isGuest := ctx.Get("guestID")
+	var owner uint
+	if !isGuest {
+		userID, _ := ctx.Get("userID")
+		role, _ := ctx.Get("role")
+		owner = userID.(uint)
+		if order.UserID != 0 && models.Role(role.(string)) == models.AdminRole {
+			owner = order.UserID
+		}
+	}
+
+	shipping, ok := resolveAddress(ctx, c.db, owner, order.ShippingAddressID, order.ShippingAddress)
+	if !ok {
+		return
+	}
+	if shipping == nil && !isGuest {
+		var address models.Address
+		err := c.db.Where("user_id = ? AND is_default_shipping = ?", owner, true).First(&address).Error
+		if err == nil {
+			shipping = &address.PostalAddress
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load address book"})
+			return
+		}
+	}
+	billing, ok := resolveAddress(ctx, c.db, owner, order.BillingAddressID, order.BillingAddress)
+	if !ok {
+		return
+	}
+
 	newOrder := models.Order{
-		TotalAmount:     order.TotalAmount,
-		Status:          "cart", // Default status
-		ShippingAddress: order.ShippingAddress,
-		UserID:          order.UserID,
+		TotalAmount: order.TotalAmount,
+		Status:      "cart", // Default status
+	}
+	if isGuest {
+		guest := guestID.(string)
+		newOrder.GuestID = &guest
+	} else {
+		newOrder.UserID = &owner
+	}
+	if shipping != nil {
+		newOrder.Shipping = *shipping
+		newOrder.ShippingAddress = shipping.Format()
+		newOrder.Billing = *shipping
+	}
+	if billing != nil {
+		newOrder.Billing = *billing
+	}
+
+	productIDs := make([]uint, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		productIDs[i] = item.ProductID
+	}
+	if !c.productsPurchasable(ctx, productIDs) {
+		return
 	}
 
 	for _, item := range order.OrderItems {
 		newOrder.OrderItems = append(newOrder.OrderItems, models.OrderItem{
 			Quantity:  item.Quantity,
-			Price:     item.Price,
 			ProductID: item.ProductID,
 		})
 	}
 
-	if err := c.db.Create(&newOrder).Error; err != nil {
+	if err := c.db.WithContext(ctx).Create(&newOrder).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
 		return
 	}
+	if _, err := c.orders.Reprice(ctx, newOrder.ID); err != nil {
+		c.writeOrderError(ctx, err, "Failed to price order")
+		return
+	}
 
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Order created successfully", "order_id": newOrder.ID})
 }
 
+// handleUpdateOrder replaces the addresses of a cart and reprices it for the
+// new destination.
 func (c *OrderController) handleUpdateOrder(ctx *gin.Context) {
-	orderID, err := strconv.Atoi(ctx.Param("id"))
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
 		return
 	}
 
 	var payload models.UpdateOrderPayload
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update payload"})
 		return
 	}
 
-	var order models.Order
-	if err := c.db.First(&order, orderID).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	var addressOwner uint
+	if order.UserID != nil {
+		addressOwner = *order.UserID
+	}
+	shipping, ok := resolveAddress(ctx, c.db, addressOwner, payload.ShippingAddressID, payload.ShippingAddress)
+	if !ok {
+		return
+	}
+	billing, ok := resolveAddress(ctx, c.db, addressOwner, payload.BillingAddressID, payload.BillingAddress)
+	if !ok {
 		return
 	}
 
-	order.Status = payload.Status
-	order.ShippingAddress = payload.ShippingAddress
-
-	if err := c.db.Save(&order).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order"})
+	_, err := c.orders.EditCart(ctx, order.ID, func(tx *gorm.DB, order *models.Order) error {
+		if shipping != nil {
+			order.Shipping = *shipping
+			order.ShippingAddress = shipping.Format()
+		}
+		if billing != nil {
+			order.Billing = *billing
+		}
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+	if err != nil {
+		c.writeOrderError(ctx, err, "Failed to update order")
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Order updated successfully"})
 }
 
+var errOrderItemNotFound = errors.New("order item not found")
+
 func (c *OrderController) handleUpdateOrderItem(ctx *gin.Context) {
-	orderID, err := strconv.Atoi(ctx.Param("order_id"))
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
 		return
 	}
 
@@ -175,141 +507,480 @@ func (c *OrderController) handleUpdateOrderItem(ctx *gin.Context) {
 
 	var payload models.UpdateOrderItemPayload
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update payload"})
 		return
 	}
 
-	var order models.Order
-	if err := c.db.First(&order, orderID).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	_, err = c.orders.EditCart(ctx, order.ID, func(tx *gorm.DB, order *models.Order) error {
+		result := tx.Model(&models.OrderItem{}).Where("id = ? AND order_id = ?", itemID, order.ID).
+			Update("quantity", payload.Quantity)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOrderItemNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, errOrderItemNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order item not found"})
+		return
+	}
+	if err != nil {
+		c.writeOrderError(ctx, err, "Failed to update order item")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Order item updated successfully"})
+}
+
+func (c *OrderController) handleAddItem(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.OrderItemPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item payload"})
+		return
+	}
+	if !c.productsPurchasable(ctx, []uint{payload.ProductID}) {
+		return
+	}
+
+	// The price is left to the repricing, which takes it from the product.
+	newItem := models.OrderItem{
+		Quantity:  payload.Quantity,
+		ProductID: payload.ProductID,
+		OrderID:   order.ID,
+	}
+	_, err := c.orders.EditCart(ctx, order.ID, func(tx *gorm.DB, order *models.Order) error {
+		return tx.Create(&newItem).Error
+	})
+	if err != nil {
+		c.writeOrderError(ctx, err, "Failed to add item to order")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Item added to order successfully", "item_id": newItem.ID})
+}
+
+func (c *OrderController) handleRemoveItem(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.Atoi(ctx.Param("item_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
 		return
 	}
 
-	var item models.OrderItem
-	if err := c.db.First(&item, itemID).Error; err != nil {
+	_, err = c.orders.EditCart(ctx, order.ID, func(tx *gorm.DB, order *models.Order) error {
+		result := tx.Where("id = ? AND order_id = ?", itemID, order.ID).Delete(&models.OrderItem{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOrderItemNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, errOrderItemNotFound) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order item not found"})
 		return
 	}
+	if err != nil {
+		c.writeOrderError(ctx, err, "Failed to remove item from order")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Item removed from order successfully"})
+}
+
+// handleDeleteOrder deletes a cart. Placed orders are cancelled through their
+// shop orders, which refunds the payment and restocks the items.
+func (c *OrderController) handleDeleteOrder(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
+		return
+	}
 
-	if item.OrderID != order.ID {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Item does not belong to this order"})
+	if err := c.orders.DeleteCart(ctx, order.ID); err != nil {
+		c.writeOrderError(ctx, err, "Failed to delete order")
 		return
 	}
 
-	item.Quantity = payload.Quantity
+	ctx.JSON(http.StatusOK, gin.H{"message": "Order deleted successfully"})
+}
 
-	if err := c.db.Save(&item).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order item"})
+// handleGetShippingRates quotes the shipping methods for each shop in the
+// cart, for the order's current destination.
+func (c *OrderController) handleGetShippingRates(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
 		return
 	}
 
-	order.RefreshPrice()
-	c.db.Save(&order)
+	quotes, err := c.orders.ShippingQuotes(ctx, order)
+	if err != nil {
+		c.writeOrderError(ctx, err, "Failed to quote shipping")
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Order item updated successfully"})
+	publicQuotes := make([]PublicShippingQuote, len(quotes))
+	for i, quote := range quotes {
+		publicQuotes[i] = PublicShippingQuote{
+			ID:      quote.ID,
+			ShopID:  quote.ShopID,
+			Name:    quote.Name,
+			Carrier: quote.Carrier,
+			Amount:  quote.Amount,
+			MinDays: quote.MinDays,
+			MaxDays: quote.MaxDays,
+		}
+	}
+	ctx.JSON(http.StatusOK, publicQuotes)
 }
 
-func (c *OrderController) handleAddItem(ctx *gin.Context) {
-	orderID, err := strconv.Atoi(ctx.Param("id"))
+func (c *OrderController) handleSelectShipping(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.SelectShippingPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	updated, err := c.orders.SelectShipping(ctx, order, payload.QuoteIDs)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		c.writeOrderError(ctx, err, "Failed to select shipping")
 		return
 	}
 
-	var payload models.OrderItemPayload
+	ctx.JSON(http.StatusOK, toPublicOrder(*updated))
+}
+
+func (c *OrderController) handleApplyCoupon(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.ApplyCouponPayload
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item payload"})
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
 
-	var order models.Order
-	if result := c.db.First(&order, orderID); result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	updated, err := c.orders.ApplyCoupon(ctx, order, payload.Code)
+	if err != nil {
+		c.writeOrderError(ctx, err, "Failed to apply coupon")
 		return
 	}
 
-	newItem := models.OrderItem{
-		Quantity:  payload.Quantity,
-		Price:     payload.Price,
-		ProductID: payload.ProductID,
-		OrderID:   order.ID,
+	ctx.JSON(http.StatusOK, toPublicOrder(*updated))
+}
+
+func (c *OrderController) handleRemoveCoupon(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
+		return
+	}
+
+	updated, err := c.orders.RemoveCoupon(ctx, order, ctx.Param("code"))
+	if err != nil {
+		c.writeOrderError(ctx, err, "Failed to remove coupon")
+		return
 	}
 
-	if err := c.db.Create(&newItem).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to order"})
+	ctx.JSON(http.StatusOK, toPublicOrder(*updated))
+}
+
+// handleCheckout authorizes payment for the cart and places the order. A
+// payment that needs the customer's bank to approve it comes back with 202
+// and an action_url; the client confirms it afterwards.
+func (c *OrderController) handleCheckout(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
 		return
 	}
 
-	order.RefreshPrice()
-	if err := c.db.Save(&order).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order total amount"})
+	var payload models.CheckoutPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "Item added to order successfully", "item_id": newItem.ID})
+	// Without an email in the payload, keep the one from an earlier attempt
+	// or use the account's.
+	email := payload.Email
+	if email == "" {
+		email = order.Email
+	}
+	if email == "" && order.UserID != nil {
+		var user models.User
+		if err := c.db.Select("email").First(&user, *order.UserID).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load account"})
+			return
+		}
+		email = user.Email
+	}
+
+	payment, err := c.orders.Checkout(ctx, order, payload.PaymentMethod, email)
+	c.writeCheckoutResult(ctx, payment, err)
 }
 
-func (c *OrderController) handleRemoveItem(ctx *gin.Context) {
-	orderID, err := strconv.Atoi(ctx.Param("order_id"))
+func (c *OrderController) handleConfirmPayment(ctx *gin.Context) {
+	order, paymentID, ok := c.loadOrderPayment(ctx)
+	if !ok {
+		return
+	}
+
+	payment, err := c.orders.ConfirmPayment(ctx, order, paymentID)
+	c.writeCheckoutResult(ctx, payment, err)
+}
+
+func (c *OrderController) writeCheckoutResult(ctx *gin.Context, payment *models.Payment, err error) {
+	switch {
+	case errors.Is(err, services.ErrPaymentDeclined):
+		ctx.JSON(http.StatusPaymentRequired, gin.H{"error": payment.DeclineReason, "payment": toPublicPayment(*payment)})
+	case err != nil:
+		c.writeOrderError(ctx, err, "Failed to check out")
+	case payment.Status == string(models.PaymentRequiresAction):
+		ctx.JSON(http.StatusAccepted, gin.H{"message": "The payment needs to be confirmed", "payment": toPublicPayment(*payment)})
+	default:
+		ctx.JSON(http.StatusOK, gin.H{"message": "Order placed successfully", "payment": toPublicPayment(*payment)})
+	}
+}
+
+func (c *OrderController) handleCapturePayment(ctx *gin.Context) {
+	order, paymentID, ok := c.loadOrderPayment(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := bindPaymentAmountPayload(ctx)
+	if !ok {
+		return
+	}
+
+	payment, err := c.orders.CapturePayment(ctx, order, paymentID, payload.Amount)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		c.writeOrderError(ctx, err, "Failed to capture payment")
 		return
 	}
+	ctx.JSON(http.StatusOK, toPublicPayment(*payment))
+}
 
-	itemID, err := strconv.Atoi(ctx.Param("item_id"))
+func (c *OrderController) handleVoidPayment(ctx *gin.Context) {
+	order, paymentID, ok := c.loadOrderPayment(ctx)
+	if !ok {
+		return
+	}
+
+	payment, err := c.orders.VoidPayment(ctx, order, paymentID)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		c.writeOrderError(ctx, err, "Failed to void payment")
 		return
 	}
+	ctx.JSON(http.StatusOK, toPublicPayment(*payment))
+}
 
-	var order models.Order
-	if result := c.db.First(&order, orderID); result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+func (c *OrderController) handleRefundPayment(ctx *gin.Context) {
+	order, paymentID, ok := c.loadOrderPayment(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := bindPaymentAmountPayload(ctx)
+	if !ok {
 		return
 	}
 
-	var item models.OrderItem
-	if result := c.db.First(&item, itemID); result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order item not found"})
+	payment, err := c.orders.RefundPayment(ctx, order, paymentID, payload.Amount)
+	if err != nil {
+		c.writeOrderError(ctx, err, "Failed to refund payment")
 		return
 	}
+	ctx.JSON(http.StatusOK, toPublicPayment(*payment))
+}
 
-	if item.OrderID != order.ID {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Item does not belong to this order"})
+// handleGetInvoice downloads the order's invoice as PDF, or as HTML with
+// ?format=html. The invoice is issued when the payment is captured.
+func (c *OrderController) handleGetInvoice(ctx *gin.Context) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
+		return
+	}
+	format := ctx.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "html" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected pdf or html"})
 		return
 	}
 
-	if err := c.db.Delete(&item).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item from order"})
+	doc, err := c.invoices.Document(ctx, order.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvoiceNotIssued) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("failed to load invoice", "order_id", order.ID, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load invoice"})
 		return
 	}
 
-	order.RefreshPrice()
-	if err := c.db.Save(&order).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order total amount"})
+	var body bytes.Buffer
+	contentType, disposition := "application/pdf", "attachment"
+	if format == "html" {
+		contentType, disposition = "text/html; charset=utf-8", "inline"
+		err = invoice.WriteHTML(&body, doc)
+	} else {
+		err = invoice.WritePDF(&body, doc)
+	}
+	if err != nil {
+		slog.Error("failed to render invoice", "order_id", order.ID, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render invoice"})
 		return
 	}
+	ctx.Header("Content-Disposition", fmt.Sprintf(`%s; filename="invoice-%s.%s"`, disposition, doc.Number, format))
+	ctx.Data(http.StatusOK, contentType, body.Bytes())
+}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Item removed from order successfully"})
+// bindPaymentAmountPayload allows an empty body, which means the full amount.
+func bindPaymentAmountPayload(ctx *gin.Context) (models.PaymentAmountPayload, bool) {
+	var payload models.PaymentAmountPayload
+	if ctx.Request.ContentLength == 0 {
+		return payload, true
+	}
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return payload, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return payload, false
+	}
+	return payload, true
 }
 
-func (c *OrderController) handleDeleteOrder(ctx *gin.Context) {
+// loadOrderPayment loads the order like loadOwnOrder and parses :payment_id.
+func (c *OrderController) loadOrderPayment(ctx *gin.Context) (*models.Order, uint, bool) {
+	order, ok := c.loadOwnOrder(ctx)
+	if !ok {
+		return nil, 0, false
+	}
+	paymentID, err := strconv.Atoi(ctx.Param("payment_id"))
+	if err != nil || paymentID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return nil, 0, false
+	}
+	return order, uint(paymentID), true
+}
+
+// loadOwnOrder loads the order from the URL if the caller placed it, as a user
+// or a guest, or is an admin. It writes the error response itself.
+func (c *OrderController) loadOwnOrder(ctx *gin.Context) (*models.Order, bool) {
 	orderID, err := strconv.Atoi(ctx.Param("id"))
-	if err != nil {
+	if err != nil || orderID <= 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
-		return
+		return nil, false
 	}
 
 	var order models.Order
-	if result := c.db.First(&order, orderID); result.Error != nil {
+	if err := c.db.Preload("OrderItems").First(&order, orderID).Error; err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
-		return
+		return nil, false
 	}
 
-	if err := c.db.Delete(&order).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete order"})
-		return
+	if !ownsOrder(ctx, &order) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return nil, false
 	}
+	return &order, true
+}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Order deleted successfully"})
+// productsPurchasable checks that every product is published and so can be
+// ordered. It writes the error response itself.
+func (c *OrderController) productsPurchasable(ctx *gin.Context, productIDs []uint) bool {
+	var count int64
+	err := c.db.Model(&models.Product{}).
+		Where("id IN ? AND status = ?", productIDs, models.ProductPublished).
+		Distinct("id").Count(&count).Error
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+		return false
+	}
+	if int(count) != len(slices.Compact(slices.Sorted(slices.Values(productIDs)))) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "A product in the order is not available"})
+		return false
+	}
+	return true
+}
+
+// ownsOrder reports whether the caller placed the order or is an admin.
+// Guests own the orders made with their guest token until they claim them.
+func ownsOrder(ctx *gin.Context, order *models.Order) bool {
+	if guestID, ok := ctx.Get("guestID"); ok {
+		return order.GuestID != nil && *order.GuestID == guestID.(string)
+	}
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+	if order.UserID != nil && *order.UserID == userID.(uint) {
+		return true
+	}
+	return models.Role(role.(string)) == models.AdminRole
+}
+
+// writeOrderError maps errors from the order service to responses. Rejected
+// coupons and shipping choices are explained to the customer.
+func (c *OrderController) writeOrderError(ctx *gin.Context, err error, message string) {
+	switch {
+	case services.IsCouponError(err), errors.Is(err, services.ErrShippingUnavailable),
+		errors.Is(err, services.ErrShippingShopTwice):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOrderNotEditable), errors.Is(err, repositories.ErrOrderChanged),
+		errors.Is(err, repositories.ErrInsufficientStock):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOrderEmpty), errors.Is(err, services.ErrShippingAddressRequired),
+		errors.Is(err, services.ErrEmailRequired), errors.Is(err, services.ErrCouponAccountRequired),
+		errors.Is(err, repositories.ErrProductUnavailable):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case services.IsPaymentError(err):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+	default:
+		slog.Error("failed to update order", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
 }
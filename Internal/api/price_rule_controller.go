@@ -0,0 +1,256 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type PriceRuleController struct {
+	db *gorm.DB
+}
+
+func NewPriceRuleController(db *gorm.DB) *PriceRuleController {
+	return &PriceRuleController{db: db}
+}
+
+type PublicPriceRule struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	Type        string             `json:"type"`
+	Scope       string             `json:"scope"`
+	ScopeID     *uint              `json:"scope_id"`
+	ShopID      *uint              `json:"shop_id"`
+	PercentOff  float64            `json:"percent_off"`
+	BuyQuantity int                `json:"buy_quantity,omitempty"`
+	GetQuantity int                `json:"get_quantity,omitempty"`
+	Tiers       []models.PriceTier `json:"tiers,omitempty"`
+	StartsAt    *time.Time         `json:"starts_at"`
+	EndsAt      *time.Time         `json:"ends_at"`
+	Active      bool               `json:"active"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+func toPublicPriceRule(rule models.PriceRule) PublicPriceRule {
+	return PublicPriceRule{
+		ID:          rule.ID,
+		Name:        rule.Name,
+		Type:        rule.Type,
+		Scope:       rule.Scope,
+		ScopeID:     rule.ScopeID,
+		ShopID:      rule.ShopID,
+		PercentOff:  rule.PercentOff,
+		BuyQuantity: rule.BuyQuantity,
+		GetQuantity: rule.GetQuantity,
+		Tiers:       rule.Tiers(),
+		StartsAt:    rule.StartsAt,
+		EndsAt:      rule.EndsAt,
+		Active:      rule.Active,
+		CreatedAt:   rule.CreatedAt,
+	}
+}
+
+// handleGetPriceRules lists every rule for admins and the shop's own rules
+// for sellers.
+func (c *PriceRuleController) handleGetPriceRules(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	query := c.db.Order("id DESC")
+	if models.Role(user.Role) != models.AdminRole {
+		query = query.Where("shop_id = ?", user.Shop.ID)
+	}
+
+	var rules []models.PriceRule
+	if err := query.Find(&rules).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price rules"})
+		return
+	}
+
+	publicRules := make([]PublicPriceRule, len(rules))
+	for i, rule := range rules {
+		publicRules[i] = toPublicPriceRule(rule)
+	}
+	ctx.JSON(http.StatusOK, publicRules)
+}
+
+func (c *PriceRuleController) handleGetPriceRule(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+	rule, ok := c.loadPriceRule(ctx, user)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicPriceRule(*rule))
+}
+
+func (c *PriceRuleController) handleCreatePriceRule(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+	payload, ok := c.bindPriceRulePayload(ctx, user)
+	if !ok {
+		return
+	}
+
+	rule := models.PriceRule{}
+	applyPriceRulePayload(&rule, payload)
+	if models.Role(user.Role) != models.AdminRole {
+		rule.ShopID = &user.Shop.ID
+	}
+
+	if err := c.db.WithContext(ctx).Create(&rule).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price rule"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, toPublicPriceRule(rule))
+}
+
+// handleUpdatePriceRule replaces the rule's settings. Carts pick up the change
+// the next time they are repriced; placed orders keep the prices they had.
+func (c *PriceRuleController) handleUpdatePriceRule(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+	rule, ok := c.loadPriceRule(ctx, user)
+	if !ok {
+		return
+	}
+	payload, ok := c.bindPriceRulePayload(ctx, user)
+	if !ok {
+		return
+	}
+
+	applyPriceRulePayload(rule, payload)
+	if err := c.db.WithContext(ctx).Save(rule).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update price rule"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicPriceRule(*rule))
+}
+
+func (c *PriceRuleController) handleDeletePriceRule(ctx *gin.Context) {
+	user, ok := loadPromotionManager(ctx, c.db)
+	if !ok {
+		return
+	}
+	rule, ok := c.loadPriceRule(ctx, user)
+	if !ok {
+		return
+	}
+
+	if err := c.db.WithContext(ctx).Delete(rule).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete price rule"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Price rule deleted successfully"})
+}
+
+func (c *PriceRuleController) loadPriceRule(ctx *gin.Context, user *models.User) (*models.PriceRule, bool) {
+	ruleID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || ruleID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price rule ID"})
+		return nil, false
+	}
+
+	var rule models.PriceRule
+	if err := c.db.First(&rule, ruleID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Price rule not found"})
+		return nil, false
+	}
+	if models.Role(user.Role) != models.AdminRole && (rule.ShopID == nil || *rule.ShopID != user.Shop.ID) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Price rule not found"})
+		return nil, false
+	}
+	return &rule, true
+}
+
+// bindPriceRulePayload binds a rule and checks it has the settings its type
+// needs.
+func (c *PriceRuleController) bindPriceRulePayload(ctx *gin.Context, user *models.User) (*models.PriceRulePayload, bool) {
+	var payload models.PriceRulePayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return nil, false
+	}
+
+	fail := func(message string) (*models.PriceRulePayload, bool) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return nil, false
+	}
+
+	switch models.PriceRuleType(payload.Type) {
+	case models.SaleRule:
+		if payload.PercentOff == 0 {
+			return fail("Sale rules need percent_off")
+		}
+		payload.BuyQuantity, payload.GetQuantity, payload.Tiers = 0, 0, nil
+	case models.TieredRule:
+		if len(payload.Tiers) == 0 {
+			return fail("Tiered rules need at least one tier")
+		}
+		seen := make(map[int]bool, len(payload.Tiers))
+		for _, tier := range payload.Tiers {
+			if seen[tier.MinQuantity] {
+				return fail("Tiers must have different min_quantity values")
+			}
+			seen[tier.MinQuantity] = true
+		}
+		payload.PercentOff, payload.BuyQuantity, payload.GetQuantity = 0, 0, 0
+	case models.BuyXGetYRule:
+		if payload.BuyQuantity == 0 || payload.GetQuantity == 0 {
+			return fail("Buy-x-get-y rules need buy_quantity and get_quantity")
+		}
+		if payload.PercentOff == 0 {
+			payload.PercentOff = 100
+		}
+		payload.Tiers = nil
+	}
+
+	if payload.StartsAt != nil && payload.EndsAt != nil && !payload.EndsAt.After(*payload.StartsAt) {
+		return fail("ends_at must be after starts_at")
+	}
+	if message := checkPromotionScope(c.db, user, payload.Scope, &payload.ScopeID); message != "" {
+		return fail(message)
+	}
+
+	return &payload, true
+}
+
+func applyPriceRulePayload(rule *models.PriceRule, payload *models.PriceRulePayload) {
+	rule.Name = payload.Name
+	rule.Type = payload.Type
+	rule.Scope = payload.Scope
+	rule.ScopeID = payload.ScopeID
+	rule.PercentOff = payload.PercentOff
+	rule.BuyQuantity = payload.BuyQuantity
+	rule.GetQuantity = payload.GetQuantity
+	rule.TierList = ""
+	if len(payload.Tiers) > 0 {
+		rule.SetTiers(payload.Tiers)
+	}
+	rule.StartsAt = payload.StartsAt
+	rule.EndsAt = payload.EndsAt
+	rule.Active = payload.Active == nil || *payload.Active
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PrivacyController struct {
+	service *services.PrivacyService
+}
+
+func NewPrivacyController(service *services.PrivacyService) *PrivacyController {
+	return &PrivacyController{service: service}
+}
+
+type PublicErasureRequest struct {
+	ID           uint      `json:"id"`
+	Status       string    `json:"status"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// handleExportUserData returns everything stored about the caller, as a single
+// JSON document or, with ?format=zip, as a ZIP archive with one file per section.
+func (c *PrivacyController) handleExportUserData(ctx *gin.Context) {
+	userID, _ := ctx.Get("userID")
+	export, err := c.service.ExportUserData(userID.(uint))
+	if err != nil {
+		slog.Error("failed to export user data", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data"})
+		return
+	}
+
+	filename := fmt.Sprintf("user-%d-export-%s", export.Profile.ID, export.GeneratedAt.Format("20060102"))
+
+	if ctx.Query("format") != "zip" {
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		ctx.JSON(http.StatusOK, export)
+		return
+	}
+
+	sections := map[string]interface{}{
+		"profile.json":             export.Profile,
+		"shop.json":                export.Shop,
+		"shop_applications.json":   export.ShopApplications,
+		"addresses.json":           export.Addresses,
+		"orders.json":              export.Orders,
+		"reviews.json":             export.Reviews,
+		"api_keys.json":            export.APIKeys,
+		"stock_subscriptions.json": export.StockSubscriptions,
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Status(http.StatusOK)
+
+	archive := zip.NewWriter(ctx.Writer)
+	for name, section := range sections {
+		file, err := archive.Create(name)
+		if err != nil {
+			slog.Error("failed to write export archive", "error", err)
+			return
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section); err != nil {
+			slog.Error("failed to write export archive", "error", err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		slog.Error("failed to write export archive", "error", err)
+	}
+}
+
+func (c *PrivacyController) handleGetErasure(ctx *gin.Context) {
+	userID, _ := ctx.Get("userID")
+	request, err := c.service.GetScheduledErasure(userID.(uint))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No erasure is scheduled"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch erasure request"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, PublicErasureRequest{
+		ID:           request.ID,
+		Status:       request.Status,
+		ScheduledFor: request.ScheduledFor,
+		CreatedAt:    request.CreatedAt,
+	})
+}
+
+func (c *PrivacyController) handleRequestErasure(ctx *gin.Context) {
+	if rejectImpersonation(ctx) {
+		return
+	}
+	userID, _ := ctx.Get("userID")
+	request, err := c.service.RequestErasure(ctx, userID.(uint))
+	if err != nil {
+		if errors.Is(err, services.ErrErasurePending) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "An erasure is already scheduled"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule erasure"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "Your account will be erased at the end of the grace period",
+		"erasure": PublicErasureRequest{
+			ID:           request.ID,
+			Status:       request.Status,
+			ScheduledFor: request.ScheduledFor,
+			CreatedAt:    request.CreatedAt,
+		},
+	})
+}
+
+func (c *PrivacyController) handleCancelErasure(ctx *gin.Context) {
+	if rejectImpersonation(ctx) {
+		return
+	}
+	userID, _ := ctx.Get("userID")
+	if err := c.service.CancelErasure(ctx, userID.(uint)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No erasure is scheduled"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel erasure"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Erasure cancelled"})
+}
+
+// handleEraseUser is the admin override that erases a user immediately.
+func (c *PrivacyController) handleEraseUser(ctx *gin.Context) {
+	targetUserID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || targetUserID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	adminID, _ := ctx.Get("userID")
+	if err := c.service.EraseNow(ctx, uint(targetUserID), adminID.(uint)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		slog.Error("failed to erase user", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to erase user"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User erased successfully"})
+}
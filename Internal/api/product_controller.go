@@ -4,8 +4,11 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/Archnick/go-ecommerce/Internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
@@ -20,43 +23,143 @@ func NewProductController(db *gorm.DB) *ProductController {
 }
 
 type PublicProduct struct {
-	ID          uint            `json:"id"`
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Price       float64         `json:"price"`
-	Stock       int             `json:"stock"`
-	ShopID      uint            `json:"shop_id"`
-	CategoryID  uint            `json:"category_id"`
-	Images      []string        `json:"images"`
-	Reviews     []models.Review `json:"reviews,omitempty"`
+	ID          uint                 `json:"id"`
+	SKU         string               `json:"sku,omitempty"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Price       float64              `json:"price"`
+	SalePrice   *float64             `json:"sale_price,omitempty"`
+	SaleName    string               `json:"sale_name,omitempty"`
+	SaleEndsAt  *time.Time           `json:"sale_ends_at,omitempty"`
+	Stock       int                  `json:"stock"`
+	ShopID      uint                 `json:"shop_id"`
+	CategoryID  uint                 `json:"category_id"`
+	TaxCategory string               `json:"tax_category"`
+	WeightGrams int                  `json:"weight_grams"`
+	LengthCm    float64              `json:"length_cm"`
+	WidthCm     float64              `json:"width_cm"`
+	HeightCm    float64              `json:"height_cm"`
+	Status      string               `json:"status"`
+	PublishAt   *time.Time           `json:"publish_at,omitempty"`
+	Images      []PublicProductImage `json:"images"`
+	Reviews     []models.Review      `json:"reviews,omitempty"`
 }
 
-// handleGetProducts now takes a *gin.Context.
+// visibleProducts limits a query to the products the caller may see: published
+// ones for everyone, plus their own shop's for sellers and all for admins.
+func visibleProducts(ctx *gin.Context, db *gorm.DB) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if role, _ := ctx.Get("role"); role == string(models.AdminRole) {
+			return query
+		}
+		userID, ok := ctx.Get("userID")
+		if !ok {
+			return query.Where("status = ?", models.ProductPublished)
+		}
+		ownShops := db.Model(&models.Shop{}).Select("id").Where("user_id = ?", userID)
+		return query.Where("status = ? OR shop_id IN (?)", models.ProductPublished, ownShops)
+	}
+}
+
+// productVisible reports whether the caller may see the product. It writes
+// the not-found response itself.
+func productVisible(ctx *gin.Context, db *gorm.DB, productID int) bool {
+	var count int64
+	db.Model(&models.Product{}).Scopes(visibleProducts(ctx, db)).Where("id = ?", productID).Count(&count)
+	if count == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return false
+	}
+	return true
+}
+
+// setSalePrice shows the best running sale on the product, if any.
+func setSalePrice(publicProduct *PublicProduct, product models.Product, sales []models.PriceRule) {
+	price, rule := services.SalePrice(product, sales)
+	if rule == nil {
+		return
+	}
+	publicProduct.SalePrice = price
+	publicProduct.SaleName = rule.Name
+	publicProduct.SaleEndsAt = rule.EndsAt
+}
+
+// skuTaken reports whether another product of the shop already uses sku.
+func (c *ProductController) skuTaken(shopID uint, sku string, exceptID uint) bool {
+	var count int64
+	c.db.Model(&models.Product{}).Where("shop_id = ? AND sku = ? AND id <> ?", shopID, sku, exceptID).Count(&count)
+	return count > 0
+}
+
+// productStatusFor works out the stored status from the requested one and an
+// optional publish time. A future publish time schedules anything not archived.
+func productStatusFor(requested models.ProductStatus, publishAt *time.Time, now time.Time) models.ProductStatus {
+	if publishAt != nil && requested != models.ProductArchived {
+		if publishAt.After(now) {
+			return models.ProductScheduled
+		}
+		if requested == "" {
+			return models.ProductPublished
+		}
+	}
+	if requested == "" {
+		return models.ProductDraft
+	}
+	return requested
+}
+
+// handleGetProducts lists the products visible to the caller, optionally
+// filtered by ?status= and ?shop_id=.
 func (c *ProductController) handleGetProducts(ctx *gin.Context) {
 	var products []models.Product
 
-	result := c.db.Preload("Images").Find(&products)
+	query := c.db.Scopes(visibleProducts(ctx, c.db))
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if shopID := ctx.Query("shop_id"); shopID != "" {
+		id, err := strconv.Atoi(shopID)
+		if err != nil || id <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+			return
+		}
+		query = query.Where("shop_id = ?", id)
+	}
+
+	result := query.Preload("Images", orderedImages).Find(&products)
 
 	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
 		return
 	}
 
+	sales, err := repositories.FindActivePriceRules(c.db, time.Now(), models.SaleRule)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
 	publicProducts := make([]PublicProduct, len(products))
 	for i, product := range products {
 		publicProducts[i] = PublicProduct{
 			ID:          product.ID,
+			SKU:         product.SKU,
 			Name:        product.Name,
 			Description: product.Description,
 			Price:       product.Price,
 			Stock:       product.Stock,
 			ShopID:      product.ShopID,
 			CategoryID:  product.CategoryID,
-			Images:      make([]string, len(product.Images)),
-		}
-		for j, image := range product.Images {
-			publicProducts[i].Images[j] = image.ImageURL
+			TaxCategory: product.TaxCategory,
+			WeightGrams: product.WeightGrams,
+			LengthCm:    product.LengthCm,
+			WidthCm:     product.WidthCm,
+			HeightCm:    product.HeightCm,
+			Status:      product.Status,
+			PublishAt:   product.PublishAt,
+			Images:      toPublicProductImages(product.Images),
 		}
+		setSalePrice(&publicProducts[i], product, sales)
 	}
 	ctx.JSON(http.StatusOK, publicProducts)
 }
@@ -69,7 +172,7 @@ func (c *ProductController) handleGetProduct(ctx *gin.Context) {
 	}
 
 	var product models.Product
-	result := c.db.Preload("Images").Preload("Reviews").First(&product, productID)
+	result := c.db.Scopes(visibleProducts(ctx, c.db)).Preload("Images", orderedImages).Preload("Reviews").First(&product, productID)
 
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
@@ -82,18 +185,29 @@ func (c *ProductController) handleGetProduct(ctx *gin.Context) {
 
 	publicProduct := PublicProduct{
 		ID:          product.ID,
+		SKU:         product.SKU,
 		Name:        product.Name,
 		Description: product.Description,
 		Price:       product.Price,
 		Stock:       product.Stock,
 		ShopID:      product.ShopID,
 		CategoryID:  product.CategoryID,
-		Images:      make([]string, len(product.Images)),
+		TaxCategory: product.TaxCategory,
+		WeightGrams: product.WeightGrams,
+		LengthCm:    product.LengthCm,
+		WidthCm:     product.WidthCm,
+		HeightCm:    product.HeightCm,
+		Status:      product.Status,
+		PublishAt:   product.PublishAt,
+		Images:      toPublicProductImages(product.Images),
 		Reviews:     product.Reviews,
 	}
-	for j, image := range product.Images {
-		publicProduct.Images[j] = image.ImageURL
+	sales, err := repositories.FindActivePriceRules(c.db, time.Now(), models.SaleRule)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		return
 	}
+	setSalePrice(&publicProduct, product, sales)
 	ctx.JSON(http.StatusOK, publicProduct)
 }
 
@@ -107,7 +221,7 @@ func (c *ProductController) handleCreateProduct(ctx *gin.Context) {
 	}
 	var user models.User
 	userID, _ := ctx.Get("userID")
-	if result := c.db.First(&user, userID); result.Error != nil {
+	if result := c.db.Preload("Shop").First(&user, userID); result.Error != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -122,18 +236,55 @@ func (c *ProductController) handleCreateProduct(ctx *gin.Context) {
 		}
 		shopId = payload.ShopID
 	}
+	if shopId == 0 {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You need a shop to create products"})
+		return
+	}
+
+	if payload.SKU != "" && c.skuTaken(shopId, payload.SKU, 0) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A product with this SKU already exists in the shop"})
+		return
+	}
 
 	product := models.Product{
-		Name:        payload.Name,
-		Description: payload.Description,
-		Price:       payload.Price,
-		Stock:       payload.Stock,
-		ShopID:      shopId,
-		CategoryID:  payload.CategoryID,
+		SKU:               payload.SKU,
+		Name:              payload.Name,
+		Description:       payload.Description,
+		Price:             payload.Price,
+		ShopID:            shopId,
+		CategoryID:        payload.CategoryID,
+		LowStockThreshold: payload.LowStockThreshold,
+		TaxCategory:       payload.TaxCategory,
+		WeightGrams:       payload.WeightGrams,
+		LengthCm:          payload.LengthCm,
+		WidthCm:           payload.WidthCm,
+		HeightCm:          payload.HeightCm,
+		Status:            string(productStatusFor(models.ProductStatus(payload.Status), payload.PublishAt, time.Now())),
+		PublishAt:         payload.PublishAt,
 	}
 
-	result := c.db.Create(&product)
-	if result.Error != nil {
+	// Initial stock goes through the inventory ledger like any other change.
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		if payload.Stock == 0 {
+			return nil
+		}
+		actorID := userID.(uint)
+		return repositories.RecordInventoryMovement(tx, &models.InventoryMovement{
+			ProductID: product.ID,
+			Quantity:  payload.Stock,
+			Reason:    string(models.InventoryRestock),
+			Note:      "initial stock",
+			ActorID:   &actorID,
+		})
+	})
+	if repositories.IsUniqueViolation(err, repositories.ProductSKUConstraint) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A product with this SKU already exists in the shop"})
+		return
+	}
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
 		return
 	}
@@ -149,7 +300,7 @@ func (c *ProductController) handleUpdateProduct(ctx *gin.Context) {
 	}
 	var user models.User
 	userID, _ := ctx.Get("userID")
-	if result := c.db.First(&user, userID); result.Error != nil {
+	if result := c.db.Preload("Shop").First(&user, userID); result.Error != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -179,10 +330,38 @@ func (c *ProductController) handleUpdateProduct(ctx *gin.Context) {
 		return
 	}
 
+	if payload.SKU != "" {
+		shopID := product.ShopID
+		if payload.ShopID != 0 {
+			shopID = payload.ShopID
+		}
+		if c.skuTaken(shopID, payload.SKU, product.ID) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "A product with this SKU already exists in the shop"})
+			return
+		}
+	}
+
 	// Update the Product Record
 	// GORM's Updates method will only update non-zero fields,
 	// which works perfectly with our optional payload.
-	if err := c.db.Model(&product).Updates(payload).Error; err != nil {
+	err = c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&product).Updates(payload).Error; err != nil {
+			return err
+		}
+		if payload.Status == "" && payload.PublishAt == nil {
+			return nil
+		}
+		// An explicit status without a publish time cancels any schedule.
+		return tx.Model(&product).Updates(map[string]interface{}{
+			"status":     productStatusFor(models.ProductStatus(payload.Status), payload.PublishAt, time.Now()),
+			"publish_at": payload.PublishAt,
+		}).Error
+	})
+	if repositories.IsUniqueViolation(err, repositories.ProductSKUConstraint) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A product with this SKU already exists in the shop"})
+		return
+	}
+	if err != nil {
 		slog.Error("failed to update product", "error", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
 		return
@@ -200,7 +379,7 @@ func (c *ProductController) handleDeleteProduct(ctx *gin.Context) {
 
 	var user models.User
 	userID, _ := ctx.Get("userID")
-	if result := c.db.First(&user, userID); result.Error != nil {
+	if result := c.db.Preload("Shop").First(&user, userID); result.Error != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -213,7 +392,7 @@ func (c *ProductController) handleDeleteProduct(ctx *gin.Context) {
 		return
 	}
 
-	result := c.db.Delete(&models.Product{}, productID)
+	result := c.db.WithContext(ctx).Delete(&models.Product{}, productID)
 	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product"})
 		return
@@ -1,68 +1,107 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/services"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 )
 
 type ProductImageController struct {
 	// Assuming you have a database connection or other dependencies here
-	db *gorm.DB
+	db     *gorm.DB
+	images *services.ImageService
 }
 
 // NewProductImageController creates a new instance of ProductImageController.
-func NewProductImageController(db *gorm.DB) *ProductImageController {
-	return &ProductImageController{db: db}
+func NewProductImageController(db *gorm.DB, images *services.ImageService) *ProductImageController {
+	return &ProductImageController{db: db, images: images}
 }
 
-// handleGetProductImages handles the retrieval of product images.
+type PublicProductImage struct {
+	ID         uint              `json:"id"`
+	URL        string            `json:"url"`
+	AltText    string            `json:"alt_text"`
+	Width      int               `json:"width,omitempty"`
+	Height     int               `json:"height,omitempty"`
+	Position   int               `json:"position"`
+	IsPrimary  bool              `json:"is_primary"`
+	Variant    string            `json:"variant,omitempty"`
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
+}
+
+func toPublicProductImage(image models.ProductImage) PublicProductImage {
+	return PublicProductImage{
+		ID:         image.ID,
+		URL:        image.ImageURL,
+		AltText:    image.AltText,
+		Width:      image.Width,
+		Height:     image.Height,
+		Position:   image.Position,
+		IsPrimary:  image.IsPrimary,
+		Variant:    image.Variant,
+		Thumbnails: image.ThumbnailMap(),
+	}
+}
+
+func toPublicProductImages(images []models.ProductImage) []PublicProductImage {
+	publicImages := make([]PublicProductImage, len(images))
+	for i, image := range images {
+		publicImages[i] = toPublicProductImage(image)
+	}
+	return publicImages
+}
+
+// orderedImages sorts preloaded or queried product images by position.
+func orderedImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// handleGetProductImages handles the retrieval of product images. With
+// ?variant= only that variant's images and the shared ones are returned.
 func (c *ProductImageController) handleGetProductImages(ctx *gin.Context) {
-	productIDStr := ctx.Param("product_id")
+	productIDStr := ctx.Param("id")
 	productID, err := strconv.Atoi(productIDStr)
 	if err != nil || productID <= 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
 		return
 	}
+	if !productVisible(ctx, c.db, productID) {
+		return
+	}
+
+	query := c.db.Scopes(orderedImages).Where("product_id = ?", productID)
+	if variant := ctx.Query("variant"); variant != "" {
+		query = query.Where("variant = ? OR variant = ''", variant)
+	}
 
 	var images []models.ProductImage
-	result := c.db.Where("product_id = ?", productID).Find(&images)
+	result := query.Find(&images)
 
 	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product images"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, images)
+	ctx.JSON(http.StatusOK, toPublicProductImages(images))
 }
 
 func (c *ProductImageController) handleCreateProductImage(ctx *gin.Context) {
-	productIDStr := ctx.Param("product_id")
+	productIDStr := ctx.Param("id")
 	productID, err := strconv.Atoi(productIDStr)
 	if err != nil || productID <= 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
 		return
 	}
 
-	var user models.User
-	userID, _ := ctx.Get("userID")
-	if result := c.db.First(&user, userID); result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
-	var product models.Product
-	// Check if the product exists
-	if result := c.db.First(&product, productID); result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
-		return
-	}
-
-	if models.Role(user.Role) != models.AdminRole && user.Shop.ID != product.ShopID {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to add images to this product"})
+	product, ok := loadEditableProduct(ctx, c.db, uint(productID))
+	if !ok {
 		return
 	}
 
@@ -76,21 +115,71 @@ func (c *ProductImageController) handleCreateProductImage(ctx *gin.Context) {
 	image := models.ProductImage{
 		ImageURL:  payload.ImageURL,
 		AltText:   payload.AltText,
-		ProductID: uint(productID),
+		ProductID: product.ID,
+		Variant:   payload.Variant,
 	}
 
-	result := c.db.Create(&image)
-	if result.Error != nil {
+	if err := c.addImage(ctx, &image, payload.IsPrimary); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product image"})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, image)
+	ctx.JSON(http.StatusCreated, toPublicProductImage(image))
 }
 
-func (c *ProductImageController) handleDeleteProductImage(ctx *gin.Context) {
-	imageIDStr := ctx.Param("image_id")
-	imageID, err := strconv.Atoi(imageIDStr)
+// handleUploadProductImage accepts a multipart image upload ("file", optional
+// "alt_text", "variant" and "is_primary"), stores it with its thumbnails and
+// attaches it to the product.
+func (c *ProductImageController) handleUploadProductImage(ctx *gin.Context) {
+	productIDStr := ctx.Param("id")
+	productID, err := strconv.Atoi(productIDStr)
+	if err != nil || productID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	product, ok := loadEditableProduct(ctx, c.db, uint(productID))
+	if !ok {
+		return
+	}
+
+	file, ok := openUploadedImage(ctx)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	stored, err := c.images.SaveImage(ctx, fmt.Sprintf("products/%d", product.ID), file)
+	if err != nil {
+		respondImageError(ctx, err)
+		return
+	}
+
+	image := models.ProductImage{
+		ImageURL:   stored.URL,
+		AltText:    ctx.PostForm("alt_text"),
+		ProductID:  product.ID,
+		StorageKey: stored.Key,
+		Width:      stored.Width,
+		Height:     stored.Height,
+		Variant:    ctx.PostForm("variant"),
+	}
+	image.SetThumbnails(stored.Thumbnails)
+
+	isPrimary, _ := strconv.ParseBool(ctx.PostForm("is_primary"))
+	if err := c.addImage(ctx, &image, isPrimary); err != nil {
+		c.images.DeleteImage(ctx, stored.Key)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product image"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, toPublicProductImage(image))
+}
+
+// handleUpdateProductImage edits an image's alt text and variant, and makes it
+// the product's primary image when is_primary is set.
+func (c *ProductImageController) handleUpdateProductImage(ctx *gin.Context) {
+	imageID, err := strconv.Atoi(ctx.Param("image_id"))
 	if err != nil || imageID <= 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
 		return
@@ -102,35 +191,224 @@ func (c *ProductImageController) handleDeleteProductImage(ctx *gin.Context) {
 		return
 	}
 
-	var user models.User
-	userID, _ := ctx.Get("userID")
-	if result := c.db.First(&user, userID); result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if _, ok := loadEditableProduct(ctx, c.db, productImage.ProductID); !ok {
 		return
 	}
 
-	var product models.Product
-	// Check if the product exists
-	if result := c.db.First(&product, productImage.ProductID); result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	var payload models.UpdateProductImagePayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
 
-	if models.Role(user.Role) != models.AdminRole && user.Shop.ID != product.ShopID {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to add images to this product"})
+	err = c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Updates skips zero values, so empty fields are left untouched.
+		changes := models.ProductImage{AltText: payload.AltText, Variant: payload.Variant}
+		if err := tx.Model(&productImage).Updates(changes).Error; err != nil {
+			return err
+		}
+		if payload.IsPrimary && !productImage.IsPrimary {
+			return setPrimaryImage(tx, &productImage)
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product image"})
 		return
 	}
 
-	result := c.db.Delete(&models.ProductImage{}, imageID)
-	if result.Error != nil {
+	ctx.JSON(http.StatusOK, toPublicProductImage(productImage))
+}
+
+// handleReorderProductImages sets the position of every image of a product
+// from the order of the given IDs.
+func (c *ProductImageController) handleReorderProductImages(ctx *gin.Context) {
+	productID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || productID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	product, ok := loadEditableProduct(ctx, c.db, uint(productID))
+	if !ok {
+		return
+	}
+
+	var payload models.ReorderProductImagesPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	var images []models.ProductImage
+	if err := c.db.Where("product_id = ?", product.ID).Find(&images).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product images"})
+		return
+	}
+
+	byID := make(map[uint]*models.ProductImage, len(images))
+	for i := range images {
+		byID[images[i].ID] = &images[i]
+	}
+	if len(payload.ImageIDs) != len(images) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "image_ids must list every image of the product exactly once"})
+		return
+	}
+	ordered := make([]models.ProductImage, 0, len(images))
+	for _, id := range payload.ImageIDs {
+		image, ok := byID[id]
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "image_ids must list every image of the product exactly once"})
+			return
+		}
+		delete(byID, id)
+		ordered = append(ordered, *image)
+	}
+
+	err = c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for position := range ordered {
+			if ordered[position].Position == position {
+				continue
+			}
+			if err := tx.Model(&ordered[position]).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder product images"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicProductImages(ordered))
+}
+
+func (c *ProductImageController) handleDeleteProductImage(ctx *gin.Context) {
+	imageIDStr := ctx.Param("image_id")
+	imageID, err := strconv.Atoi(imageIDStr)
+	if err != nil || imageID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	var productImage models.ProductImage
+	if result := c.db.First(&productImage, imageID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product image not found"})
+		return
+	}
+
+	if _, ok := loadEditableProduct(ctx, c.db, productImage.ProductID); !ok {
+		return
+	}
+
+	var rowsAffected int64
+	err = c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.ProductImage{}, imageID)
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+		if rowsAffected == 0 || !productImage.IsPrimary {
+			return nil
+		}
+
+		// Hand the primary flag to the next image in line, if any.
+		var next models.ProductImage
+		err := tx.Scopes(orderedImages).Where("product_id = ?", productImage.ProductID).First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_primary", true).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product image"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
+	if rowsAffected == 0 {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product image not found"})
 		return
 	}
 
+	c.images.DeleteImage(ctx, productImage.StorageKey)
+
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+// loadEditableProduct fetches the product and checks the caller owns its shop
+// or is an admin. It writes the error response itself.
+func loadEditableProduct(ctx *gin.Context, db *gorm.DB, productID uint) (*models.Product, bool) {
+	var user models.User
+	userID, _ := ctx.Get("userID")
+	if result := db.Preload("Shop").First(&user, userID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+
+	var product models.Product
+	if result := db.First(&product, productID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return nil, false
+	}
+
+	if models.Role(user.Role) != models.AdminRole && user.Shop.ID != product.ShopID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to manage this product"})
+		return nil, false
+	}
+	return &product, true
+}
+
+// addImage appends the image after the product's existing ones. The first
+// image of a product always becomes its primary image.
+func (c *ProductImageController) addImage(ctx *gin.Context, image *models.ProductImage, primary bool) error {
+	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ProductImage{}).Where("product_id = ?", image.ProductID).
+			Select("COALESCE(MAX(position), -1) + 1").Scan(&image.Position).Error
+		if err != nil {
+			return err
+		}
+
+		if primary {
+			err := tx.Model(&models.ProductImage{}).Where("product_id = ? AND is_primary", image.ProductID).
+				Update("is_primary", false).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			var primaries int64
+			err := tx.Model(&models.ProductImage{}).Where("product_id = ? AND is_primary", image.ProductID).
+				Count(&primaries).Error
+			if err != nil {
+				return err
+			}
+			primary = primaries == 0
+		}
+
+		image.IsPrimary = primary
+		return tx.Create(image).Error
+	})
+}
+
+// setPrimaryImage makes image the only primary image of its product.
+func setPrimaryImage(tx *gorm.DB, image *models.ProductImage) error {
+	err := tx.Model(&models.ProductImage{}).Where("product_id = ? AND id <> ? AND is_primary", image.ProductID, image.ID).
+		Update("is_primary", false).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(image).Update("is_primary", true).Error
+}
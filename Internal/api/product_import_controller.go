@@ -0,0 +1,216 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ProductImportController struct {
+	db      *gorm.DB
+	service *services.ProductImportService
+}
+
+func NewProductImportController(db *gorm.DB, service *services.ProductImportService) *ProductImportController {
+	return &ProductImportController{db: db, service: service}
+}
+
+type PublicProductImportJob struct {
+	ID           uint                    `json:"id"`
+	ShopID       uint                    `json:"shop_id"`
+	Format       string                  `json:"format"`
+	Status       string                  `json:"status"`
+	TotalRows    int                     `json:"total_rows"`
+	CreatedCount int                     `json:"created"`
+	UpdatedCount int                     `json:"updated"`
+	FailedCount  int                     `json:"failed"`
+	Errors       []models.ImportRowError `json:"errors"`
+	CreatedAt    time.Time               `json:"created_at"`
+	FinishedAt   *time.Time              `json:"finished_at"`
+}
+
+func toPublicProductImportJob(job *models.ProductImportJob) PublicProductImportJob {
+	return PublicProductImportJob{
+		ID:           job.ID,
+		ShopID:       job.ShopID,
+		Format:       job.Format,
+		Status:       job.Status,
+		TotalRows:    job.TotalRows,
+		CreatedCount: job.CreatedCount,
+		UpdatedCount: job.UpdatedCount,
+		FailedCount:  job.FailedCount,
+		Errors:       job.RowErrors(),
+		CreatedAt:    job.CreatedAt,
+		FinishedAt:   job.FinishedAt,
+	}
+}
+
+// handleImportProducts accepts a CSV or JSON Lines file, either as the
+// multipart "file" field or as the raw request body, and queues it for import.
+// The format comes from ?format=, the file extension or the Content-Type.
+func (c *ProductImportController) handleImportProducts(ctx *gin.Context) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, services.MaxImportBytes+uploadOverhead)
+
+	var data []byte
+	var filename string
+	var err error
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		header, formErr := ctx.FormFile("file")
+		if formErr != nil {
+			err = formErr
+		} else {
+			filename = header.Filename
+			file, openErr := header.Open()
+			if openErr != nil {
+				err = openErr
+			} else {
+				data, err = io.ReadAll(file)
+				file.Close()
+			}
+		}
+	} else {
+		data, err = io.ReadAll(ctx.Request.Body)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || len(data) > services.MaxImportBytes {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import file is too large"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+		return
+	}
+	if len(data) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Import file is empty"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	job, err := c.service.StartImport(ctx, shop.ID, userID.(uint), importFormat(ctx, filename), data)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedImportFormat) {
+			ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("failed to start product import", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/shops/%d/products/imports/%d", shop.ID, job.ID))
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "Import started", "job": toPublicProductImportJob(job)})
+}
+
+func (c *ProductImportController) handleGetImportJob(ctx *gin.Context) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	jobID, err := strconv.Atoi(ctx.Param("job_id"))
+	if err != nil || jobID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import job ID"})
+		return
+	}
+
+	job, err := c.service.GetImportJob(shop.ID, uint(jobID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import job"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicProductImportJob(job))
+}
+
+// handleExportProducts streams the shop's whole catalog as CSV (the default)
+// or, with ?format=jsonl, as JSON Lines.
+func (c *ProductImportController) handleExportProducts(ctx *gin.Context) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	format := ctx.DefaultQuery("format", services.ImportFormatCSV)
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case services.ImportFormatCSV:
+	case services.ImportFormatJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": services.ErrUnsupportedImportFormat.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("shop-%d-products-%s.%s", shop.ID, time.Now().Format("20060102"), format)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Header("Content-Type", contentType)
+	ctx.Status(http.StatusOK)
+
+	if err := c.service.Export(ctx, shop.ID, format, ctx.Writer); err != nil {
+		// Headers are already sent, so all we can do is log and cut the stream short.
+		slog.Error("failed to export products", "shop_id", shop.ID, "error", err)
+	}
+}
+
+// loadManagedShop fetches the shop from the :id parameter and checks the
+// caller owns it or is an admin. It writes the error response itself.
+func loadManagedShop(ctx *gin.Context, db *gorm.DB) (*models.Shop, bool) {
+	shopID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || shopID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return nil, false
+	}
+
+	var shop models.Shop
+	if err := db.First(&shop, shopID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+		return nil, false
+	}
+
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+	if models.Role(role.(string)) != models.AdminRole && shop.UserID != userID.(uint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to manage this shop"})
+		return nil, false
+	}
+	return &shop, true
+}
+
+func importFormat(ctx *gin.Context, filename string) string {
+	if format := ctx.Query("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return services.ImportFormatCSV
+	case ".jsonl", ".ndjson":
+		return services.ImportFormatJSONL
+	}
+	switch ctx.ContentType() {
+	case "text/csv":
+		return services.ImportFormatCSV
+	case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+		return services.ImportFormatJSONL
+	}
+	return ""
+}
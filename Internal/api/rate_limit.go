@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// orderLookupLimit is how many order lookups, and separately order claims, a
+// client may make per minute.
+const orderLookupLimit = 10
+
+// TrustedProxiesFromEnv reads TRUSTED_PROXIES, the comma-separated IPs or
+// CIDRs of the proxies in front of the server. Only they may set the client
+// IP with X-Forwarded-For; by default nobody may, so clients cannot pick the
+// IP they are rate limited by.
+func TrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// RateLimitMiddleware allows each client IP limit requests per window and
+// answers 429 beyond that. Counts are kept in memory, so each instance of
+// the server limits on its own.
+func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := &rateLimiter{limit: limit, window: window, windows: make(map[string]*rateWindow)}
+	return func(c *gin.Context) {
+		if retryAfter, ok := limiter.allow(c.ClientIP(), time.Now()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+// allow counts a request from key and reports whether it is within the
+// limit, and if not how long until it would be.
+func (l *rateLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget clients whose window ended, so the map does not grow forever.
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.windows[key] = &rateWindow{start: now, count: 1}
+		return 0, true
+	}
+	if w.count >= l.limit {
+		return w.start.Add(l.window).Sub(now), false
+	}
+	w.count++
+	return 0, true
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// ReturnController handles return requests. Customers open and cancel their
+// returns; the owner of the shop that sold the items, or an admin, approves,
+// rejects, receives and refunds them.
+type ReturnController struct {
+	db      *gorm.DB
+	service *services.ReturnService
+}
+
+func NewReturnController(db *gorm.DB, service *services.ReturnService) *ReturnController {
+	return &ReturnController{db: db, service: service}
+}
+
+type PublicReturn struct {
+	ID              uint               `json:"id"`
+	OrderID         uint               `json:"order_id"`
+	UserID          uint               `json:"user_id"`
+	ShopID          uint               `json:"shop_id"`
+	Status          string             `json:"status"`
+	Note            string             `json:"note"`
+	RejectionReason string             `json:"rejection_reason,omitempty"`
+	RefundAmount    float64            `json:"refund_amount"`
+	Restocked       bool               `json:"restocked"`
+	ReceivedAt      *time.Time         `json:"received_at"`
+	RefundedAt      *time.Time         `json:"refunded_at"`
+	Items           []PublicReturnItem `json:"items"`
+	CreatedAt       time.Time          `json:"created_at"`
+}
+
+type PublicReturnItem struct {
+	OrderItemID uint   `json:"order_item_id"`
+	ProductID   uint   `json:"product_id"`
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason"`
+	Comment     string `json:"comment"`
+}
+
+func toPublicReturn(ret models.Return) PublicReturn {
+	publicReturn := PublicReturn{
+		ID:              ret.ID,
+		OrderID:         ret.OrderID,
+		UserID:          ret.UserID,
+		ShopID:          ret.ShopID,
+		Status:          ret.Status,
+		Note:            ret.Note,
+		RejectionReason: ret.RejectionReason,
+		RefundAmount:    ret.RefundAmount,
+		Restocked:       ret.Restocked,
+		ReceivedAt:      ret.ReceivedAt,
+		RefundedAt:      ret.RefundedAt,
+		Items:           make([]PublicReturnItem, len(ret.Items)),
+		CreatedAt:       ret.CreatedAt,
+	}
+	for i, item := range ret.Items {
+		publicReturn.Items[i] = PublicReturnItem{
+			OrderItemID: item.OrderItemID,
+			ProductID:   item.ProductID,
+			Quantity:    item.Quantity,
+			Reason:      item.Reason,
+			Comment:     item.Comment,
+		}
+	}
+	return publicReturn
+}
+
+func (c *ReturnController) handleCreateReturn(ctx *gin.Context) {
+	orderID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || orderID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var payload models.ReturnPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	var order models.Order
+	if err := c.db.Where("user_id = ?", userID).First(&order, orderID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	ret, err := c.service.RequestReturn(ctx, order.ID, userID.(uint), payload)
+	if err != nil {
+		c.writeReturnError(ctx, err, "Failed to request return")
+		return
+	}
+	ctx.JSON(http.StatusCreated, toPublicReturn(*ret))
+}
+
+// handleGetReturns lists the caller's returns and the returns of their shop,
+// or every return for admins. ?status= filters by status.
+func (c *ReturnController) handleGetReturns(ctx *gin.Context) {
+	var user models.User
+	userID, _ := ctx.Get("userID")
+	if err := c.db.Preload("Shop").First(&user, userID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	isAdmin := models.Role(user.Role) == models.AdminRole
+	returns, err := c.service.ListReturns(isAdmin, user.ID, user.Shop.ID, ctx.Query("status"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch returns"})
+		return
+	}
+
+	publicReturns := make([]PublicReturn, len(returns))
+	for i, ret := range returns {
+		publicReturns[i] = toPublicReturn(ret)
+	}
+	ctx.JSON(http.StatusOK, publicReturns)
+}
+
+func (c *ReturnController) handleGetReturn(ctx *gin.Context) {
+	ret, _, _, ok := c.loadReturn(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicReturn(*ret))
+}
+
+func (c *ReturnController) handleApproveReturn(ctx *gin.Context) {
+	ret, ok := c.loadSellerReturn(ctx)
+	if !ok {
+		return
+	}
+	if err := c.service.Approve(ctx, ret); err != nil {
+		c.writeReturnError(ctx, err, "Failed to approve return")
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicReturn(*ret))
+}
+
+func (c *ReturnController) handleRejectReturn(ctx *gin.Context) {
+	ret, ok := c.loadSellerReturn(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.RejectReturnPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := c.service.Reject(ctx, ret, payload.Reason); err != nil {
+		c.writeReturnError(ctx, err, "Failed to reject return")
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicReturn(*ret))
+}
+
+func (c *ReturnController) handleCancelReturn(ctx *gin.Context) {
+	ret, isCustomer, _, ok := c.loadReturn(ctx)
+	if !ok {
+		return
+	}
+	role, _ := ctx.Get("role")
+	if !isCustomer && models.Role(role.(string)) != models.AdminRole {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only the customer can cancel a return"})
+		return
+	}
+
+	if err := c.service.Cancel(ctx, ret); err != nil {
+		c.writeReturnError(ctx, err, "Failed to cancel return")
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicReturn(*ret))
+}
+
+func (c *ReturnController) handleReceiveReturn(ctx *gin.Context) {
+	ret, ok := c.loadSellerReturn(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.ReceiveReturnPayload
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&payload); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+	}
+
+	restock := payload.Restock == nil || *payload.Restock
+	if err := c.service.Receive(ctx, ret, restock); err != nil {
+		c.writeReturnError(ctx, err, "Failed to receive return")
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicReturn(*ret))
+}
+
+func (c *ReturnController) handleRefundReturn(ctx *gin.Context) {
+	ret, ok := c.loadSellerReturn(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := bindPaymentAmountPayload(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Refund(ctx, ret, payload.Amount); err != nil {
+		c.writeReturnError(ctx, err, "Failed to refund return")
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicReturn(*ret))
+}
+
+// loadReturn loads the :id return if the caller may see it, and reports
+// whether they asked for it and whether they may act for the seller (shop
+// owner or admin). It writes the error response itself.
+func (c *ReturnController) loadReturn(ctx *gin.Context) (*models.Return, bool, bool, bool) {
+	returnID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || returnID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return nil, false, false, false
+	}
+
+	ret, err := c.service.GetReturn(uint(returnID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return nil, false, false, false
+	}
+
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+	isCustomer := ret.UserID == userID.(uint)
+	isSeller := models.Role(role.(string)) == models.AdminRole
+	if !isSeller {
+		var shop models.Shop
+		if err := c.db.First(&shop, ret.ShopID).Error; err == nil && shop.UserID == userID.(uint) {
+			isSeller = true
+		}
+	}
+	if !isCustomer && !isSeller {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return nil, false, false, false
+	}
+	return ret, isCustomer, isSeller, true
+}
+
+func (c *ReturnController) loadSellerReturn(ctx *gin.Context) (*models.Return, bool) {
+	ret, _, isSeller, ok := c.loadReturn(ctx)
+	if !ok {
+		return nil, false
+	}
+	if !isSeller {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only the seller can manage this return"})
+		return nil, false
+	}
+	return ret, true
+}
+
+// writeReturnError maps errors from the return service to responses.
+func (c *ReturnController) writeReturnError(ctx *gin.Context, err error, message string) {
+	switch {
+	case services.IsReturnError(err):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReturnState), errors.Is(err, repositories.ErrReturnChanged),
+		services.IsPaymentError(err):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	default:
+		slog.Error("failed to update return", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -24,12 +24,15 @@ type PublicReview struct {
 }
 
 func (c *ReviewController) handleGetReviewsForProduct(ctx *gin.Context) {
-	productIDStr := ctx.Param("product_id")
+	productIDStr := ctx.Param("id")
 	productID, err := strconv.Atoi(productIDStr)
 	if err != nil || productID <= 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
 		return
 	}
+	if !productVisible(ctx, c.db, productID) {
+		return
+	}
 
 	var reviews []models.Review
 	result := c.db.Where("product_id = ?", productID).Find(&reviews)
@@ -51,7 +54,7 @@ func (c *ReviewController) handleGetReviewsForProduct(ctx *gin.Context) {
 }
 
 func (c *ReviewController) handleGetReviewsForUser(ctx *gin.Context) {
-	userIDStr := ctx.Param("user_id")
+	userIDStr := ctx.Param("id")
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil || userID <= 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -79,7 +82,7 @@ func (c *ReviewController) handleGetReviewsForUser(ctx *gin.Context) {
 }
 
 func (c *ReviewController) handleCreateReview(ctx *gin.Context) {
-	productIDStr := ctx.Param("product_id")
+	productIDStr := ctx.Param("id")
 	productID, err := strconv.Atoi(productIDStr)
 	if err != nil || productID <= 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
@@ -88,7 +91,7 @@ func (c *ReviewController) handleCreateReview(ctx *gin.Context) {
 
 	var user models.User
 	userID, _ := ctx.Get("userID")
-	if result := c.db.First(&user, userID); result.Error != nil {
+	if result := c.db.Preload("Shop").First(&user, userID); result.Error != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -119,7 +122,7 @@ func (c *ReviewController) handleCreateReview(ctx *gin.Context) {
 		ProductID: product.ID,
 	}
 
-	result := c.db.Create(&review)
+	result := c.db.WithContext(ctx).Create(&review)
 	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
 		return
@@ -167,7 +170,7 @@ func (c *ReviewController) handleUpdateReview(ctx *gin.Context) {
 		review.Comment = payload.Comment
 	}
 
-	result := c.db.Save(&review)
+	result := c.db.WithContext(ctx).Save(&review)
 	if result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
 		return
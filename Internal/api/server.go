@@ -1,12 +1,20 @@
 package api
 
 import (
+	"log/slog"
 	"reflect"
 	"strings"
+	"time"
 
+	"github.com/Archnick/go-ecommerce/Internal/invoice"
 	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/notify"
+	"github.com/Archnick/go-ecommerce/Internal/payment"
 	"github.com/Archnick/go-ecommerce/Internal/repositories"
 	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/Archnick/go-ecommerce/Internal/shipping"
+	"github.com/Archnick/go-ecommerce/Internal/storage"
+	"github.com/Archnick/go-ecommerce/Internal/tax"
 	"github.com/gin-gonic/gin" // Import Gin
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/locales/en"
@@ -20,14 +28,31 @@ var trans ut.Translator
 
 // Server holds the dependencies for our API.
 type Server struct {
-	db     *gorm.DB
-	router *gin.Engine // The router is now a Gin Engine
+	db          *gorm.DB
+	router      *gin.Engine // The router is now a Gin Engine
+	apiKeys     *services.APIKeyService
+	privacy     *services.PrivacyService
+	images      *services.ImageService
+	inventory   *services.InventoryService
+	orders      *services.OrderService
+	webhooks    *services.WebhookService
+	returns     *services.ReturnService
+	shopOrders  *services.ShopOrderService
+	ledger      *services.LedgerService
+	invoices    *services.InvoiceService
+	idempotency *services.IdempotencyService
+	notifier    notify.Notifier
 }
 
 // NewServer creates a new Server instance with Gin.
-func NewServer(db *gorm.DB) *Server {
+func NewServer(db *gorm.DB, blobs storage.BlobStore, notifier notify.Notifier, payments payment.Provider, privacy *services.PrivacyService) *Server {
 	// gin.Default() creates a Gin router with default middleware (logger, recovery).
 	router := gin.Default()
+	if err := router.SetTrustedProxies(TrustedProxiesFromEnv()); err != nil {
+		slog.Error("invalid TRUSTED_PROXIES, trusting no proxy", "error", err)
+		router.SetTrustedProxies(nil)
+	}
+	router.Use(RequestIDMiddleware())
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		// Use JSON tag name for field names in errors
 		v.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -45,10 +70,64 @@ func NewServer(db *gorm.DB) *Server {
 		en_translations.RegisterDefaultTranslations(v, trans)
 	}
 
+	images := services.NewImageService(blobs)
+	inventory := services.NewInventoryService(
+		repositories.NewInventoryRepository(db),
+		notifier,
+	)
+	ledger := services.NewLedgerService(
+		repositories.NewLedgerRepository(db),
+		repositories.NewOrderRepository(db),
+		services.LedgerSettingsFromEnv(),
+	)
+	invoices := services.NewInvoiceService(
+		repositories.NewInvoiceRepository(db),
+		invoice.SellerFromEnv(),
+		invoice.PrefixFromEnv(),
+		payment.CurrencyFromEnv(),
+	)
 	s := &Server{
-		db:     db,
-		router: router,
+		db:        db,
+		router:    router,
+		images:    images,
+		inventory: inventory,
+		ledger:    ledger,
+		invoices:  invoices,
+		notifier:  notifier,
+		idempotency: services.NewIdempotencyService(
+			repositories.NewIdempotencyRepository(db),
+			services.IdempotencyTTLFromEnv(),
+		),
+		apiKeys: services.NewAPIKeyService(
+			repositories.NewAPIKeyRepository(db),
+			repositories.NewUserRepository(db),
+		),
+		orders: services.NewOrderService(
+			repositories.NewOrderRepository(db),
+			tax.NewTableCalculator(repositories.NewTaxRepository(db)),
+			tax.PricesIncludeTaxFromEnv(),
+			shipping.NewTableProvider(repositories.NewShippingRepository(db)),
+			payments,
+			payment.CurrencyFromEnv(),
+			inventory,
+			ledger,
+			invoices,
+		),
+		privacy: privacy,
 	}
+	s.webhooks = services.NewWebhookService(repositories.NewPaymentEventRepository(db), s.orders, payments)
+	s.returns = services.NewReturnService(
+		repositories.NewReturnRepository(db),
+		repositories.NewOrderRepository(db),
+		s.orders,
+		inventory,
+		ledger,
+	)
+	s.shopOrders = services.NewShopOrderService(
+		repositories.NewShopOrderRepository(db),
+		repositories.NewShipmentRepository(db),
+		inventory,
+	)
 	s.routes()
 	return s
 }
@@ -61,82 +140,243 @@ func (s *Server) Start(addr string) error {
 
 // routes sets up all the routing for the application using Gin.
 func (s *Server) routes() {
+	mediaController := NewMediaController(s.images)
+	s.router.GET("/media/*key", mediaController.handleGetMedia)
+
 	api := s.router.Group("/api")
 	s.getAuthRoutes(api)
 	s.getUserRoutes(api)
+	s.getAccountRoutes(api)
 	s.getProductRoutes(api)
 	s.getCategoryRoutes(api)
 	s.getShopRoutes(api)
+	s.getShopApplicationRoutes(api)
 	s.getOrderRoutes(api)
+	s.getReturnRoutes(api)
+	s.getCouponRoutes(api)
+	s.getPriceRuleRoutes(api)
+	s.getWebhookRoutes(api)
+	s.getAdminRoutes(api)
+}
+
+// getWebhookRoutes are called by third parties and authenticate each request
+// by its signature instead of a token.
+func (s *Server) getWebhookRoutes(api *gin.RouterGroup) {
+	webhookController := NewWebhookController(s.webhooks)
+	api.POST("/webhooks/payments/:provider", webhookController.handlePaymentWebhook)
 }
 
 func (s *Server) getAuthRoutes(api *gin.RouterGroup) {
 	userRepository := repositories.NewUserRepository(s.db)
 	userService := services.NewUserService(userRepository)
-	authController := NewAuthController(userService)
+	authController := NewAuthController(userService, s.orders)
+	guestController := NewGuestController(s.db, s.orders, s.notifier)
 
 	api.POST("/register", authController.handleRegisterUser)
 	api.POST("/login", authController.handleLogin)
 	api.POST("/refresh", authController.handleRefreshToken)
-	api.POST("/logout", AuthMiddleware(), authController.handleLogout)
+	api.POST("/logout", AuthMiddleware(s.apiKeys), authController.handleLogout)
+	api.POST("/guest/session", guestController.handleCreateGuestSession)
+	// Claims mail codes to order emails, so they are limited like lookups.
+	api.POST("/me/orders/claim", RateLimitMiddleware(orderLookupLimit, time.Minute), AuthMiddleware(s.apiKeys), guestController.handleClaimOrders)
 }
 
 func (s *Server) getUserRoutes(api *gin.RouterGroup) {
 	userRepositery := repositories.NewUserRepository(s.db)
 	userService := services.NewUserService(userRepositery)
-	usersController := NewUsersController(userService)
+	usersController := NewUsersController(userService, s.privacy, s.images)
 	ReviewController := NewReviewController(s.db) //TODO
 
-	api.GET("/users", AuthMiddleware(), usersController.handleGetUsers)
-	api.GET("/users/:id", AuthMiddleware(), usersController.handleGetUser)
-	api.GET("/users/:user_id/reviews", ReviewController.handleGetReviewsForUser)
-	api.PUT("/users/:id", AuthMiddleware(), usersController.handleUpdateUser)
-	api.DELETE("/users/:id", AuthMiddleware(), usersController.handleDeleteUser)
+	api.GET("/users", AuthMiddleware(s.apiKeys), usersController.handleGetUsers)
+	api.GET("/users/:id", AuthMiddleware(s.apiKeys), usersController.handleGetUser)
+	api.GET("/users/:id/reviews", ReviewController.handleGetReviewsForUser)
+	api.PUT("/users/:id", AuthMiddleware(s.apiKeys), usersController.handleUpdateUser)
+	api.POST("/users/:id/photo", AuthMiddleware(s.apiKeys), usersController.handleUploadProfileImage)
+	api.DELETE("/users/:id", AuthMiddleware(s.apiKeys), usersController.handleDeleteUser)
+}
+
+func (s *Server) getAccountRoutes(api *gin.RouterGroup) {
+	apiKeyController := NewAPIKeyController(s.apiKeys)
+	me := api.Group("/me", AuthMiddleware(s.apiKeys))
+	me.GET("/api-keys", apiKeyController.handleGetAPIKeys)
+	me.POST("/api-keys", apiKeyController.handleCreateAPIKey)
+	me.DELETE("/api-keys/:id", apiKeyController.handleRevokeAPIKey)
+
+	addressController := NewAddressController(s.db)
+	me.GET("/addresses", addressController.handleGetAddresses)
+	me.POST("/addresses", addressController.handleCreateAddress)
+	me.GET("/addresses/:id", addressController.handleGetAddress)
+	me.PUT("/addresses/:id", addressController.handleUpdateAddress)
+	me.DELETE("/addresses/:id", addressController.handleDeleteAddress)
+
+	privacyController := NewPrivacyController(s.privacy)
+	me.GET("/export", privacyController.handleExportUserData)
+	me.GET("/erasure", privacyController.handleGetErasure)
+	me.POST("/erasure", privacyController.handleRequestErasure)
+	me.DELETE("/erasure", privacyController.handleCancelErasure)
 }
 
 func (s *Server) getProductRoutes(api *gin.RouterGroup) {
 	productController := NewProductController(s.db)
-	productImageController := NewProductImageController(s.db)
+	productImageController := NewProductImageController(s.db, s.images)
 	reviewController := NewReviewController(s.db)
-	api.GET("/products", productController.handleGetProducts)
-	api.GET("/products/:id", productController.handleGetProduct)
-	api.GET("/products/:product_id/images", productImageController.handleGetProductImages)
-	api.GET("/products/:product_id/reviews", reviewController.handleGetReviewsForProduct)
-	api.POST("/products", AuthMiddleware(), productController.handleCreateProduct)
-	api.POST("/products/:product_id/images", AuthMiddleware(), productImageController.handleCreateProductImage)
-	api.POST("/products/:product_id/reviews", AuthMiddleware(), reviewController.handleCreateReview)
-	api.PUT("/products/:id", AuthMiddleware(), productController.handleUpdateProduct)
-	api.DELETE("/products/:id", AuthMiddleware(), productController.handleDeleteProduct)
+	inventoryController := NewInventoryController(s.db, s.inventory)
+	api.GET("/products", OptionalAuthMiddleware(s.apiKeys), productController.handleGetProducts)
+	api.GET("/products/:id", OptionalAuthMiddleware(s.apiKeys), productController.handleGetProduct)
+	api.GET("/products/:id/images", OptionalAuthMiddleware(s.apiKeys), productImageController.handleGetProductImages)
+	api.GET("/products/:id/reviews", OptionalAuthMiddleware(s.apiKeys), reviewController.handleGetReviewsForProduct)
+	api.POST("/products", AuthMiddleware(s.apiKeys), productController.handleCreateProduct)
+	api.POST("/products/:id/images", AuthMiddleware(s.apiKeys), productImageController.handleCreateProductImage)
+	api.POST("/products/:id/images/upload", AuthMiddleware(s.apiKeys), productImageController.handleUploadProductImage)
+	api.POST("/products/:id/stock/adjust", AuthMiddleware(s.apiKeys), inventoryController.handleAdjustStock)
+	api.GET("/products/:id/stock/movements", AuthMiddleware(s.apiKeys), inventoryController.handleGetStockMovements)
+	api.POST("/products/:id/stock/subscription", AuthMiddleware(s.apiKeys), inventoryController.handleSubscribeStock)
+	api.DELETE("/products/:id/stock/subscription", AuthMiddleware(s.apiKeys), inventoryController.handleUnsubscribeStock)
+	api.PUT("/products/:id/images/order", AuthMiddleware(s.apiKeys), productImageController.handleReorderProductImages)
+	api.POST("/products/:id/reviews", AuthMiddleware(s.apiKeys), reviewController.handleCreateReview)
+	api.PUT("/products/:id", AuthMiddleware(s.apiKeys), productController.handleUpdateProduct)
+	api.DELETE("/products/:id", AuthMiddleware(s.apiKeys), productController.handleDeleteProduct)
 
-	api.DELETE("/product_images/:image_id", AuthMiddleware(), productImageController.handleDeleteProductImage)
-	api.PUT("/reviews/:review_id", AuthMiddleware(), reviewController.handleUpdateReview)
+	api.PUT("/product_images/:image_id", AuthMiddleware(s.apiKeys), productImageController.handleUpdateProductImage)
+	api.DELETE("/product_images/:image_id", AuthMiddleware(s.apiKeys), productImageController.handleDeleteProductImage)
+	api.PUT("/reviews/:review_id", AuthMiddleware(s.apiKeys), reviewController.handleUpdateReview)
 }
 
 func (s *Server) getCategoryRoutes(api *gin.RouterGroup) {
 	categoryController := NewCategoryController(s.db)
 	api.GET("/categories", categoryController.handleGetCategories)
-	api.POST("/categories", AuthMiddleware(), RoleMiddleware(models.AdminRole), categoryController.handleCreateCategory)
-	api.PUT("/categories/:id", AuthMiddleware(), RoleMiddleware(models.AdminRole), categoryController.handleUpdateCategory)
-	api.DELETE("/categories/:id", AuthMiddleware(), RoleMiddleware(models.AdminRole), categoryController.handleDeleteCategory)
+	api.POST("/categories", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), categoryController.handleCreateCategory)
+	api.PUT("/categories/:id", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), categoryController.handleUpdateCategory)
+	api.DELETE("/categories/:id", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), categoryController.handleDeleteCategory)
 }
 
 func (s *Server) getShopRoutes(api *gin.RouterGroup) {
-	shopController := NewShopController(s.db)
+	shopController := NewShopController(s.db, s.images)
+	productImportController := NewProductImportController(s.db,
+		services.NewProductImportService(repositories.NewProductRepository(s.db), s.inventory))
+	shippingProfileController := NewShippingProfileController(s.db)
+	shopOrderController := NewShopOrderController(s.db, s.shopOrders)
+	ledgerController := NewLedgerController(s.db, s.ledger)
 	api.GET("/shops", shopController.handleGetShops)
 	api.GET("/shops/:id", shopController.handleGetShop)
-	api.POST("/shops", AuthMiddleware(), RoleMiddleware(models.AdminRole), shopController.handleCreateShop)
-	api.PUT("/shops/:id", AuthMiddleware(), RoleMiddleware(models.AdminRole), shopController.handleUpdateShop)
-	api.DELETE("/shops/:id", AuthMiddleware(), RoleMiddleware(models.AdminRole), shopController.handleDeleteShop)
+	api.POST("/shops", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), shopController.handleCreateShop)
+	api.PUT("/shops/:id", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), shopController.handleUpdateShop)
+	api.POST("/shops/:id/image", AuthMiddleware(s.apiKeys), shopController.handleUploadShopImage)
+	api.DELETE("/shops/:id", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), shopController.handleDeleteShop)
+	api.POST("/shops/:id/products/import", AuthMiddleware(s.apiKeys), productImportController.handleImportProducts)
+	api.GET("/shops/:id/products/imports/:job_id", AuthMiddleware(s.apiKeys), productImportController.handleGetImportJob)
+	api.GET("/shops/:id/products/export", AuthMiddleware(s.apiKeys), productImportController.handleExportProducts)
+	api.GET("/shops/:id/shipping-profiles", OptionalAuthMiddleware(s.apiKeys), shippingProfileController.handleGetShippingProfiles)
+	api.POST("/shops/:id/shipping-profiles", AuthMiddleware(s.apiKeys), shippingProfileController.handleCreateShippingProfile)
+	api.PUT("/shops/:id/shipping-profiles/:profile_id", AuthMiddleware(s.apiKeys), shippingProfileController.handleUpdateShippingProfile)
+	api.DELETE("/shops/:id/shipping-profiles/:profile_id", AuthMiddleware(s.apiKeys), shippingProfileController.handleDeleteShippingProfile)
+	api.GET("/shops/:id/orders", AuthMiddleware(s.apiKeys), shopOrderController.handleGetShopOrders)
+	api.GET("/shops/:id/orders/:order_id", AuthMiddleware(s.apiKeys), shopOrderController.handleGetShopOrder)
+	api.PUT("/shops/:id/orders/:order_id", AuthMiddleware(s.apiKeys), shopOrderController.handleUpdateShopOrder)
+	api.GET("/shops/:id/orders/:order_id/shipments", AuthMiddleware(s.apiKeys), shopOrderController.handleGetShipments)
+	api.POST("/shops/:id/orders/:order_id/shipments", AuthMiddleware(s.apiKeys), IdempotencyMiddleware(s.idempotency), shopOrderController.handleCreateShipment)
+	api.POST("/shops/:id/orders/:order_id/shipments/:shipment_id/deliver", AuthMiddleware(s.apiKeys), shopOrderController.handleDeliverShipment)
+	api.GET("/shops/:id/balance", AuthMiddleware(s.apiKeys), ledgerController.handleGetShopBalance)
+	api.GET("/shops/:id/statement", AuthMiddleware(s.apiKeys), ledgerController.handleGetShopStatement)
+	api.GET("/shops/:id/payouts", AuthMiddleware(s.apiKeys), ledgerController.handleGetShopPayouts)
+}
+
+func (s *Server) getShopApplicationRoutes(api *gin.RouterGroup) {
+	applicationController := NewShopApplicationController(s.db)
+	api.GET("/shop-applications", AuthMiddleware(s.apiKeys), applicationController.handleGetShopApplications)
+	api.GET("/shop-applications/:id", AuthMiddleware(s.apiKeys), applicationController.handleGetShopApplication)
+	api.POST("/shop-applications", AuthMiddleware(s.apiKeys), applicationController.handleCreateShopApplication)
+	api.POST("/shop-applications/:id/approve", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), applicationController.handleApproveShopApplication)
+	api.POST("/shop-applications/:id/reject", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), applicationController.handleRejectShopApplication)
 }
 
 func (s *Server) getOrderRoutes(api *gin.RouterGroup) {
-	orderController := NewOrderController(s.db)
-	api.GET("/orders", AuthMiddleware(), orderController.handleGetOrders)
-	api.GET("/orders/:id", AuthMiddleware(), orderController.handleGetOrder)
-	api.POST("/orders", AuthMiddleware(), orderController.handleCreateOrder)
-	api.POST("/orders/:id/items", AuthMiddleware(), orderController.handleAddItem)
-	api.PUT("/orders/:id/items/:item_id", AuthMiddleware(), orderController.handleUpdateOrderItem)
-	api.PUT("/orders/:id", AuthMiddleware(), orderController.handleUpdateOrder)
-	api.DELETE("/orders/:id", AuthMiddleware(), orderController.handleDeleteOrder)
-	api.DELETE("/orders/:id/items/:item_id", AuthMiddleware(), orderController.handleRemoveItem)
+	orderController := NewOrderController(s.db, s.orders, s.invoices)
+	idempotent := IdempotencyMiddleware(s.idempotency)
+	// Guests can shop and check out too; the order history and the staff
+	// payment actions need an account.
+	customer := CustomerMiddleware(s.apiKeys)
+	api.GET("/orders", AuthMiddleware(s.apiKeys), orderController.handleGetOrders)
+	// Lookups are limited per client so order numbers cannot be guessed.
+	api.POST("/orders/lookup", RateLimitMiddleware(orderLookupLimit, time.Minute), orderController.handleLookupOrder)
+	api.GET("/orders/:id", customer, orderController.handleGetOrder)
+	api.GET("/orders/:id/invoice", customer, orderController.handleGetInvoice)
+	api.POST("/orders", customer, idempotent, orderController.handleCreateOrder)
+	api.POST("/orders/:id/items", customer, idempotent, orderController.handleAddItem)
+	api.PUT("/orders/:id/items/:item_id", customer, orderController.handleUpdateOrderItem)
+	api.PUT("/orders/:id", customer, orderController.handleUpdateOrder)
+	api.DELETE("/orders/:id", customer, orderController.handleDeleteOrder)
+	api.DELETE("/orders/:id/items/:item_id", customer, orderController.handleRemoveItem)
+	api.POST("/orders/:id/coupons", customer, orderController.handleApplyCoupon)
+	api.DELETE("/orders/:id/coupons/:code", customer, orderController.handleRemoveCoupon)
+	api.GET("/orders/:id/shipping-rates", customer, orderController.handleGetShippingRates)
+	api.PUT("/orders/:id/shipping", customer, orderController.handleSelectShipping)
+	api.POST("/orders/:id/checkout", customer, idempotent, orderController.handleCheckout)
+	api.POST("/orders/:id/payments/:payment_id/confirm", customer, idempotent, orderController.handleConfirmPayment)
+	api.POST("/orders/:id/payments/:payment_id/capture", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), idempotent, orderController.handleCapturePayment)
+	api.POST("/orders/:id/payments/:payment_id/void", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), orderController.handleVoidPayment)
+	api.POST("/orders/:id/payments/:payment_id/refund", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole), idempotent, orderController.handleRefundPayment)
+}
+
+func (s *Server) getReturnRoutes(api *gin.RouterGroup) {
+	returnController := NewReturnController(s.db, s.returns)
+	idempotent := IdempotencyMiddleware(s.idempotency)
+	api.POST("/orders/:id/returns", AuthMiddleware(s.apiKeys), idempotent, returnController.handleCreateReturn)
+	api.GET("/returns", AuthMiddleware(s.apiKeys), returnController.handleGetReturns)
+	api.GET("/returns/:id", AuthMiddleware(s.apiKeys), returnController.handleGetReturn)
+	api.POST("/returns/:id/approve", AuthMiddleware(s.apiKeys), returnController.handleApproveReturn)
+	api.POST("/returns/:id/reject", AuthMiddleware(s.apiKeys), returnController.handleRejectReturn)
+	api.POST("/returns/:id/cancel", AuthMiddleware(s.apiKeys), returnController.handleCancelReturn)
+	api.POST("/returns/:id/receive", AuthMiddleware(s.apiKeys), returnController.handleReceiveReturn)
+	api.POST("/returns/:id/refund", AuthMiddleware(s.apiKeys), idempotent, returnController.handleRefundReturn)
+}
+
+func (s *Server) getCouponRoutes(api *gin.RouterGroup) {
+	couponController := NewCouponController(s.db)
+	api.GET("/coupons", AuthMiddleware(s.apiKeys), couponController.handleGetCoupons)
+	api.GET("/coupons/:id", AuthMiddleware(s.apiKeys), couponController.handleGetCoupon)
+	api.POST("/coupons", AuthMiddleware(s.apiKeys), couponController.handleCreateCoupon)
+	api.PUT("/coupons/:id", AuthMiddleware(s.apiKeys), couponController.handleUpdateCoupon)
+	api.DELETE("/coupons/:id", AuthMiddleware(s.apiKeys), couponController.handleDeleteCoupon)
+}
+
+func (s *Server) getPriceRuleRoutes(api *gin.RouterGroup) {
+	priceRuleController := NewPriceRuleController(s.db)
+	api.GET("/price-rules", AuthMiddleware(s.apiKeys), priceRuleController.handleGetPriceRules)
+	api.GET("/price-rules/:id", AuthMiddleware(s.apiKeys), priceRuleController.handleGetPriceRule)
+	api.POST("/price-rules", AuthMiddleware(s.apiKeys), priceRuleController.handleCreatePriceRule)
+	api.PUT("/price-rules/:id", AuthMiddleware(s.apiKeys), priceRuleController.handleUpdatePriceRule)
+	api.DELETE("/price-rules/:id", AuthMiddleware(s.apiKeys), priceRuleController.handleDeletePriceRule)
+}
+
+func (s *Server) getAdminRoutes(api *gin.RouterGroup) {
+	impersonationController := NewImpersonationController(s.db)
+	auditController := NewAuditController(s.db)
+	privacyController := NewPrivacyController(s.privacy)
+	taxRateController := NewTaxRateController(s.db)
+	webhookController := NewWebhookController(s.webhooks)
+	commissionRateController := NewCommissionRateController(s.db)
+	ledgerController := NewLedgerController(s.db, s.ledger)
+	admin := api.Group("/admin", AuthMiddleware(s.apiKeys), RoleMiddleware(models.AdminRole))
+	admin.POST("/impersonate/:user_id", impersonationController.handleImpersonate)
+	admin.GET("/impersonations", impersonationController.handleGetImpersonationLogs)
+	admin.GET("/audit", auditController.handleGetAuditEvents)
+	admin.POST("/users/:id/erase", privacyController.handleEraseUser)
+	admin.GET("/tax-rates", taxRateController.handleGetTaxRates)
+	admin.POST("/tax-rates", taxRateController.handleCreateTaxRate)
+	admin.PUT("/tax-rates/:id", taxRateController.handleUpdateTaxRate)
+	admin.DELETE("/tax-rates/:id", taxRateController.handleDeleteTaxRate)
+	admin.GET("/payment-events", webhookController.handleGetPaymentEvents)
+	admin.POST("/payment-events/replay", webhookController.handleReplayFailedPaymentEvents)
+	admin.POST("/payment-events/:id/replay", webhookController.handleReplayPaymentEvent)
+	admin.GET("/commission-rates", commissionRateController.handleGetCommissionRates)
+	admin.POST("/commission-rates", commissionRateController.handleCreateCommissionRate)
+	admin.PUT("/commission-rates/:id", commissionRateController.handleUpdateCommissionRate)
+	admin.DELETE("/commission-rates/:id", commissionRateController.handleDeleteCommissionRate)
+	admin.GET("/payout-batches", ledgerController.handleGetPayoutBatches)
+	admin.POST("/payout-batches", IdempotencyMiddleware(s.idempotency), ledgerController.handleCreatePayoutBatch)
+	admin.GET("/payout-batches/:id", ledgerController.handleGetPayoutBatch)
+	admin.POST("/payouts/:id/paid", ledgerController.handleMarkPayoutPaid)
+	admin.POST("/payouts/:id/failed", ledgerController.handleMarkPayoutFailed)
+	admin.GET("/ledger/trial-balance", ledgerController.handleGetTrialBalance)
 }
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/shipping"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type ShippingProfileController struct {
+	db *gorm.DB
+}
+
+func NewShippingProfileController(db *gorm.DB) *ShippingProfileController {
+	return &ShippingProfileController{db: db}
+}
+
+type PublicShippingProfile struct {
+	ID          uint     `json:"id"`
+	ShopID      uint     `json:"shop_id"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Countries   []string `json:"countries"`
+	BaseAmount  float64  `json:"base_amount"`
+	PerKgAmount float64  `json:"per_kg_amount"`
+	FreeOver    *float64 `json:"free_over"`
+	MinDays     int      `json:"min_days"`
+	MaxDays     int      `json:"max_days"`
+	Active      bool     `json:"active"`
+}
+
+func toPublicShippingProfile(profile models.ShippingProfile) PublicShippingProfile {
+	return PublicShippingProfile{
+		ID:          profile.ID,
+		ShopID:      profile.ShopID,
+		Name:        profile.Name,
+		Type:        profile.Type,
+		Countries:   profile.CountryList(),
+		BaseAmount:  profile.BaseAmount,
+		PerKgAmount: profile.PerKgAmount,
+		FreeOver:    profile.FreeOver,
+		MinDays:     profile.MinDays,
+		MaxDays:     profile.MaxDays,
+		Active:      profile.Active,
+	}
+}
+
+// handleGetShippingProfiles lists a shop's active shipping profiles, or all
+// of them with ?all=true for the shop owner and admins.
+func (c *ShippingProfileController) handleGetShippingProfiles(ctx *gin.Context) {
+	shopID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || shopID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	query := c.db.Where("shop_id = ?", shopID).Order("id")
+	if ctx.Query("all") == "true" {
+		if _, ok := ctx.Get("userID"); !ok {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			return
+		}
+		if _, ok := loadManagedShop(ctx, c.db); !ok {
+			return
+		}
+	} else {
+		query = query.Where("active = ?", true)
+	}
+
+	var profiles []models.ShippingProfile
+	if err := query.Find(&profiles).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipping profiles"})
+		return
+	}
+
+	publicProfiles := make([]PublicShippingProfile, len(profiles))
+	for i, profile := range profiles {
+		publicProfiles[i] = toPublicShippingProfile(profile)
+	}
+	ctx.JSON(http.StatusOK, publicProfiles)
+}
+
+func (c *ShippingProfileController) handleCreateShippingProfile(ctx *gin.Context) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return
+	}
+	payload, ok := bindShippingProfilePayload(ctx)
+	if !ok {
+		return
+	}
+
+	profile := models.ShippingProfile{ShopID: shop.ID}
+	applyShippingProfilePayload(&profile, payload)
+	if err := c.db.WithContext(ctx).Create(&profile).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shipping profile"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, toPublicShippingProfile(profile))
+}
+
+// handleUpdateShippingProfile replaces a profile's settings. Carts using it
+// are charged the new rate the next time they are repriced.
+func (c *ShippingProfileController) handleUpdateShippingProfile(ctx *gin.Context) {
+	profile, ok := c.loadShippingProfile(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := bindShippingProfilePayload(ctx)
+	if !ok {
+		return
+	}
+
+	applyShippingProfilePayload(profile, payload)
+	if err := c.db.WithContext(ctx).Save(profile).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shipping profile"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicShippingProfile(*profile))
+}
+
+func (c *ShippingProfileController) handleDeleteShippingProfile(ctx *gin.Context) {
+	profile, ok := c.loadShippingProfile(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.db.WithContext(ctx).Delete(profile).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shipping profile"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Shipping profile deleted successfully"})
+}
+
+func (c *ShippingProfileController) loadShippingProfile(ctx *gin.Context) (*models.ShippingProfile, bool) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return nil, false
+	}
+
+	profileID, err := strconv.Atoi(ctx.Param("profile_id"))
+	if err != nil || profileID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shipping profile ID"})
+		return nil, false
+	}
+
+	var profile models.ShippingProfile
+	if err := c.db.Where("shop_id = ?", shop.ID).First(&profile, profileID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Shipping profile not found"})
+		return nil, false
+	}
+	return &profile, true
+}
+
+func bindShippingProfilePayload(ctx *gin.Context) (*models.ShippingProfilePayload, bool) {
+	var payload models.ShippingProfilePayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return nil, false
+	}
+	if payload.Type == shipping.WeightRate && payload.PerKgAmount == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Weight-based profiles need per_kg_amount"})
+		return nil, false
+	}
+	return &payload, true
+}
+
+func applyShippingProfilePayload(profile *models.ShippingProfile, payload *models.ShippingProfilePayload) {
+	profile.Name = payload.Name
+	profile.Type = payload.Type
+	profile.SetCountryList(payload.Countries)
+	profile.BaseAmount = payload.BaseAmount
+	profile.PerKgAmount = payload.PerKgAmount
+	profile.FreeOver = payload.FreeOver
+	profile.MinDays = payload.MinDays
+	profile.MaxDays = payload.MaxDays
+	profile.Active = payload.Active == nil || *payload.Active
+}
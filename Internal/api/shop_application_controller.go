@@ -0,0 +1,312 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ShopApplicationController struct {
+	db *gorm.DB
+}
+
+func NewShopApplicationController(db *gorm.DB) *ShopApplicationController {
+	return &ShopApplicationController{db: db}
+}
+
+type PublicShopApplication struct {
+	ID              uint       `json:"id"`
+	UserID          uint       `json:"user_id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Policies        string     `json:"policies"`
+	PayoutDetails   string     `json:"payout_details"`
+	Status          string     `json:"status"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	ShopID          *uint      `json:"shop_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func toPublicShopApplication(application models.ShopApplication) PublicShopApplication {
+	return PublicShopApplication{
+		ID:              application.ID,
+		UserID:          application.UserID,
+		Name:            application.Name,
+		Description:     application.Description,
+		Policies:        application.Policies,
+		PayoutDetails:   application.PayoutDetails,
+		Status:          application.Status,
+		RejectionReason: application.RejectionReason,
+		ReviewedAt:      application.ReviewedAt,
+		ShopID:          application.ShopID,
+		CreatedAt:       application.CreatedAt,
+	}
+}
+
+// handleCreateShopApplication lets a customer apply to become a seller.
+func (c *ShopApplicationController) handleCreateShopApplication(ctx *gin.Context) {
+	var payload models.ShopApplicationPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	var user models.User
+	userID, _ := ctx.Get("userID")
+	if result := c.db.Preload("Shop").First(&user, userID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.Shop.ID != 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "You already own a shop"})
+		return
+	}
+
+	var pending int64
+	c.db.Model(&models.ShopApplication{}).
+		Where("user_id = ? AND status = ?", user.ID, models.ApplicationPending).
+		Count(&pending)
+	if pending > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "You already have a pending shop application"})
+		return
+	}
+
+	application := models.ShopApplication{
+		UserID:        user.ID,
+		Name:          payload.Name,
+		Description:   payload.Description,
+		Policies:      payload.Policies,
+		PayoutDetails: payload.PayoutDetails,
+		Status:        string(models.ApplicationPending),
+	}
+
+	if result := c.db.WithContext(ctx).Create(&application); result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit shop application"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, toPublicShopApplication(application))
+}
+
+// handleGetShopApplications returns the admin review queue, or the caller's own
+// applications for everybody else. Admins can filter with ?status=.
+func (c *ShopApplicationController) handleGetShopApplications(ctx *gin.Context) {
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+
+	query := c.db.Order("created_at ASC")
+	if models.Role(role.(string)) != models.AdminRole {
+		query = query.Where("user_id = ?", userID)
+	}
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var applications []models.ShopApplication
+	if result := query.Find(&applications); result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shop applications"})
+		return
+	}
+
+	publicApplications := make([]PublicShopApplication, len(applications))
+	for i, application := range applications {
+		publicApplications[i] = toPublicShopApplication(application)
+	}
+	ctx.JSON(http.StatusOK, publicApplications)
+}
+
+func (c *ShopApplicationController) handleGetShopApplication(ctx *gin.Context) {
+	applicationID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || applicationID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return
+	}
+
+	var application models.ShopApplication
+	if result := c.db.First(&application, applicationID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Shop application not found"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+	if models.Role(role.(string)) != models.AdminRole && application.UserID != userID.(uint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to view this application"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicShopApplication(application))
+}
+
+var (
+	errApplicationReviewed = errors.New("shop application has already been reviewed")
+	errShopNameTaken       = errors.New("a shop with this name already exists")
+	errAlreadyOwnsShop     = errors.New("the applicant already owns a shop")
+)
+
+// handleApproveShopApplication creates the shop for the applicant and upgrades
+// them to the shop role.
+func (c *ShopApplicationController) handleApproveShopApplication(ctx *gin.Context) {
+	application, ok := c.loadPendingApplication(ctx)
+	if !ok {
+		return
+	}
+
+	reviewerID, _ := ctx.Get("userID")
+	reviewer := reviewerID.(uint)
+	now := time.Now()
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Claiming the application first makes a concurrent review of it wait
+		// here and then find it reviewed.
+		claim := tx.Model(&models.ShopApplication{}).
+			Where("id = ? AND status = ?", application.ID, models.ApplicationPending).
+			Updates(map[string]interface{}{
+				"status":      string(models.ApplicationApproved),
+				"reviewer_id": reviewer,
+				"reviewed_at": now,
+			})
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return errApplicationReviewed
+		}
+
+		var taken int64
+		if err := tx.Model(&models.Shop{}).Where("name = ?", application.Name).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errShopNameTaken
+		}
+		var owned int64
+		if err := tx.Model(&models.Shop{}).Where("user_id = ?", application.UserID).Count(&owned).Error; err != nil {
+			return err
+		}
+		if owned > 0 {
+			return errAlreadyOwnsShop
+		}
+
+		shop := models.Shop{
+			Name:          application.Name,
+			Description:   application.Description,
+			Policies:      application.Policies,
+			PayoutDetails: application.PayoutDetails,
+			UserID:        application.UserID,
+		}
+		if err := tx.Create(&shop).Error; err != nil {
+			switch {
+			case repositories.IsUniqueViolation(err, repositories.ShopNameConstraint):
+				return errShopNameTaken
+			case repositories.IsUniqueViolation(err, repositories.ShopOwnerConstraint):
+				return errAlreadyOwnsShop
+			}
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ? AND role = ?", application.UserID, models.CustomerRole).
+			Update("role", string(models.ShopRole)).Error; err != nil {
+			return err
+		}
+
+		application.Status = string(models.ApplicationApproved)
+		application.ReviewerID = &reviewer
+		application.ReviewedAt = &now
+		application.ShopID = &shop.ID
+		return tx.Model(application).Update("shop_id", shop.ID).Error
+	})
+	switch {
+	case errors.Is(err, errApplicationReviewed):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Shop application has already been reviewed"})
+		return
+	case errors.Is(err, errShopNameTaken):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A shop with this name already exists"})
+		return
+	case errors.Is(err, errAlreadyOwnsShop):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "The applicant already owns a shop"})
+		return
+	case err != nil:
+		slog.Error("failed to approve shop application", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve shop application"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicShopApplication(*application))
+}
+
+func (c *ShopApplicationController) handleRejectShopApplication(ctx *gin.Context) {
+	var payload models.ReviewShopApplicationPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil || payload.Reason == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A rejection reason is required"})
+		return
+	}
+
+	application, ok := c.loadPendingApplication(ctx)
+	if !ok {
+		return
+	}
+
+	reviewerID, _ := ctx.Get("userID")
+	reviewer := reviewerID.(uint)
+	now := time.Now()
+
+	application.Status = string(models.ApplicationRejected)
+	application.RejectionReason = payload.Reason
+	application.ReviewerID = &reviewer
+	application.ReviewedAt = &now
+
+	// Only a still pending application is rejected, so a concurrent approval
+	// is never overwritten.
+	result := c.db.WithContext(ctx).Model(&models.ShopApplication{}).
+		Where("id = ? AND status = ?", application.ID, models.ApplicationPending).
+		Updates(map[string]interface{}{
+			"status":           application.Status,
+			"rejection_reason": application.RejectionReason,
+			"reviewer_id":      reviewer,
+			"reviewed_at":      now,
+		})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject shop application"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Shop application has already been reviewed"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicShopApplication(*application))
+}
+
+// loadPendingApplication fetches the application from the :id param and makes
+// sure it has not been reviewed yet. It writes the error response itself.
+func (c *ShopApplicationController) loadPendingApplication(ctx *gin.Context) (*models.ShopApplication, bool) {
+	applicationID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || applicationID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return nil, false
+	}
+
+	var application models.ShopApplication
+	if result := c.db.First(&application, applicationID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Shop application not found"})
+		return nil, false
+	}
+
+	if application.Status != string(models.ApplicationPending) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Shop application has already been reviewed"})
+		return nil, false
+	}
+
+	return &application, true
+}
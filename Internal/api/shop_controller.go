@@ -1,20 +1,24 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/Archnick/go-ecommerce/Internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type ShopController struct {
-	db *gorm.DB
+	db     *gorm.DB
+	images *services.ImageService
 }
 
-func NewShopController(db *gorm.DB) *ShopController {
-	return &ShopController{db: db}
+func NewShopController(db *gorm.DB, images *services.ImageService) *ShopController {
+	return &ShopController{db: db, images: images}
 }
 
 // handleGetShops retrieves all shops from the database.
@@ -32,7 +36,9 @@ func (c *ShopController) handleGetShops(ctx *gin.Context) {
 			ID:           shop.ID,
 			Name:         shop.Name,
 			Description:  shop.Description,
+			Policies:     shop.Policies,
 			ShopImageUrl: shop.ShopImageUrl,
+			UserID:       shop.UserID,
 		}
 	}
 	ctx.JSON(http.StatusOK, publicShops)
@@ -56,7 +62,9 @@ func (c *ShopController) handleGetShop(ctx *gin.Context) {
 		ID:           shop.ID,
 		Name:         shop.Name,
 		Description:  shop.Description,
+		Policies:     shop.Policies,
 		ShopImageUrl: shop.ShopImageUrl,
+		UserID:       shop.UserID,
 	}
 	ctx.JSON(http.StatusOK, response)
 }
@@ -68,13 +76,42 @@ func (c *ShopController) handleCreateShop(ctx *gin.Context) {
 		return
 	}
 
+	var owner models.User
+	if result := c.db.Preload("Shop").First(&owner, payload.UserID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if owner.Shop.ID != 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "This user already owns a shop"})
+		return
+	}
+
 	shop := models.Shop{
 		Name:         payload.Name,
 		Description:  payload.Description,
+		Policies:     payload.Policies,
 		ShopImageUrl: payload.ShopImageUrl,
+		UserID:       owner.ID,
 	}
 
-	if result := c.db.Create(&shop); result.Error != nil {
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&shop).Error; err != nil {
+			return err
+		}
+		// Admins keep their role; everybody else becomes a seller.
+		if models.Role(owner.Role) == models.CustomerRole {
+			return tx.Model(&owner).Update("role", string(models.ShopRole)).Error
+		}
+		return nil
+	})
+	switch {
+	case repositories.IsUniqueViolation(err, repositories.ShopNameConstraint):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A shop with this name already exists"})
+		return
+	case repositories.IsUniqueViolation(err, repositories.ShopOwnerConstraint):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "This user already owns a shop"})
+		return
+	case err != nil:
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shop"})
 		return
 	}
@@ -83,7 +120,9 @@ func (c *ShopController) handleCreateShop(ctx *gin.Context) {
 		ID:           shop.ID,
 		Name:         shop.Name,
 		Description:  shop.Description,
+		Policies:     shop.Policies,
 		ShopImageUrl: shop.ShopImageUrl,
+		UserID:       shop.UserID,
 	}
 	ctx.JSON(http.StatusCreated, response)
 }
@@ -114,11 +153,14 @@ func (c *ShopController) handleUpdateShop(ctx *gin.Context) {
 	if payload.Description != "" {
 		shop.Description = payload.Description
 	}
+	if payload.Policies != "" {
+		shop.Policies = payload.Policies
+	}
 	if payload.ShopImageUrl != "" {
 		shop.ShopImageUrl = payload.ShopImageUrl
 	}
 
-	if result := c.db.Save(&shop); result.Error != nil {
+	if result := c.db.WithContext(ctx).Save(&shop); result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shop"})
 		return
 	}
@@ -127,11 +169,63 @@ func (c *ShopController) handleUpdateShop(ctx *gin.Context) {
 		ID:           shop.ID,
 		Name:         shop.Name,
 		Description:  shop.Description,
+		Policies:     shop.Policies,
 		ShopImageUrl: shop.ShopImageUrl,
+		UserID:       shop.UserID,
 	}
 	ctx.JSON(http.StatusOK, response)
 }
 
+// handleUploadShopImage replaces the shop's image with an uploaded file.
+// Shop owners can change their own shop, admins any shop.
+func (c *ShopController) handleUploadShopImage(ctx *gin.Context) {
+	shopIDStr := ctx.Param("id")
+	shopID, err := strconv.Atoi(shopIDStr)
+	if err != nil || shopID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	var shop models.Shop
+	if result := c.db.First(&shop, shopID); result.Error != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+	if models.Role(role.(string)) != models.AdminRole && shop.UserID != userID.(uint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to update this shop"})
+		return
+	}
+
+	file, ok := openUploadedImage(ctx)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	stored, err := c.images.SaveImage(ctx, fmt.Sprintf("shops/%d", shop.ID), file)
+	if err != nil {
+		respondImageError(ctx, err)
+		return
+	}
+
+	previousKey := shop.ShopImageKey
+	shop.ShopImageUrl = stored.URL
+	shop.ShopImageKey = stored.Key
+	if result := c.db.WithContext(ctx).Save(&shop); result.Error != nil {
+		c.images.DeleteImage(ctx, stored.Key)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shop"})
+		return
+	}
+	if previousKey != "" {
+		c.images.DeleteImage(ctx, previousKey)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"shop_image_url": shop.ShopImageUrl, "thumbnails": stored.Thumbnails})
+}
+
 func (c *ShopController) handleDeleteShop(ctx *gin.Context) {
 	shopIDStr := ctx.Param("id")
 	shopID, err := strconv.Atoi(shopIDStr)
@@ -140,7 +234,7 @@ func (c *ShopController) handleDeleteShop(ctx *gin.Context) {
 		return
 	}
 
-	if result := c.db.Delete(&models.Shop{}, shopID); result.Error != nil {
+	if result := c.db.WithContext(ctx).Delete(&models.Shop{}, shopID); result.Error != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shop"})
 		return
 	}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// ShopOrderController lets shop owners see and fulfil the part of each
+// order their shop sold. Admins may act for any shop.
+type ShopOrderController struct {
+	db      *gorm.DB
+	service *services.ShopOrderService
+}
+
+func NewShopOrderController(db *gorm.DB, service *services.ShopOrderService) *ShopOrderController {
+	return &ShopOrderController{db: db, service: service}
+}
+
+type PublicShopOrder struct {
+	ID             uint       `json:"id"`
+	OrderID        uint       `json:"order_id"`
+	ShopID         uint       `json:"shop_id"`
+	Status         string     `json:"status"`
+	Subtotal       float64    `json:"subtotal"`
+	DiscountTotal  float64    `json:"discount_total"`
+	TaxTotal       float64    `json:"tax_total"`
+	ShippingTotal  float64    `json:"shipping_total"`
+	Total          float64    `json:"total"`
+	ShippingMethod string     `json:"shipping_method"`
+	Carrier        string     `json:"carrier"`
+	TrackingNumber string     `json:"tracking_number"`
+	TrackingURL    string     `json:"tracking_url"`
+	ShippedAt      *time.Time `json:"shipped_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CancelledAt    *time.Time `json:"cancelled_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	// OrderNumber, ShippingAddress, Items and Shipments are only filled in
+	// for the seller; the customer sees them on the parent order.
+	OrderNumber     *string              `json:"order_number,omitempty"`
+	ShippingAddress *PublicPostalAddress `json:"shipping_address,omitempty"`
+	Items           []PublicOrderItem    `json:"items,omitempty"`
+	Shipments       []PublicShipment     `json:"shipments,omitempty"`
+}
+
+type PublicShipment struct {
+	ID             uint                 `json:"id"`
+	ShopOrderID    uint                 `json:"shop_order_id"`
+	ShopID         uint                 `json:"shop_id"`
+	Carrier        string               `json:"carrier"`
+	TrackingNumber string               `json:"tracking_number"`
+	TrackingURL    string               `json:"tracking_url"`
+	ShippedAt      time.Time            `json:"shipped_at"`
+	DeliveredAt    *time.Time           `json:"delivered_at"`
+	Items          []PublicShipmentItem `json:"items"`
+}
+
+type PublicShipmentItem struct {
+	OrderItemID uint `json:"order_item_id"`
+	ProductID   uint `json:"product_id"`
+	Quantity    int  `json:"quantity"`
+}
+
+func toPublicShipment(shipment models.Shipment) PublicShipment {
+	publicShipment := PublicShipment{
+		ID:             shipment.ID,
+		ShopOrderID:    shipment.ShopOrderID,
+		ShopID:         shipment.ShopID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		TrackingURL:    shipment.TrackingURL,
+		ShippedAt:      shipment.ShippedAt,
+		DeliveredAt:    shipment.DeliveredAt,
+		Items:          make([]PublicShipmentItem, len(shipment.Items)),
+	}
+	for i, item := range shipment.Items {
+		publicShipment.Items[i] = PublicShipmentItem{
+			OrderItemID: item.OrderItemID,
+			ProductID:   item.ProductID,
+			Quantity:    item.Quantity,
+		}
+	}
+	return publicShipment
+}
+
+func toPublicShopOrder(shopOrder models.ShopOrder) PublicShopOrder {
+	return PublicShopOrder{
+		ID:             shopOrder.ID,
+		OrderID:        shopOrder.OrderID,
+		ShopID:         shopOrder.ShopID,
+		Status:         shopOrder.Status,
+		Subtotal:       shopOrder.Subtotal,
+		DiscountTotal:  shopOrder.DiscountTotal,
+		TaxTotal:       shopOrder.TaxTotal,
+		ShippingTotal:  shopOrder.ShippingTotal,
+		Total:          shopOrder.Total,
+		ShippingMethod: shopOrder.ShippingMethod,
+		Carrier:        shopOrder.Carrier,
+		TrackingNumber: shopOrder.TrackingNumber,
+		TrackingURL:    shopOrder.TrackingURL,
+		ShippedAt:      shopOrder.ShippedAt,
+		DeliveredAt:    shopOrder.DeliveredAt,
+		CancelledAt:    shopOrder.CancelledAt,
+		CreatedAt:      shopOrder.CreatedAt,
+	}
+}
+
+// toSellerShopOrder adds what the seller needs to ship: the destination, the
+// items and what has shipped so far. The parent order must be loaded.
+func toSellerShopOrder(shopOrder models.ShopOrder) PublicShopOrder {
+	publicShopOrder := toPublicShopOrder(shopOrder)
+	address := toPublicPostalAddress(shopOrder.Order.Shipping)
+	if address.Formatted == "" {
+		address.Formatted = shopOrder.Order.ShippingAddress
+	}
+	publicShopOrder.OrderNumber = shopOrder.Order.Number
+	publicShopOrder.ShippingAddress = &address
+	publicShopOrder.Items = make([]PublicOrderItem, len(shopOrder.Items))
+	for i, item := range shopOrder.Items {
+		publicShopOrder.Items[i] = toPublicOrderItem(item)
+	}
+	publicShopOrder.Shipments = make([]PublicShipment, len(shopOrder.Shipments))
+	for i, shipment := range shopOrder.Shipments {
+		publicShopOrder.Shipments[i] = toPublicShipment(shipment)
+	}
+	return publicShopOrder
+}
+
+// handleGetShopOrders lists the :id shop's orders, newest first. ?status=
+// filters by status.
+func (c *ShopOrderController) handleGetShopOrders(ctx *gin.Context) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return
+	}
+
+	shopOrders, err := c.service.ListShopOrders(shop.ID, ctx.Query("status"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
+		return
+	}
+
+	publicShopOrders := make([]PublicShopOrder, len(shopOrders))
+	for i, shopOrder := range shopOrders {
+		publicShopOrders[i] = toSellerShopOrder(shopOrder)
+	}
+	ctx.JSON(http.StatusOK, publicShopOrders)
+}
+
+func (c *ShopOrderController) handleGetShopOrder(ctx *gin.Context) {
+	shopOrder, ok := c.loadShopOrder(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, toSellerShopOrder(*shopOrder))
+}
+
+func (c *ShopOrderController) handleUpdateShopOrder(ctx *gin.Context) {
+	shopOrder, ok := c.loadShopOrder(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.UpdateShopOrderPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := c.service.Update(ctx, shopOrder, payload); err != nil {
+		c.writeFulfillmentError(ctx, err, "Failed to update order")
+		return
+	}
+	ctx.JSON(http.StatusOK, toSellerShopOrder(*shopOrder))
+}
+
+func (c *ShopOrderController) handleGetShipments(ctx *gin.Context) {
+	shopOrder, ok := c.loadShopOrder(ctx)
+	if !ok {
+		return
+	}
+
+	shipments, err := c.service.ListShipments(shopOrder.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments"})
+		return
+	}
+
+	publicShipments := make([]PublicShipment, len(shipments))
+	for i, shipment := range shipments {
+		publicShipments[i] = toPublicShipment(shipment)
+	}
+	ctx.JSON(http.StatusOK, publicShipments)
+}
+
+func (c *ShopOrderController) handleCreateShipment(ctx *gin.Context) {
+	shopOrder, ok := c.loadShopOrder(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.ShipmentPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	shipment, err := c.service.Ship(ctx, shopOrder, payload)
+	if err != nil {
+		c.writeFulfillmentError(ctx, err, "Failed to record shipment")
+		return
+	}
+	ctx.JSON(http.StatusCreated, toPublicShipment(*shipment))
+}
+
+func (c *ShopOrderController) handleDeliverShipment(ctx *gin.Context) {
+	shopOrder, ok := c.loadShopOrder(ctx)
+	if !ok {
+		return
+	}
+	shipmentID, err := strconv.Atoi(ctx.Param("shipment_id"))
+	if err != nil || shipmentID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shipment ID"})
+		return
+	}
+
+	shipment, err := c.service.GetShipment(shopOrder.ID, uint(shipmentID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	}
+	if err := c.service.Deliver(ctx, shipment); err != nil {
+		c.writeFulfillmentError(ctx, err, "Failed to update shipment")
+		return
+	}
+	ctx.JSON(http.StatusOK, toPublicShipment(*shipment))
+}
+
+// writeFulfillmentError maps errors from the shop order service to responses.
+func (c *ShopOrderController) writeFulfillmentError(ctx *gin.Context, err error, message string) {
+	switch {
+	case services.IsShipmentError(err):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrShopOrderState), errors.Is(err, services.ErrOrderNotFulfillable):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		slog.Error("failed to update shop order", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// loadShopOrder loads the :order_id shop order of the :id shop, which the
+// caller must manage. It writes the error response itself.
+func (c *ShopOrderController) loadShopOrder(ctx *gin.Context) (*models.ShopOrder, bool) {
+	shop, ok := loadManagedShop(ctx, c.db)
+	if !ok {
+		return nil, false
+	}
+	shopOrderID, err := strconv.Atoi(ctx.Param("order_id"))
+	if err != nil || shopOrderID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return nil, false
+	}
+
+	shopOrder, err := c.service.GetShopOrder(shop.ID, uint(shopOrderID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return nil, false
+	}
+	return shopOrder, true
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// TaxRateController lets admins maintain the tax table.
+type TaxRateController struct {
+	db *gorm.DB
+}
+
+func NewTaxRateController(db *gorm.DB) *TaxRateController {
+	return &TaxRateController{db: db}
+}
+
+type PublicTaxRate struct {
+	ID          uint    `json:"id"`
+	Country     string  `json:"country"`
+	Region      string  `json:"region"`
+	TaxCategory string  `json:"tax_category"`
+	Name        string  `json:"name"`
+	Percent     float64 `json:"percent"`
+}
+
+func toPublicTaxRate(rate models.TaxRate) PublicTaxRate {
+	return PublicTaxRate{
+		ID:          rate.ID,
+		Country:     rate.Country,
+		Region:      rate.Region,
+		TaxCategory: rate.TaxCategory,
+		Name:        rate.Name,
+		Percent:     rate.Percent,
+	}
+}
+
+// handleGetTaxRates lists the tax table, optionally for a single ?country=.
+func (c *TaxRateController) handleGetTaxRates(ctx *gin.Context) {
+	query := c.db.Order("country, region, tax_category")
+	if country := ctx.Query("country"); country != "" {
+		query = query.Where("country = ?", strings.ToUpper(country))
+	}
+
+	var rates []models.TaxRate
+	if err := query.Find(&rates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tax rates"})
+		return
+	}
+
+	publicRates := make([]PublicTaxRate, len(rates))
+	for i, rate := range rates {
+		publicRates[i] = toPublicTaxRate(rate)
+	}
+	ctx.JSON(http.StatusOK, publicRates)
+}
+
+func (c *TaxRateController) handleCreateTaxRate(ctx *gin.Context) {
+	payload, ok := c.bindTaxRatePayload(ctx, 0)
+	if !ok {
+		return
+	}
+
+	rate := models.TaxRate{}
+	applyTaxRatePayload(&rate, payload)
+	if err := c.db.WithContext(ctx).Create(&rate).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tax rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, toPublicTaxRate(rate))
+}
+
+// handleUpdateTaxRate changes a rate. Carts are taxed with the new rate the
+// next time they are repriced; placed orders keep the tax they were charged.
+func (c *TaxRateController) handleUpdateTaxRate(ctx *gin.Context) {
+	rate, ok := c.loadTaxRate(ctx)
+	if !ok {
+		return
+	}
+	payload, ok := c.bindTaxRatePayload(ctx, rate.ID)
+	if !ok {
+		return
+	}
+
+	applyTaxRatePayload(rate, payload)
+	if err := c.db.WithContext(ctx).Save(rate).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tax rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toPublicTaxRate(*rate))
+}
+
+func (c *TaxRateController) handleDeleteTaxRate(ctx *gin.Context) {
+	rate, ok := c.loadTaxRate(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.db.WithContext(ctx).Delete(rate).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tax rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tax rate deleted successfully"})
+}
+
+func (c *TaxRateController) loadTaxRate(ctx *gin.Context) (*models.TaxRate, bool) {
+	rateID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || rateID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax rate ID"})
+		return nil, false
+	}
+
+	var rate models.TaxRate
+	if err := c.db.First(&rate, rateID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+		return nil, false
+	}
+	return &rate, true
+}
+
+// bindTaxRatePayload binds a rate and makes sure the table has at most one
+// rate per country, region and tax category.
+func (c *TaxRateController) bindTaxRatePayload(ctx *gin.Context, rateID uint) (*models.TaxRatePayload, bool) {
+	var payload models.TaxRatePayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs.Translate(trans)})
+			return nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return nil, false
+	}
+	payload.Country = strings.ToUpper(payload.Country)
+	payload.Region = strings.ToUpper(strings.TrimSpace(payload.Region))
+	payload.TaxCategory = strings.ToLower(strings.TrimSpace(payload.TaxCategory))
+
+	var count int64
+	c.db.Model(&models.TaxRate{}).
+		Where("country = ? AND region = ? AND tax_category = ? AND id <> ?",
+			payload.Country, payload.Region, payload.TaxCategory, rateID).
+		Count(&count)
+	if count > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A tax rate for this country, region and tax category already exists"})
+		return nil, false
+	}
+	return &payload, true
+}
+
+func applyTaxRatePayload(rate *models.TaxRate, payload *models.TaxRatePayload) {
+	rate.Country = payload.Country
+	rate.Region = payload.Region
+	rate.TaxCategory = payload.TaxCategory
+	rate.Name = payload.Name
+	rate.Percent = payload.Percent
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// uploadOverhead leaves room for multipart boundaries and extra form fields.
+const uploadOverhead = 1 << 20
+
+// openUploadedImage returns the "file" part of a multipart upload. On failure
+// it writes the error response and returns false.
+func openUploadedImage(ctx *gin.Context) (multipart.File, bool) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, services.MaxImageUploadBytes+uploadOverhead)
+
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image is too large"})
+			return nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A multipart 'file' field is required"})
+		return nil, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return nil, false
+	}
+	return file, true
+}
+
+// respondImageError maps an ImageService error to an HTTP response.
+func respondImageError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrImageTooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnsupportedImageType):
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	default:
+		slog.Error("failed to store image", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,11 +12,14 @@ import (
 	"github.com/Archnick/go-ecommerce/Internal/services"
 	"github.com/gin-gonic/gin" // Import Gin
 	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
 // UsersController holds the dependencies for user-related handlers.
 type UsersController struct {
 	service *services.UserService
+	privacy *services.PrivacyService
+	images  *services.ImageService
 }
 
 type PublicUser struct {
@@ -29,8 +33,8 @@ type PublicUser struct {
 }
 
 // NewUsersController creates a new instance of the UsersController.
-func NewUsersController(service *services.UserService) *UsersController {
-	return &UsersController{service: service}
+func NewUsersController(service *services.UserService, privacy *services.PrivacyService, images *services.ImageService) *UsersController {
+	return &UsersController{service: service, privacy: privacy, images: images}
 }
 
 // handleGetUsers now takes a *gin.Context.
@@ -126,7 +130,7 @@ func (c *UsersController) handleUpdateUser(ctx *gin.Context) {
 	}
 
 	// 4. Update the User Record
-	err = c.service.UpdateUser(user, payload)
+	err = c.service.UpdateUser(ctx, user, payload)
 	if err != nil {
 		slog.Error("failed to update user", "error", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
@@ -136,6 +140,52 @@ func (c *UsersController) handleUpdateUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
 
+// handleUploadProfileImage replaces the user's photo with an uploaded image.
+func (c *UsersController) handleUploadProfileImage(ctx *gin.Context) {
+	requestingUserID, _ := ctx.Get("userID")
+	requestingUserRole, _ := ctx.Get("role")
+
+	targetUserID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if models.Role(requestingUserRole.(string)) != models.AdminRole && requestingUserID.(uint) != uint(targetUserID) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to update this user"})
+		return
+	}
+
+	user, err := c.service.GetUserByID(uint(targetUserID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	file, ok := openUploadedImage(ctx)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	stored, err := c.images.SaveImage(ctx, fmt.Sprintf("users/%d", user.ID), file)
+	if err != nil {
+		respondImageError(ctx, err)
+		return
+	}
+
+	previousKey := user.ProfileImageKey
+	if err := c.service.SetProfileImage(ctx, user, stored.URL, stored.Key); err != nil {
+		c.images.DeleteImage(ctx, stored.Key)
+		slog.Error("failed to update profile image", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+	c.images.DeleteImage(ctx, previousKey)
+
+	ctx.JSON(http.StatusOK, gin.H{"photo": user.ProfileImageURL, "thumbnails": stored.Thumbnails})
+}
+
 func (c *UsersController) handleDeleteUser(ctx *gin.Context) {
 	// 1. Authorization Check (same as handleGetUser and handleUpdateUser)
 	requestingUserID, _ := ctx.Get("userID")
@@ -153,8 +203,33 @@ func (c *UsersController) handleDeleteUser(ctx *gin.Context) {
 		return
 	}
 
-	err = c.service.DeleteUser(uint(targetUserID))
+	// Users deleting themselves get the erasure grace period; admins deleting
+	// someone else erase the account straight away.
+	if requestingUserID.(uint) == uint(targetUserID) {
+		request, err := c.privacy.RequestErasure(ctx, uint(targetUserID))
+		if err != nil {
+			if errors.Is(err, services.ErrErasurePending) {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "An erasure is already scheduled"})
+				return
+			}
+			slog.Error("failed to schedule user erasure", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+			return
+		}
+
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"message":       "Your account will be erased at the end of the grace period",
+			"scheduled_for": request.ScheduledFor,
+		})
+		return
+	}
+
+	err = c.privacy.EraseNow(ctx, uint(targetUserID), requestingUserID.(uint))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
 		slog.Error("failed to delete user", "error", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
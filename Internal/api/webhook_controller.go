@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/payment"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPaymentEventPageSize = 50
+	maxPaymentEventPageSize     = 200
+	maxWebhookBytes             = 1 << 20
+)
+
+type WebhookController struct {
+	service *services.WebhookService
+}
+
+func NewWebhookController(service *services.WebhookService) *WebhookController {
+	return &WebhookController{service: service}
+}
+
+type PublicPaymentEvent struct {
+	ID          uint       `json:"id"`
+	Provider    string     `json:"provider"`
+	EventID     string     `json:"event_id"`
+	Type        string     `json:"type"`
+	IntentID    string     `json:"intent_id"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	ProcessedAt *time.Time `json:"processed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func toPublicPaymentEvent(event models.PaymentEvent) PublicPaymentEvent {
+	return PublicPaymentEvent{
+		ID:          event.ID,
+		Provider:    event.Provider,
+		EventID:     event.EventID,
+		Type:        event.Type,
+		IntentID:    event.IntentID,
+		Status:      event.Status,
+		Attempts:    event.Attempts,
+		LastError:   event.LastError,
+		ProcessedAt: event.ProcessedAt,
+		CreatedAt:   event.CreatedAt,
+	}
+}
+
+// handlePaymentWebhook ingests a signed provider event. Anything other than a
+// 2xx makes the provider retry, so only failures worth retrying return 500.
+func (c *WebhookController) handlePaymentWebhook(ctx *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxWebhookBytes))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	event, err := c.service.Receive(ctx, ctx.Param("provider"), ctx.Request.Header, payload)
+	switch {
+	case errors.Is(err, services.ErrUnknownPaymentProvider):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+	case errors.Is(err, payment.ErrInvalidSignature), errors.Is(err, payment.ErrStaleWebhook):
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidWebhook):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		slog.Error("failed to process payment webhook", "provider", ctx.Param("provider"), "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process event"})
+	default:
+		ctx.JSON(http.StatusOK, gin.H{"received": true, "status": event.Status})
+	}
+}
+
+// handleGetPaymentEvents lists stored webhook events, newest first, filtered
+// by ?status= and paged with limit/offset.
+func (c *WebhookController) handleGetPaymentEvents(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultPaymentEventPageSize)))
+	if err != nil || limit <= 0 || limit > maxPaymentEventPageSize {
+		limit = defaultPaymentEventPageSize
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	events, total, err := c.service.ListEvents(ctx.Query("status"), limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment events"})
+		return
+	}
+
+	publicEvents := make([]PublicPaymentEvent, len(events))
+	for i, event := range events {
+		publicEvents[i] = toPublicPaymentEvent(event)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"total": total, "events": publicEvents})
+}
+
+func (c *WebhookController) handleReplayPaymentEvent(ctx *gin.Context) {
+	eventID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || eventID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	event, err := c.service.Replay(ctx, uint(eventID))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound) && event == nil:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment event not found"})
+	case errors.Is(err, services.ErrEventProcessed), errors.Is(err, services.ErrEventInProgress):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil && event != nil:
+		// The event was replayed and failed again; LastError says why.
+		ctx.JSON(http.StatusUnprocessableEntity, toPublicPaymentEvent(*event))
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay payment event"})
+	default:
+		ctx.JSON(http.StatusOK, toPublicPaymentEvent(*event))
+	}
+}
+
+func (c *WebhookController) handleReplayFailedPaymentEvents(ctx *gin.Context) {
+	processed, failed, err := c.service.ReplayFailed(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay payment events"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"processed": processed, "failed": failed})
+}
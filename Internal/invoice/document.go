@@ -0,0 +1,123 @@
+// Package invoice renders order invoices as HTML and PDF.
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// Party is the seller or the buyer on an invoice. Address holds one entry
+// per printed line.
+type Party struct {
+	Name    string
+	Address []string
+	TaxID   string
+	Email   string
+}
+
+// Line is one invoiced item. Amount is UnitPrice times Quantity less
+// Discount; Tax is shown separately.
+type Line struct {
+	Description string
+	Quantity    int
+	UnitPrice   float64
+	Discount    float64
+	Tax         float64
+	Amount      float64
+}
+
+// Charge is an amount on the invoice that is not an item, such as shipping
+// or a coupon.
+type Charge struct {
+	Description string
+	Amount      float64
+}
+
+// Section holds the items one shop sold on the order.
+type Section struct {
+	SoldBy   string
+	Lines    []Line
+	Shipping *Charge
+}
+
+// TaxLine is the total of one tax across the invoice.
+type TaxLine struct {
+	Name    string
+	Percent float64
+	Amount  float64
+}
+
+// Document is everything printed on an invoice.
+type Document struct {
+	Number      string
+	OrderNumber string
+	IssuedAt    time.Time
+	OrderDate   time.Time
+	Currency    string
+	Seller      Party
+	Buyer       Party
+	Sections    []Section
+	Discounts   []Charge
+	Taxes       []TaxLine
+
+	Subtotal      float64
+	DiscountTotal float64
+	ShippingTotal float64
+	TaxTotal      float64
+	Total         float64
+	// PricesIncludeTax means the tax is already part of the item amounts and
+	// is shown for information only.
+	PricesIncludeTax bool
+}
+
+// SellerFromEnv reads the details of the business issuing invoices:
+// INVOICE_SELLER_NAME, INVOICE_SELLER_ADDRESS with lines separated by ";"
+// and INVOICE_SELLER_TAX_ID.
+func SellerFromEnv() Party {
+	seller := Party{
+		Name:  os.Getenv("INVOICE_SELLER_NAME"),
+		TaxID: os.Getenv("INVOICE_SELLER_TAX_ID"),
+	}
+	if seller.Name == "" {
+		seller.Name = "Go E-commerce"
+	}
+	for _, line := range strings.Split(os.Getenv("INVOICE_SELLER_ADDRESS"), ";") {
+		if line = strings.TrimSpace(line); line != "" {
+			seller.Address = append(seller.Address, line)
+		}
+	}
+	return seller
+}
+
+// PrefixFromEnv is what invoice numbers start with, set with
+// INVOICE_NUMBER_PREFIX. It is "INV" by default.
+func PrefixFromEnv() string {
+	if prefix := os.Getenv("INVOICE_NUMBER_PREFIX"); prefix != "" {
+		return prefix
+	}
+	return "INV"
+}
+
+// formatMoney prints an amount with two decimals and a thousands separator.
+func formatMoney(amount float64) string {
+	cents := int64(math.Round(amount * 100))
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	whole := fmt.Sprint(cents / 100)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return fmt.Sprintf("%s%s.%02d", sign, whole, cents%100)
+}
+
+// formatPercent prints a tax rate without trailing zeros, e.g. 7.5%.
+func formatPercent(percent float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", percent), "0"), ".") + "%"
+}
+
+const dateLayout = "2 January 2006"
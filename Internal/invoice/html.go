@@ -0,0 +1,82 @@
+package invoice
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money":   formatMoney,
+	"percent": formatPercent,
+	"date":    func(t time.Time) string { return t.Format(dateLayout) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 14px; color: #222; max-width: 800px; margin: 2em auto; }
+h1 { font-size: 28px; margin: 0 0 0.5em; }
+h2 { font-size: 15px; margin: 1.5em 0 0.5em; }
+.parties { display: flex; justify-content: space-between; gap: 2em; }
+.parties p, .meta p { margin: 0.2em 0; }
+table { width: 100%; border-collapse: collapse; }
+th, td { padding: 0.4em; text-align: right; border-bottom: 1px solid #ddd; }
+th:first-child, td:first-child { text-align: left; }
+.totals { width: auto; margin-left: auto; margin-top: 1.5em; }
+.totals .total td { font-weight: bold; border-top: 2px solid #222; }
+.note { color: #666; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>Invoice</h1>
+<div class="meta">
+<p>Invoice number: <strong>{{.Number}}</strong></p>
+<p>Invoice date: {{date .IssuedAt}}</p>
+{{if .OrderNumber}}<p>Order number: {{.OrderNumber}}</p>{{end}}
+<p>Order date: {{date .OrderDate}}</p>
+</div>
+<div class="parties">
+<div>
+<h2>Seller</h2>
+<p><strong>{{.Seller.Name}}</strong></p>
+{{range .Seller.Address}}<p>{{.}}</p>{{end}}
+{{if .Seller.TaxID}}<p>Tax ID: {{.Seller.TaxID}}</p>{{end}}
+{{if .Seller.Email}}<p>{{.Seller.Email}}</p>{{end}}
+</div>
+<div>
+<h2>Bill to</h2>
+<p><strong>{{.Buyer.Name}}</strong></p>
+{{range .Buyer.Address}}<p>{{.}}</p>{{end}}
+{{if .Buyer.TaxID}}<p>Tax ID: {{.Buyer.TaxID}}</p>{{end}}
+{{if .Buyer.Email}}<p>{{.Buyer.Email}}</p>{{end}}
+</div>
+</div>
+{{$currency := .Currency}}
+{{range .Sections}}
+<h2>Sold by {{.SoldBy}}</h2>
+<table>
+<thead><tr><th>Item</th><th>Qty</th><th>Unit price</th><th>Discount</th><th>Tax</th><th>Amount</th></tr></thead>
+<tbody>
+{{range .Lines}}<tr><td>{{.Description}}</td><td>{{.Quantity}}</td><td>{{money .UnitPrice}}</td><td>{{money .Discount}}</td><td>{{money .Tax}}</td><td>{{money .Amount}}</td></tr>
+{{end}}{{with .Shipping}}<tr><td>{{.Description}}</td><td></td><td></td><td></td><td></td><td>{{money .Amount}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+<table class="totals">
+<tr><td>Subtotal</td><td>{{money .Subtotal}} {{$currency}}</td></tr>
+{{range .Discounts}}<tr><td>{{.Description}}</td><td>-{{money .Amount}} {{$currency}}</td></tr>
+{{end}}<tr><td>Shipping</td><td>{{money .ShippingTotal}} {{$currency}}</td></tr>
+{{$included := .PricesIncludeTax}}{{range .Taxes}}<tr><td>{{.Name}} {{percent .Percent}}{{if $included}} (included){{end}}</td><td>{{money .Amount}} {{$currency}}</td></tr>
+{{end}}<tr class="total"><td>Total</td><td>{{money .Total}} {{$currency}}</td></tr>
+</table>
+{{if .PricesIncludeTax}}<p class="note">Prices include tax.</p>{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML renders doc as a standalone HTML page.
+func WriteHTML(w io.Writer, doc Document) error {
+	return htmlTemplate.Execute(w, doc)
+}
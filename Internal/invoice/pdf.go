@@ -0,0 +1,244 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The PDF is laid out on A4 in Courier, one of the fonts every PDF reader
+// has built in. Its fixed width lets columns line up by padding the text, so
+// no font metrics or embedding are needed.
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	pageMargin   = 48.0
+	fontSize     = 9.0
+	lineHeight   = 12.0
+	charWidth    = 0.6 * fontSize
+	columnsWidth = 92
+)
+
+// Item table columns, in characters.
+const (
+	descriptionWidth = 40
+	quantityWidth    = 6
+	amountWidth      = 11
+)
+
+// WritePDF renders doc as a PDF document.
+func WritePDF(w io.Writer, doc Document) error {
+	layout := &pdfLayout{}
+	layout.newPage()
+
+	layout.text(pageMargin, "F2", 20, "INVOICE")
+	layout.y -= 10
+	layout.row("F1", rightAlign("Invoice number: "+doc.Number, columnsWidth))
+	layout.row("F1", rightAlign("Invoice date: "+doc.IssuedAt.Format(dateLayout), columnsWidth))
+	if doc.OrderNumber != "" {
+		layout.row("F1", rightAlign("Order number: "+doc.OrderNumber, columnsWidth))
+	}
+	layout.row("F1", rightAlign("Order date: "+doc.OrderDate.Format(dateLayout), columnsWidth))
+	layout.gap()
+
+	seller, buyer := partyLines(doc.Seller), partyLines(doc.Buyer)
+	half := columnsWidth / 2
+	layout.row("F2", padRight("Seller", half)+"Bill to")
+	for i := 0; i < max(len(seller), len(buyer)); i++ {
+		var left, right string
+		if i < len(seller) {
+			left = seller[i]
+		}
+		if i < len(buyer) {
+			right = buyer[i]
+		}
+		layout.row("F1", padRight(truncate(left, half-2), half)+truncate(right, columnsWidth-half))
+	}
+
+	for _, section := range doc.Sections {
+		layout.gap()
+		layout.row("F2", truncate("Sold by "+section.SoldBy, columnsWidth))
+		layout.row("F2", itemRow("Item", "Qty", "Unit price", "Discount", "Tax", "Amount"))
+		layout.rule()
+		for _, line := range section.Lines {
+			layout.row("F1", itemRow(line.Description, fmt.Sprint(line.Quantity), formatMoney(line.UnitPrice),
+				formatMoney(line.Discount), formatMoney(line.Tax), formatMoney(line.Amount)))
+		}
+		if section.Shipping != nil {
+			layout.row("F1", itemRow(section.Shipping.Description, "", "", "", "", formatMoney(section.Shipping.Amount)))
+		}
+	}
+
+	layout.gap()
+	layout.row("F1", totalRow("Subtotal", formatMoney(doc.Subtotal), doc.Currency))
+	for _, discount := range doc.Discounts {
+		layout.row("F1", totalRow(discount.Description, "-"+formatMoney(discount.Amount), doc.Currency))
+	}
+	layout.row("F1", totalRow("Shipping", formatMoney(doc.ShippingTotal), doc.Currency))
+	for _, tax := range doc.Taxes {
+		label := tax.Name + " " + formatPercent(tax.Percent)
+		if doc.PricesIncludeTax {
+			label += " (included)"
+		}
+		layout.row("F1", totalRow(label, formatMoney(tax.Amount), doc.Currency))
+	}
+	layout.rule()
+	layout.row("F2", totalRow("Total", formatMoney(doc.Total), doc.Currency))
+	if doc.PricesIncludeTax {
+		layout.gap()
+		layout.row("F1", "Prices include tax.")
+	}
+
+	for i, page := range layout.pages {
+		footer := fmt.Sprintf("Invoice %s - page %d of %d", doc.Number, i+1, len(layout.pages))
+		page.WriteString(textOp(pageMargin, pageMargin/2, "F1", 8, footer))
+	}
+	return writePDFFile(w, "Invoice "+doc.Number, layout.pages)
+}
+
+// partyLines lists what is printed for a seller or buyer.
+func partyLines(party Party) []string {
+	lines := append([]string{party.Name}, party.Address...)
+	if party.TaxID != "" {
+		lines = append(lines, "Tax ID: "+party.TaxID)
+	}
+	if party.Email != "" {
+		lines = append(lines, party.Email)
+	}
+	return lines
+}
+
+func itemRow(description, quantity, unitPrice, discount, tax, amount string) string {
+	return padRight(truncate(description, descriptionWidth-1), descriptionWidth) +
+		rightAlign(quantity, quantityWidth) + rightAlign(unitPrice, amountWidth) +
+		rightAlign(discount, amountWidth) + rightAlign(tax, amountWidth) +
+		rightAlign(amount, columnsWidth-descriptionWidth-quantityWidth-3*amountWidth)
+}
+
+func totalRow(label, amount, currency string) string {
+	value := amount + " " + currency
+	return rightAlign(truncate(label, 40), columnsWidth-20) + rightAlign(value, 20)
+}
+
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-len([]rune(s))))
+}
+
+func rightAlign(s string, width int) string {
+	return strings.Repeat(" ", max(0, width-len([]rune(s)))) + s
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-3]) + "..."
+}
+
+// pdfLayout places rows of text top to bottom, starting a new page when one
+// fills up. Each page is its content stream.
+type pdfLayout struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func (l *pdfLayout) newPage() {
+	l.pages = append(l.pages, &bytes.Buffer{})
+	l.y = pageHeight - pageMargin
+}
+
+func (l *pdfLayout) page() *bytes.Buffer {
+	return l.pages[len(l.pages)-1]
+}
+
+func (l *pdfLayout) text(x float64, font string, size float64, s string) {
+	if l.y-size < pageMargin {
+		l.newPage()
+	}
+	l.y -= size
+	l.page().WriteString(textOp(x, l.y, font, size, s))
+}
+
+func (l *pdfLayout) row(font, s string) {
+	if l.y-lineHeight < pageMargin {
+		l.newPage()
+	}
+	l.y -= lineHeight
+	l.page().WriteString(textOp(pageMargin, l.y, font, fontSize, s))
+}
+
+func (l *pdfLayout) gap() {
+	l.y -= lineHeight
+}
+
+// rule draws a line under the previous row.
+func (l *pdfLayout) rule() {
+	y := l.y - 3
+	fmt.Fprintf(l.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", pageMargin, y, pageMargin+columnsWidth*charWidth, y)
+}
+
+func textOp(x, y float64, font string, size float64, s string) string {
+	return fmt.Sprintf("BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+// pdfString escapes s for a PDF string literal in WinAnsiEncoding. Characters
+// the encoding lacks print as "?".
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r == '€':
+			b.WriteString(`\200`)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// writePDFFile writes the page content streams out as a complete PDF file
+// with its cross-reference table.
+func writePDFFile(w io.Writer, title string, pages []*bytes.Buffer) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1 to 5 are fixed; each page then takes a page and a content
+	// object.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (go-ecommerce) >>", pdfString(title)))
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 7+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
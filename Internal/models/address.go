@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// Address is an entry in a user's address book.
+type Address struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index"`
+	Label  string `gorm:"type:varchar(50);not null;default:''"`
+	PostalAddress
+	IsDefaultShipping bool `gorm:"not null;default:false"`
+	IsDefaultBilling  bool `gorm:"not null;default:false"`
+}
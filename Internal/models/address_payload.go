@@ -0,0 +1,38 @@
+package models
+
+import "strings"
+
+// PostalAddressPayload is checked further against the country's rules by
+// services.ValidatePostalAddress.
+type PostalAddressPayload struct {
+	FullName   string `json:"full_name" binding:"required,max=200"`
+	Line1      string `json:"line1" binding:"required,max=200"`
+	Line2      string `json:"line2" binding:"omitempty,max=200"`
+	City       string `json:"city" binding:"required,max=100"`
+	Region     string `json:"region" binding:"omitempty,max=50"`
+	PostalCode string `json:"postal_code" binding:"omitempty,max=20"`
+	Country    string `json:"country" binding:"required,len=2,alpha"`
+	Phone      string `json:"phone" binding:"omitempty,max=30"`
+}
+
+// PostalAddress returns the payload as a PostalAddress with the country and
+// region codes upper-cased and surrounding spaces trimmed.
+func (p PostalAddressPayload) PostalAddress() PostalAddress {
+	return PostalAddress{
+		FullName:   strings.TrimSpace(p.FullName),
+		Line1:      strings.TrimSpace(p.Line1),
+		Line2:      strings.TrimSpace(p.Line2),
+		City:       strings.TrimSpace(p.City),
+		Region:     strings.ToUpper(strings.TrimSpace(p.Region)),
+		PostalCode: strings.ToUpper(strings.TrimSpace(p.PostalCode)),
+		Country:    strings.ToUpper(p.Country),
+		Phone:      strings.TrimSpace(p.Phone),
+	}
+}
+
+type AddressPayload struct {
+	Label string `json:"label" binding:"omitempty,max=50"`
+	PostalAddressPayload
+	IsDefaultShipping bool `json:"is_default_shipping"`
+	IsDefaultBilling  bool `json:"is_default_billing"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey is a long-lived credential a user can hand to an integration.
+// Only a SHA-256 hash of the key is stored; Prefix is kept in clear text so
+// the key can be recognised in listings and looked up on use.
+type APIKey struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Prefix     string `gorm:"type:varchar(20);uniqueIndex;not null"`
+	KeyHash    string `gorm:"type:varchar(64);not null"`
+	Scopes     string `gorm:"type:varchar(255);not null"`
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+}
+
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.ScopeList() {
+		if s == string(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key can still be used at the given time.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
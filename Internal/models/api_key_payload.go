@@ -0,0 +1,7 @@
+package models
+
+type APIKeyPayload struct {
+	Name          string   `json:"name" binding:"required,min=2,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=catalog:read catalog:write orders:read orders:write"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
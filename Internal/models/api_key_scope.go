@@ -0,0 +1,10 @@
+package models
+
+type APIKeyScope string
+
+const (
+	CatalogReadScope  APIKeyScope = "catalog:read"
+	CatalogWriteScope APIKeyScope = "catalog:write"
+	OrdersReadScope   APIKeyScope = "orders:read"
+	OrdersWriteScope  APIKeyScope = "orders:write"
+)
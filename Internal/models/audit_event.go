@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AuditEvent is an append-only record of a single row being created, updated
+// or deleted. It deliberately has no UpdatedAt/DeletedAt columns.
+type AuditEvent struct {
+	ID             uint  `gorm:"primaryKey"`
+	ActorID        *uint `gorm:"index"`
+	ImpersonatorID *uint
+	Action         string    `gorm:"type:varchar(20);not null;index"`
+	EntityType     string    `gorm:"type:varchar(100);not null;index:idx_audit_entity"`
+	EntityID       uint      `gorm:"index:idx_audit_entity"`
+	Before         string    `gorm:"type:text"`
+	After          string    `gorm:"type:text"`
+	IPAddress      string    `gorm:"type:varchar(45)"`
+	RequestID      string    `gorm:"type:varchar(64);index"`
+	CreatedAt      time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// CommissionRate is the platform's cut of a sale, as a percentage of the
+// items after discounts. The most specific rate wins: shop and category,
+// then shop, then category, then the platform default.
+type CommissionRate struct {
+	gorm.Model
+	ShopID     *uint   `gorm:"index"`
+	CategoryID *uint   `gorm:"index"`
+	Percent    float64 `gorm:"type:decimal(6,3);not null"`
+}
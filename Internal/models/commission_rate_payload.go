@@ -0,0 +1,7 @@
+package models
+
+type CommissionRatePayload struct {
+	ShopID     *uint   `json:"shop_id"`
+	CategoryID *uint   `json:"category_id"`
+	Percent    float64 `json:"percent" binding:"gte=0,lte=100"`
+}
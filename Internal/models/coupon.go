@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Coupon is a discount code customers apply to their cart. Coupons created by
+// a shop only ever discount that shop's products.
+type Coupon struct {
+	gorm.Model
+	Code        string `gorm:"type:varchar(50);not null;uniqueIndex"`
+	Description string `gorm:"type:varchar(255)"`
+	Type        string `gorm:"type:varchar(20);not null"`
+	// Value is a percentage for percentage coupons and an amount for fixed ones.
+	Value float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	Scope string  `gorm:"type:varchar(20);not null"`
+	// ScopeID is the shop, category or product the coupon is limited to.
+	ScopeID       *uint
+	ShopID        *uint   `gorm:"index"`
+	MinOrderValue float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	// UsageLimit and PerUserLimit are unlimited when nil.
+	UsageLimit   *int
+	PerUserLimit *int
+	StartsAt     *time.Time
+	EndsAt       *time.Time
+	// Stackable coupons can be combined with other stackable coupons.
+	Stackable bool `gorm:"not null;default:false"`
+	Active    bool `gorm:"not null"`
+}
+
+// CouponRedemption links a coupon to the order it is applied to. Usage
+// limits count these rows once the order is placed.
+type CouponRedemption struct {
+	ID       uint `gorm:"primaryKey"`
+	CouponID uint `gorm:"not null;index;uniqueIndex:idx_redemption_order_coupon"`
+	OrderID  uint `gorm:"not null;uniqueIndex:idx_redemption_order_coupon"`
+	// UserID is nil for guest orders, which cannot use coupons limited per
+	// customer.
+	UserID *uint `gorm:"index"`
+	// Pending redemptions belong to carts and do not count against usage
+	// limits, so abandoned carts cannot use a coupon up.
+	Pending   bool      `gorm:"not null;default:false"`
+	CreatedAt time.Time `gorm:"not null"`
+}
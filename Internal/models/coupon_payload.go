@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type CouponPayload struct {
+	Code          string     `json:"code" binding:"required,min=3,max=50,alphanum"`
+	Description   string     `json:"description" binding:"omitempty,max=255"`
+	Type          string     `json:"type" binding:"required,oneof=percentage fixed free_shipping"`
+	Value         float64    `json:"value" binding:"omitempty,gte=0"`
+	Scope         string     `json:"scope" binding:"required,oneof=store shop category product"`
+	ScopeID       *uint      `json:"scope_id"`
+	MinOrderValue float64    `json:"min_order_value" binding:"omitempty,gte=0"`
+	UsageLimit    *int       `json:"usage_limit" binding:"omitempty,gte=1"`
+	PerUserLimit  *int       `json:"per_user_limit" binding:"omitempty,gte=1"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	Stackable     bool       `json:"stackable"`
+	Active        *bool      `json:"active"`
+}
+
+type ApplyCouponPayload struct {
+	Code string `json:"code" binding:"required"`
+}
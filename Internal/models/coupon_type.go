@@ -0,0 +1,18 @@
+package models
+
+type CouponType string
+
+const (
+	PercentageCoupon   CouponType = "percentage"
+	FixedCoupon        CouponType = "fixed"
+	FreeShippingCoupon CouponType = "free_shipping"
+)
+
+type CouponScope string
+
+const (
+	StoreScope    CouponScope = "store"
+	ShopScope     CouponScope = "shop"
+	CategoryScope CouponScope = "category"
+	ProductScope  CouponScope = "product"
+)
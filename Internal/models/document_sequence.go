@@ -0,0 +1,9 @@
+package models
+
+// DocumentSequence is a counter for numbering documents such as orders and
+// invoices. Name identifies the series, e.g. "invoice:2026", and Last is the
+// number most recently handed out.
+type DocumentSequence struct {
+	Name string `gorm:"type:varchar(50);primaryKey"`
+	Last uint   `gorm:"not null;default:0"`
+}
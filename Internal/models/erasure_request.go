@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErasureRequest tracks a pending or finished account erasure. Users get a
+// grace period in which they can cancel; admins can erase immediately.
+type ErasureRequest struct {
+	gorm.Model
+	UserID        uint      `gorm:"not null;index"`
+	RequestedByID uint      `gorm:"not null"`
+	Status        string    `gorm:"type:varchar(20);not null;index"`
+	ScheduledFor  time.Time `gorm:"not null;index"`
+	CompletedAt   *time.Time
+}
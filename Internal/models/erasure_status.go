@@ -0,0 +1,9 @@
+package models
+
+type ErasureStatus string
+
+const (
+	ErasureScheduled ErasureStatus = "scheduled"
+	ErasureCancelled ErasureStatus = "cancelled"
+	ErasureCompleted ErasureStatus = "completed"
+)
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header, so a retry of the same request gets the same
+// response instead of being carried out again. Keys are scoped to the user,
+// or for guests to GuestID with UserID 0, and deleted outright once they
+// expire.
+type IdempotencyKey struct {
+	ID      uint   `gorm:"primaryKey"`
+	UserID  uint   `gorm:"not null;uniqueIndex:idx_idempotency_key"`
+	GuestID string `gorm:"type:varchar(64);not null;default:'';uniqueIndex:idx_idempotency_key"`
+	Key     string `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_key"`
+	Method  string `gorm:"type:varchar(10);not null"`
+	Path    string `gorm:"type:varchar(2048);not null"`
+	// Fingerprint is a hash of the method, path and body of the request.
+	Fingerprint string `gorm:"type:varchar(64);not null"`
+	// ResponseStatus is 0 while the first request is still being handled.
+	ResponseStatus int    `gorm:"not null;default:0"`
+	ContentType    string `gorm:"type:varchar(255);not null;default:''"`
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ImpersonationLog records every impersonation token handed out to an admin.
+type ImpersonationLog struct {
+	gorm.Model
+	AdminID   uint      `gorm:"not null;index"`
+	UserID    uint      `gorm:"not null;index"`
+	Reason    string    `gorm:"type:text;not null"`
+	ReadOnly  bool      `gorm:"not null"`
+	IPAddress string    `gorm:"type:varchar(45)"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
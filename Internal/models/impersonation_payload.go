@@ -0,0 +1,6 @@
+package models
+
+type ImpersonationPayload struct {
+	Reason      string `json:"reason" binding:"required,min=5"`
+	AllowWrites bool   `json:"allow_writes"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// InventoryMovement is an append-only record of a stock change. Product.Stock
+// is only ever changed together with a movement, so the ledger explains it.
+type InventoryMovement struct {
+	ID        uint `gorm:"primaryKey"`
+	ProductID uint `gorm:"not null;index"`
+	// Quantity is signed: positive adds stock, negative removes it.
+	Quantity   int       `gorm:"not null"`
+	StockAfter int       `gorm:"not null"`
+	Reason     string    `gorm:"type:varchar(20);not null;index"`
+	Note       string    `gorm:"type:varchar(255)"`
+	ActorID    *uint     `gorm:"index"`
+	OrderID    *uint     `gorm:"index"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// StockAdjustmentPayload is a manual stock change. Sales and reservations are
+// recorded by the order flow, not through this payload.
+type StockAdjustmentPayload struct {
+	Quantity int    `json:"quantity" binding:"required"`
+	Reason   string `json:"reason" binding:"required,oneof=restock adjustment return"`
+	Note     string `json:"note" binding:"omitempty,max=255"`
+}
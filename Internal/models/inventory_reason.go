@@ -0,0 +1,11 @@
+package models
+
+type InventoryReason string
+
+const (
+	InventorySale        InventoryReason = "sale"
+	InventoryRestock     InventoryReason = "restock"
+	InventoryAdjustment  InventoryReason = "adjustment"
+	InventoryReturn      InventoryReason = "return"
+	InventoryReservation InventoryReason = "reservation"
+)
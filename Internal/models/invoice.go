@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invoice records the number an order was invoiced under. Numbers run without
+// gaps within each year, as accounting requires. The document is stored at
+// issue so reprinting an invoice gives the same document.
+type Invoice struct {
+	gorm.Model
+	Number        string    `gorm:"type:varchar(32);not null;uniqueIndex"`
+	OrderID       uint      `gorm:"not null;uniqueIndex"`
+	IssuedAt      time.Time `gorm:"not null"`
+	SellerName    string    `gorm:"type:varchar(200);not null"`
+	SellerAddress string    `gorm:"type:text;not null;default:''"`
+	SellerTaxID   string    `gorm:"type:varchar(50);not null;default:''"`
+	// Document holds the invoice.Document as issued, in JSON. It is empty
+	// for invoices issued before documents were stored.
+	Document string `gorm:"type:text;not null;default:''"`
+	Order    Order
+}
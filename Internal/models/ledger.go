@@ -0,0 +1,31 @@
+package models
+
+import "gorm.io/gorm"
+
+// LedgerTransaction is one balanced posting to the seller ledger: its
+// entries sum to zero.
+type LedgerTransaction struct {
+	gorm.Model
+	Kind string `gorm:"type:varchar(30);not null;index"`
+	// Reference names what was posted, e.g. "sale:12" for shop order 12, so
+	// the same event is never posted twice.
+	Reference   string        `gorm:"type:varchar(100);not null;uniqueIndex"`
+	ShopID      uint          `gorm:"not null;index"`
+	OrderID     *uint         `gorm:"index"`
+	ShopOrderID *uint         `gorm:"index"`
+	PaymentID   *uint         `gorm:"index"`
+	PayoutID    *uint         `gorm:"index"`
+	Description string        `gorm:"type:varchar(255);not null;default:''"`
+	Entries     []LedgerEntry `gorm:"foreignKey:TransactionID"`
+}
+
+type LedgerEntry struct {
+	gorm.Model
+	TransactionID uint   `gorm:"not null;index"`
+	Account       string `gorm:"type:varchar(30);not null;index"`
+	// ShopID is set on shop_payable entries.
+	ShopID *uint `gorm:"index"`
+	// Amount is positive for a debit and negative for a credit.
+	Amount      float64           `gorm:"type:decimal(12,2);not null"`
+	Transaction LedgerTransaction `gorm:"foreignKey:TransactionID"`
+}
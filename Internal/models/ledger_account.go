@@ -0,0 +1,26 @@
+package models
+
+type LedgerAccount string
+
+const (
+	// LedgerPlatformCash is the money the platform holds.
+	LedgerPlatformCash LedgerAccount = "platform_cash"
+	// LedgerShopPayable is what the platform owes a shop; its entries carry
+	// the shop's ID.
+	LedgerShopPayable       LedgerAccount = "shop_payable"
+	LedgerCommissionRevenue LedgerAccount = "commission_revenue"
+	// LedgerPayoutsInTransit holds payouts sent to shops but not yet settled.
+	LedgerPayoutsInTransit LedgerAccount = "payouts_in_transit"
+)
+
+type LedgerKind string
+
+const (
+	LedgerSale               LedgerKind = "sale"
+	LedgerCommission         LedgerKind = "commission"
+	LedgerRefund             LedgerKind = "refund"
+	LedgerCommissionReversal LedgerKind = "commission_reversal"
+	LedgerPayout             LedgerKind = "payout"
+	LedgerPayoutSettled      LedgerKind = "payout_settled"
+	LedgerPayoutFailed       LedgerKind = "payout_failed"
+)
@@ -1,20 +1,90 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"math"
+
+	"gorm.io/gorm"
+)
 
 type Order struct {
 	gorm.Model
-	TotalAmount     float64     `gorm:"type:decimal(10,2);not null"`
-	Status          string      `gorm:"type:varchar(50);deafault:'cart';not null"`
-	ShippingAddress string      `gorm:"type:text;not null"`
-	UserID          uint        `gorm:"type:not null"`
-	OrderItems      []OrderItem `gorm:"foreignKey:OrderID"`
+	// Subtotal is the sum of the items after price rules; TotalAmount is the
+	// grand total the customer pays.
+	Subtotal      float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	DiscountTotal float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	TaxTotal      float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	ShippingTotal float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	FreeShipping  bool    `gorm:"not null;default:false"`
+	TotalAmount   float64 `gorm:"type:decimal(10,2);not null"`
+	// PricesIncludeTax records the pricing mode the order was taxed under.
+	// Included tax is part of Subtotal and not added to the total again.
+	PricesIncludeTax bool   `gorm:"not null;default:false"`
+	Status           string `gorm:"type:varchar(50);default:'cart';not null"`
+	// Number is the order number shown to customers, given at checkout.
+	Number *string `gorm:"type:varchar(32);uniqueIndex"`
+	// Shipping and Billing are snapshots of the addresses used for the order.
+	Shipping PostalAddress `gorm:"embedded;embeddedPrefix:shipping_"`
+	Billing  PostalAddress `gorm:"embedded;embeddedPrefix:billing_"`
+	// ShippingAddress is Shipping formatted for labels. Orders placed before
+	// addresses were structured only have this.
+	ShippingAddress string `gorm:"type:text;not null"`
+	// UserID is nil for guest checkouts, which belong to GuestID until the
+	// guest claims them with an account.
+	UserID  *uint   `gorm:"index"`
+	GuestID *string `gorm:"type:varchar(64);index"`
+	// Email is where the customer hears about the order, copied from the
+	// account or given by the guest at checkout.
+	Email         string              `gorm:"type:varchar(255);not null;default:''"`
+	OrderItems    []OrderItem         `gorm:"foreignKey:OrderID"`
+	Discounts     []OrderDiscount     `gorm:"foreignKey:OrderID"`
+	ShippingLines []OrderShippingLine `gorm:"foreignKey:OrderID"`
+	Payments      []Payment           `gorm:"foreignKey:OrderID"`
+	ShopOrders    []ShopOrder         `gorm:"foreignKey:OrderID"`
+	Shipments     []Shipment          `gorm:"foreignKey:OrderID"`
 }
 
+// RefreshPrice recomputes the totals from OrderItems, Discounts and
+// ShippingLines, which must all be loaded.
 func (o *Order) RefreshPrice() {
-	var total float64
+	var subtotal, discount, tax, shipping float64
 	for _, item := range o.OrderItems {
-		total += item.Price * float64(item.Quantity)
+		subtotal += item.LineTotal()
+		tax += item.Tax
+	}
+	o.FreeShipping = false
+	for _, line := range o.Discounts {
+		discount += line.Amount
+		o.FreeShipping = o.FreeShipping || line.FreeShipping
+	}
+	for i := range o.ShippingLines {
+		line := &o.ShippingLines[i]
+		line.Waived = o.shippingWaived(line.ShopID)
+		if !line.Waived {
+			shipping += line.Amount
+		}
 	}
-	o.TotalAmount = total
+	o.ShippingTotal = RoundMoney(shipping)
+	o.Subtotal = RoundMoney(subtotal)
+	o.DiscountTotal = RoundMoney(min(discount, subtotal))
+	o.TaxTotal = RoundMoney(tax)
+	o.TotalAmount = RoundMoney(subtotal - o.DiscountTotal + o.ShippingTotal)
+	if !o.PricesIncludeTax {
+		o.TotalAmount = RoundMoney(o.TotalAmount + o.TaxTotal)
+	}
+}
+
+// shippingWaived reports whether a free-shipping discount covers the shop's
+// shipment.
+func (o *Order) shippingWaived(shopID uint) bool {
+	for _, line := range o.Discounts {
+		if line.FreeShipping && (line.ShopID == nil || *line.ShopID == shopID) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundMoney rounds an amount to whole cents.
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
 }
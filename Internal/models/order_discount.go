@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// OrderDiscount is a discount line on an order, recomputed whenever the
+// order is repriced.
+type OrderDiscount struct {
+	gorm.Model
+	OrderID  uint  `gorm:"not null;index"`
+	CouponID *uint `gorm:"index"`
+	// ShopID limits a free-shipping line to that shop's shipment.
+	ShopID      *uint
+	Code        string  `gorm:"type:varchar(50)"`
+	Description string  `gorm:"type:varchar(255)"`
+	Amount      float64 `gorm:"type:decimal(10,2);not null"`
+	// FreeShipping lines waive the order's shipping cost instead of an amount.
+	FreeShipping bool `gorm:"not null;default:false"`
+}
@@ -4,8 +4,29 @@ import "gorm.io/gorm"
 
 type OrderItem struct {
 	gorm.Model
-	Quantity  int     `gorm:"type:integer;not null"`
-	Price     float64 `gorm:"type:decimal(10,2);not null"`
-	OrderID   uint    `gorm:"not null"`
-	ProductID uint    `gorm:"not null"`
+	Quantity int     `gorm:"type:integer;not null"`
+	Price    float64 `gorm:"type:decimal(10,2);not null"`
+	OrderID  uint    `gorm:"not null"`
+	// ShopOrderID is set when checkout splits the order by shop.
+	ShopOrderID *uint `gorm:"index"`
+	ProductID   uint  `gorm:"not null"`
+	// Discount is the total of Adjustments, taken off the whole line.
+	Discount    float64               `gorm:"type:decimal(10,2);not null;default:0"`
+	Adjustments []OrderItemAdjustment `gorm:"foreignKey:OrderItemID"`
+	// CouponDiscount is the item's share of the order's coupon discounts,
+	// spread over the items each coupon covers.
+	CouponDiscount float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	// Tax is the total of Taxes.
+	Tax   float64        `gorm:"type:decimal(10,2);not null;default:0"`
+	Taxes []OrderItemTax `gorm:"foreignKey:OrderItemID"`
+}
+
+// LineTotal is what the item costs after price rules, before coupons.
+func (i *OrderItem) LineTotal() float64 {
+	return i.Price*float64(i.Quantity) - i.Discount
+}
+
+// NetTotal is what the item costs after price rules and coupons, before tax.
+func (i *OrderItem) NetTotal() float64 {
+	return i.LineTotal() - i.CouponDiscount
 }
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// OrderItemAdjustment explains a price rule's discount on an order item.
+type OrderItemAdjustment struct {
+	gorm.Model
+	OrderItemID uint    `gorm:"not null;index"`
+	PriceRuleID *uint   `gorm:"index"`
+	Description string  `gorm:"type:varchar(255)"`
+	Amount      float64 `gorm:"type:decimal(10,2);not null"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// OrderItemTax is a tax charged on an order item.
+type OrderItemTax struct {
+	gorm.Model
+	OrderItemID uint    `gorm:"not null;index"`
+	Name        string  `gorm:"type:varchar(100);not null"`
+	Percent     float64 `gorm:"type:decimal(6,3);not null"`
+	Amount      float64 `gorm:"type:decimal(10,2);not null"`
+}
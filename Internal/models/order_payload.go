@@ -1,23 +1,51 @@
 package models
 
+// OrderPayload takes each address either as an address book entry or inline.
+// Without a shipping address the user's default one is used, and billing
+// falls back to the shipping address. UserID is only honoured for admins
+// placing an order for someone else.
 type OrderPayload struct {
-	TotalAmount     float64            `json:"total_amount"`
-	ShippingAddress string             `json:"shipping_address"`
-	UserID          uint               `json:"user_id"`
-	OrderItems      []OrderItemPayload `json:"order_items"`
+	TotalAmount       float64               `json:"total_amount"`
+	ShippingAddressID *uint                 `json:"shipping_address_id"`
+	ShippingAddress   *PostalAddressPayload `json:"shipping_address"`
+	BillingAddressID  *uint                 `json:"billing_address_id"`
+	BillingAddress    *PostalAddressPayload `json:"billing_address"`
+	UserID            uint                  `json:"user_id"`
+	OrderItems        []OrderItemPayload    `json:"order_items" binding:"dive"`
 }
 
+// OrderItemPayload has no price: items are priced from their product.
 type OrderItemPayload struct {
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
-	ProductID uint    `json:"product_id"`
+	Quantity  int  `json:"quantity" binding:"required,gte=1"`
+	ProductID uint `json:"product_id" binding:"required"`
 }
 
+// UpdateOrderPayload only replaces the address snapshots that are given.
+// The status is not set by customers: it follows the payment and the shop
+// orders.
 type UpdateOrderPayload struct {
-	Status          string `json:"status"`
-	ShippingAddress string `json:"shipping_address"`
+	ShippingAddressID *uint                 `json:"shipping_address_id"`
+	ShippingAddress   *PostalAddressPayload `json:"shipping_address"`
+	BillingAddressID  *uint                 `json:"billing_address_id"`
+	BillingAddress    *PostalAddressPayload `json:"billing_address"`
 }
 
 type UpdateOrderItemPayload struct {
-	Quantity int `json:"quantity"`
+	Quantity int `json:"quantity" binding:"required,gte=1"`
+}
+
+// OrderLookupPayload finds a placed order without signing in.
+type OrderLookupPayload struct {
+	OrderNumber string `json:"order_number" binding:"required,max=32"`
+	Email       string `json:"email" binding:"required,email,max=255"`
+}
+
+// ClaimOrdersPayload attaches a guest order to the signed-in account. The
+// order's number and email get a claim code mailed to that email, and the
+// code claims the order. Orders of the guest token sent with the request are
+// claimed whether or not these are given.
+type ClaimOrdersPayload struct {
+	OrderNumber string `json:"order_number" binding:"required_with=Email,excluded_with=ClaimCode,max=32"`
+	Email       string `json:"email" binding:"required_with=OrderNumber,omitempty,email,max=255"`
+	ClaimCode   string `json:"claim_code" binding:"max=1024"`
 }
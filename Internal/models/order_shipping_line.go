@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// OrderShippingLine is the shipping method chosen for one shop's items.
+type OrderShippingLine struct {
+	gorm.Model
+	OrderID uint    `gorm:"not null;index"`
+	ShopID  uint    `gorm:"not null"`
+	QuoteID string  `gorm:"type:varchar(100);not null"`
+	Name    string  `gorm:"type:varchar(100);not null"`
+	Carrier string  `gorm:"type:varchar(100)"`
+	Amount  float64 `gorm:"type:decimal(10,2);not null"`
+	// Waived lines are covered by a free-shipping coupon.
+	Waived  bool `gorm:"not null;default:false"`
+	MinDays int  `gorm:"not null;default:0"`
+	MaxDays int  `gorm:"not null;default:0"`
+}
@@ -8,4 +8,8 @@ const (
 	Processing OrderStatus = "processing"
 	Completed  OrderStatus = "completed"
 	Cancelled  OrderStatus = "cancelled"
+	// Paid, PaymentFailed and Refunded are set from payment provider events.
+	Paid          OrderStatus = "paid"
+	PaymentFailed OrderStatus = "payment_failed"
+	Refunded      OrderStatus = "refunded"
 )
@@ -0,0 +1,21 @@
+package models
+
+import "gorm.io/gorm"
+
+// Payment is an attempt to pay for an order through a payment provider. An
+// order can have several, e.g. a declined card followed by a working one.
+type Payment struct {
+	gorm.Model
+	OrderID  uint    `gorm:"not null;index"`
+	Provider string  `gorm:"type:varchar(30);not null;uniqueIndex:idx_payment_intent"`
+	IntentID string  `gorm:"type:varchar(100);not null;uniqueIndex:idx_payment_intent"`
+	Amount   float64 `gorm:"type:decimal(10,2);not null"`
+	Currency string  `gorm:"type:varchar(3);not null"`
+	Status   string  `gorm:"type:varchar(20);not null;index"`
+	// CapturedAmount and RefundedAmount mirror the provider's running totals.
+	CapturedAmount float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	RefundedAmount float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	// ActionURL is where the customer completes 3-D Secure or similar.
+	ActionURL     string `gorm:"type:varchar(500);not null;default:''"`
+	DeclineReason string `gorm:"type:varchar(255);not null;default:''"`
+}
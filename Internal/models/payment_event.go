@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentEvent is a webhook delivery from a payment provider, stored as
+// received. Each provider event is stored once however often it is delivered.
+type PaymentEvent struct {
+	gorm.Model
+	Provider string `gorm:"type:varchar(30);not null;uniqueIndex:idx_payment_event"`
+	EventID  string `gorm:"type:varchar(100);not null;uniqueIndex:idx_payment_event"`
+	Type     string `gorm:"type:varchar(50);not null"`
+	IntentID string `gorm:"type:varchar(100);not null;index"`
+	// Payload is the raw request body, kept so the event can be replayed.
+	Payload     string `gorm:"type:text;not null"`
+	Status      string `gorm:"type:varchar(20);not null;index"`
+	Attempts    int    `gorm:"not null;default:0"`
+	LastError   string `gorm:"type:text;not null;default:''"`
+	ProcessedAt *time.Time
+}
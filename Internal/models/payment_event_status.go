@@ -0,0 +1,13 @@
+package models
+
+type PaymentEventStatus string
+
+const (
+	PaymentEventReceived PaymentEventStatus = "received"
+	// PaymentEventProcessing is claimed by a delivery or replay applying it.
+	PaymentEventProcessing PaymentEventStatus = "processing"
+	PaymentEventProcessed  PaymentEventStatus = "processed"
+	PaymentEventFailed     PaymentEventStatus = "failed"
+	// PaymentEventIgnored is for an intent no payment of ours was made with.
+	PaymentEventIgnored PaymentEventStatus = "ignored"
+)
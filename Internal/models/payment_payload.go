@@ -0,0 +1,16 @@
+package models
+
+// CheckoutPayload carries the provider's token for the customer's payment
+// method, as collected by the client, and the email the customer hears about
+// the order at. Signed-in customers may leave out the email to use their
+// account's; guests must give it on their first checkout attempt.
+type CheckoutPayload struct {
+	PaymentMethod string `json:"payment_method" binding:"required,max=255"`
+	Email         string `json:"email" binding:"omitempty,email,max=255"`
+}
+
+// PaymentAmountPayload captures or refunds Amount, or the full remaining
+// amount when it is omitted.
+type PaymentAmountPayload struct {
+	Amount *float64 `json:"amount" binding:"omitempty,gt=0"`
+}
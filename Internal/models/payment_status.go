@@ -0,0 +1,13 @@
+package models
+
+type PaymentStatus string
+
+const (
+	PaymentRequiresAction PaymentStatus = "requires_action"
+	PaymentAuthorized     PaymentStatus = "authorized"
+	PaymentDeclined       PaymentStatus = "declined"
+	PaymentCaptured       PaymentStatus = "captured"
+	PaymentVoided         PaymentStatus = "voided"
+	// PaymentRefunded is a captured payment that was refunded in full.
+	PaymentRefunded PaymentStatus = "refunded"
+)
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PayoutBatch groups the payouts made in one scheduled run.
+type PayoutBatch struct {
+	gorm.Model
+	Total   float64  `gorm:"type:decimal(12,2);not null;default:0"`
+	Payouts []Payout `gorm:"foreignKey:BatchID"`
+}
+
+// Payout is money sent to a shop from its available balance.
+type Payout struct {
+	gorm.Model
+	BatchID       uint    `gorm:"not null;index"`
+	ShopID        uint    `gorm:"not null;index"`
+	Amount        float64 `gorm:"type:decimal(12,2);not null"`
+	Status        string  `gorm:"type:varchar(20);not null;index"`
+	FailureReason string  `gorm:"type:text;not null;default:''"`
+	PaidAt        *time.Time
+}
@@ -0,0 +1,5 @@
+package models
+
+type FailPayoutPayload struct {
+	Reason string `json:"reason" binding:"required,max=2000"`
+}
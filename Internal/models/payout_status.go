@@ -0,0 +1,10 @@
+package models
+
+type PayoutStatus string
+
+const (
+	PayoutPending PayoutStatus = "pending"
+	PayoutPaid    PayoutStatus = "paid"
+	// PayoutFailed payouts are credited back to the shop's balance.
+	PayoutFailed PayoutStatus = "failed"
+)
@@ -0,0 +1,30 @@
+package models
+
+import "strings"
+
+// PostalAddress is a structured postal address. It is embedded in the address
+// book and snapshotted onto orders, so later edits to the book do not change
+// where an order was shipped.
+type PostalAddress struct {
+	FullName   string `gorm:"type:varchar(200);not null;default:''"`
+	Line1      string `gorm:"type:varchar(200);not null;default:''"`
+	Line2      string `gorm:"type:varchar(200);not null;default:''"`
+	City       string `gorm:"type:varchar(100);not null;default:''"`
+	Region     string `gorm:"type:varchar(50);not null;default:''"`
+	PostalCode string `gorm:"type:varchar(20);not null;default:''"`
+	// Country is an ISO 3166-1 alpha-2 code.
+	Country string `gorm:"type:varchar(2);not null;default:''"`
+	Phone   string `gorm:"type:varchar(30);not null;default:''"`
+}
+
+// Format renders the address on several lines, e.g. for a shipping label.
+func (a PostalAddress) Format() string {
+	cityLine := strings.TrimSpace(strings.Join([]string{a.PostalCode, a.City, a.Region}, " "))
+	var lines []string
+	for _, line := range []string{a.FullName, a.Line1, a.Line2, cityLine, a.Country} {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
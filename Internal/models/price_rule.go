@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PriceRule is an automatic promotion applied while pricing a cart, without
+// the customer entering a code. Scopes are the same as for coupons.
+type PriceRule struct {
+	gorm.Model
+	Name    string `gorm:"type:varchar(100);not null"`
+	Type    string `gorm:"type:varchar(20);not null;index"`
+	Scope   string `gorm:"type:varchar(20);not null"`
+	ScopeID *uint
+	ShopID  *uint `gorm:"index"`
+	// PercentOff is the sale discount, or what buy-x-get-y takes off the
+	// free items (100 makes them free).
+	PercentOff  float64 `gorm:"type:decimal(5,2);not null;default:0"`
+	BuyQuantity int     `gorm:"not null;default:0"`
+	GetQuantity int     `gorm:"not null;default:0"`
+	// TierList holds a JSON array of PriceTier for tiered rules.
+	TierList string `gorm:"type:text"`
+	StartsAt *time.Time
+	EndsAt   *time.Time
+	Active   bool `gorm:"not null"`
+}
+
+// PriceTier takes PercentOff off the unit price once MinQuantity units of an
+// item are in the cart.
+type PriceTier struct {
+	MinQuantity int     `json:"min_quantity" binding:"required,gte=2"`
+	PercentOff  float64 `json:"percent_off" binding:"required,gt=0,lte=100"`
+}
+
+func (r *PriceRule) Tiers() []PriceTier {
+	tiers := []PriceTier{}
+	if r.TierList != "" {
+		json.Unmarshal([]byte(r.TierList), &tiers)
+	}
+	return tiers
+}
+
+func (r *PriceRule) SetTiers(tiers []PriceTier) {
+	encoded, _ := json.Marshal(tiers)
+	r.TierList = string(encoded)
+}
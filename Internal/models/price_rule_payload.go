@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type PriceRulePayload struct {
+	Name        string      `json:"name" binding:"required,max=100"`
+	Type        string      `json:"type" binding:"required,oneof=sale tiered buy_x_get_y"`
+	Scope       string      `json:"scope" binding:"required,oneof=store shop category product"`
+	ScopeID     *uint       `json:"scope_id"`
+	PercentOff  float64     `json:"percent_off" binding:"omitempty,gt=0,lte=100"`
+	BuyQuantity int         `json:"buy_quantity" binding:"omitempty,gte=1"`
+	GetQuantity int         `json:"get_quantity" binding:"omitempty,gte=1"`
+	Tiers       []PriceTier `json:"tiers" binding:"omitempty,max=10,dive"`
+	StartsAt    *time.Time  `json:"starts_at"`
+	EndsAt      *time.Time  `json:"ends_at"`
+	Active      *bool       `json:"active"`
+}
@@ -0,0 +1,13 @@
+package models
+
+type PriceRuleType string
+
+const (
+	// SaleRule takes a percentage off every covered product and is shown as
+	// the product's sale price.
+	SaleRule PriceRuleType = "sale"
+	// TieredRule discounts the unit price by quantity bought.
+	TieredRule PriceRuleType = "tiered"
+	// BuyXGetYRule discounts GetQuantity units for every BuyQuantity bought.
+	BuyXGetYRule PriceRuleType = "buy_x_get_y"
+)
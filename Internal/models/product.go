@@ -1,17 +1,34 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type Product struct {
 	gorm.Model
-	Name        string         `gorm:"type:varchar(255);not null"`
-	Description string         `gorm:"type:text"`
-	Price       float64        `gorm:"type:decimal(10,2);not null"`
-	Stock       int            `gorm:"type:integer;default:0"`
-	ShopID      uint           `gorm:"not null"`
-	CategoryID  uint           `gorm:"not null"`
-	Images      []ProductImage `gorm:"foreignKey:ProductID"`
-	Reviews     []Review       `gorm:"foreignKey:ProductID"`
+	// SKU identifies the product within its shop, e.g. for bulk imports.
+	SKU         string  `gorm:"type:varchar(64);index;uniqueIndex:idx_products_shop_sku,priority:2,where:sku <> '' AND deleted_at IS NULL"`
+	Name        string  `gorm:"type:varchar(255);not null"`
+	Description string  `gorm:"type:text"`
+	Price       float64 `gorm:"type:decimal(10,2);not null"`
+	Stock       int     `gorm:"type:integer;default:0"`
+	// LowStockThreshold alerts the shop owner when stock falls to it; 0 disables.
+	LowStockThreshold int  `gorm:"not null;default:0"`
+	ShopID            uint `gorm:"not null;uniqueIndex:idx_products_shop_sku,priority:1"`
+	CategoryID        uint `gorm:"not null"`
+	// WeightGrams and the dimensions, in centimetres, are per unit and used to
+	// quote shipping.
+	WeightGrams int     `gorm:"not null;default:0"`
+	LengthCm    float64 `gorm:"type:decimal(8,2);not null;default:0"`
+	WidthCm     float64 `gorm:"type:decimal(8,2);not null;default:0"`
+	HeightCm    float64 `gorm:"type:decimal(8,2);not null;default:0"`
+	// TaxCategory selects the tax rates that apply, e.g. "books" for a reduced rate.
+	TaxCategory string `gorm:"type:varchar(50);not null;default:'standard'"`
+	// Status defaults to published so rows from before statuses existed stay visible.
+	Status    string `gorm:"type:varchar(20);not null;default:'published';index"`
+	PublishAt *time.Time
+	Images    []ProductImage `gorm:"foreignKey:ProductID"`
+	Reviews   []Review       `gorm:"foreignKey:ProductID"`
 }
@@ -1,10 +1,39 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
 
 type ProductImage struct {
 	gorm.Model
 	ImageURL  string `gorm:"type:varchar(255);not null"`
 	AltText   string `gorm:"type:varchar(255)"`
 	ProductID uint   `gorm:"not null"`
+	// StorageKey is set for images uploaded to our own blob store.
+	StorageKey string `gorm:"type:varchar(255)"`
+	Width      int    `gorm:"type:integer"`
+	Height     int    `gorm:"type:integer"`
+	// Thumbnails holds a JSON object of thumbnail URLs keyed by size name.
+	Thumbnails string `gorm:"type:text"`
+	// Position orders a product's images, lowest first.
+	Position  int  `gorm:"not null;default:0"`
+	IsPrimary bool `gorm:"not null;default:false"`
+	// Variant is the option value the image shows (e.g. "red"). Images
+	// without one apply to every variant.
+	Variant string `gorm:"type:varchar(100)"`
+}
+
+func (i *ProductImage) ThumbnailMap() map[string]string {
+	thumbnails := map[string]string{}
+	if i.Thumbnails != "" {
+		json.Unmarshal([]byte(i.Thumbnails), &thumbnails)
+	}
+	return thumbnails
+}
+
+func (i *ProductImage) SetThumbnails(thumbnails map[string]string) {
+	encoded, _ := json.Marshal(thumbnails)
+	i.Thumbnails = string(encoded)
 }
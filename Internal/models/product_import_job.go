@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProductImportJob tracks a bulk catalog import running in the background.
+type ProductImportJob struct {
+	gorm.Model
+	ShopID       uint   `gorm:"not null;index"`
+	UserID       uint   `gorm:"not null"`
+	Format       string `gorm:"type:varchar(10);not null"`
+	Status       string `gorm:"type:varchar(20);not null;index"`
+	TotalRows    int    `gorm:"not null;default:0"`
+	CreatedCount int    `gorm:"not null;default:0"`
+	UpdatedCount int    `gorm:"not null;default:0"`
+	FailedCount  int    `gorm:"not null;default:0"`
+	// Errors holds a JSON array of ImportRowError.
+	Errors     string `gorm:"type:text"`
+	FinishedAt *time.Time
+}
+
+// ImportRowError explains why a single row of an import was skipped. Rows are
+// numbered from 1, not counting a CSV header.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+func (j *ProductImportJob) RowErrors() []ImportRowError {
+	rowErrors := []ImportRowError{}
+	if j.Errors != "" {
+		json.Unmarshal([]byte(j.Errors), &rowErrors)
+	}
+	return rowErrors
+}
+
+func (j *ProductImportJob) SetRowErrors(rowErrors []ImportRowError) {
+	encoded, _ := json.Marshal(rowErrors)
+	j.Errors = string(encoded)
+}
@@ -1,25 +1,62 @@
 package models
 
+import "time"
+
 type ProductPayload struct {
-	Name        string  `json:"name" binding:"required,min=2"`
-	Description string  `json:"description" binding:"omitempty"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
-	Stock       int     `json:"stock" binding:"omitempty,gte=0"`
-	ShopID      uint    `json:"shop_id" binding:"required"`
-	CategoryID  uint    `json:"category_id" binding:"required"`
+	SKU               string  `json:"sku" binding:"omitempty,max=64"`
+	Name              string  `json:"name" binding:"required,min=2"`
+	Description       string  `json:"description" binding:"omitempty"`
+	Price             float64 `json:"price" binding:"required,gt=0"`
+	Stock             int     `json:"stock" binding:"omitempty,gte=0"`
+	LowStockThreshold int     `json:"low_stock_threshold" binding:"omitempty,gte=0"`
+	ShopID            uint    `json:"shop_id" binding:"omitempty"`
+	CategoryID        uint    `json:"category_id" binding:"required"`
+	TaxCategory       string  `json:"tax_category" binding:"omitempty,max=50"`
+	WeightGrams       int     `json:"weight_grams" binding:"omitempty,gte=0"`
+	LengthCm          float64 `json:"length_cm" binding:"omitempty,gte=0"`
+	WidthCm           float64 `json:"width_cm" binding:"omitempty,gte=0"`
+	HeightCm          float64 `json:"height_cm" binding:"omitempty,gte=0"`
+	// Status defaults to draft. A future PublishAt schedules the product.
+	Status    string     `json:"status" binding:"omitempty,oneof=draft published archived"`
+	PublishAt *time.Time `json:"publish_at"`
 }
 
+// UpdateProductPayload has no stock field; stock changes go through
+// POST /api/products/:id/stock/adjust so they are recorded in the ledger.
 type UpdateProductPayload struct {
+	SKU         string  `json:"sku" binding:"omitempty,max=64"`
 	Name        string  `json:"name" binding:"omitempty,min=2"`
 	Description string  `json:"description" binding:"omitempty"`
 	Price       float64 `json:"price" binding:"omitempty,gt=0"`
-	Stock       int     `json:"stock" binding:"omitempty,gte=0"`
-	ShopID      uint    `json:"shop_id" binding:"omitempty"`
-	CategoryID  uint    `json:"category_id" binding:"omitempty"`
+	// LowStockThreshold is a pointer so it can be set back to 0.
+	LowStockThreshold *int    `json:"low_stock_threshold" binding:"omitempty,gte=0"`
+	ShopID            uint    `json:"shop_id" binding:"omitempty"`
+	CategoryID        uint    `json:"category_id" binding:"omitempty"`
+	TaxCategory       string  `json:"tax_category" binding:"omitempty,max=50"`
+	WeightGrams       int     `json:"weight_grams" binding:"omitempty,gte=0"`
+	LengthCm          float64 `json:"length_cm" binding:"omitempty,gte=0"`
+	WidthCm           float64 `json:"width_cm" binding:"omitempty,gte=0"`
+	HeightCm          float64 `json:"height_cm" binding:"omitempty,gte=0"`
+	// Status and PublishAt are applied by the handler, not by Updates.
+	Status    string     `json:"status" binding:"omitempty,oneof=draft published archived" gorm:"-"`
+	PublishAt *time.Time `json:"publish_at" gorm:"-"`
 }
 
 type ProductImagePayload struct {
 	ProductID uint   `json:"product_id" binding:"required"`
 	AltText   string `json:"alt_text" binding:"omitempty"`
 	ImageURL  string `json:"image_url" binding:"required,url"`
+	Variant   string `json:"variant" binding:"omitempty,max=100"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type UpdateProductImagePayload struct {
+	AltText   string `json:"alt_text" binding:"omitempty,max=255"`
+	Variant   string `json:"variant" binding:"omitempty,max=100"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+// ReorderProductImagesPayload lists every image of a product in its new order.
+type ReorderProductImagesPayload struct {
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1"`
 }
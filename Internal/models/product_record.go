@@ -0,0 +1,15 @@
+package models
+
+// ProductRecord is one product in a bulk import or export file. In CSV files
+// ImageURLs is a single "image_urls" column separated by "|".
+type ProductRecord struct {
+	SKU         string  `json:"sku"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Stock       int     `json:"stock"`
+	// Category is matched against category names or their slugs.
+	Category  string   `json:"category"`
+	Status    string   `json:"status,omitempty"`
+	ImageURLs []string `json:"image_urls,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type ProductStatus string
+
+const (
+	ProductDraft ProductStatus = "draft"
+	// ProductScheduled products go live once their PublishAt has passed.
+	ProductScheduled ProductStatus = "scheduled"
+	ProductPublished ProductStatus = "published"
+	ProductArchived  ProductStatus = "archived"
+)
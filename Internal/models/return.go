@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Return is a return merchandise authorization for items of one order, all
+// sold by the same shop, whose owner approves it. It moves from requested
+// through approved and received to refunded, or ends rejected or cancelled.
+type Return struct {
+	gorm.Model
+	OrderID uint   `gorm:"not null;index"`
+	UserID  uint   `gorm:"not null;index"`
+	ShopID  uint   `gorm:"not null;index"`
+	Status  string `gorm:"type:varchar(20);not null;index"`
+	Note    string `gorm:"type:text;not null;default:''"`
+	// RejectionReason explains a rejected return to the customer.
+	RejectionReason string `gorm:"type:text;not null;default:''"`
+	// RefundAmount is what was refunded through the order's payment.
+	RefundAmount float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	PaymentID    *uint
+	Restocked    bool `gorm:"not null;default:false"`
+	ReceivedAt   *time.Time
+	RefundedAt   *time.Time
+	Items        []ReturnItem `gorm:"foreignKey:ReturnID"`
+}
+
+type ReturnItem struct {
+	gorm.Model
+	ReturnID    uint   `gorm:"not null;index"`
+	OrderItemID uint   `gorm:"not null;index"`
+	ProductID   uint   `gorm:"not null"`
+	Quantity    int    `gorm:"not null"`
+	Reason      string `gorm:"type:varchar(30);not null"`
+	Comment     string `gorm:"type:text;not null;default:''"`
+}
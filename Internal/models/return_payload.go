@@ -0,0 +1,23 @@
+package models
+
+type ReturnPayload struct {
+	Items []ReturnItemPayload `json:"items" binding:"required,min=1,dive"`
+	Note  string              `json:"note" binding:"omitempty,max=2000"`
+}
+
+type ReturnItemPayload struct {
+	OrderItemID uint   `json:"order_item_id" binding:"required"`
+	Quantity    int    `json:"quantity" binding:"required,gte=1"`
+	Reason      string `json:"reason" binding:"required,oneof=damaged wrong_item not_as_described no_longer_needed other"`
+	Comment     string `json:"comment" binding:"omitempty,max=1000"`
+}
+
+type RejectReturnPayload struct {
+	Reason string `json:"reason" binding:"required,max=2000"`
+}
+
+// ReceiveReturnPayload puts the goods back in stock unless Restock is false,
+// e.g. for damaged items.
+type ReceiveReturnPayload struct {
+	Restock *bool `json:"restock"`
+}
@@ -0,0 +1,25 @@
+package models
+
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested"
+	ReturnApproved  ReturnStatus = "approved"
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnCancelled ReturnStatus = "cancelled"
+	// ReturnReceived means the goods are back with the seller.
+	ReturnReceived ReturnStatus = "received"
+	// ReturnRefunding means the refund is being sent to the payment provider.
+	ReturnRefunding ReturnStatus = "refunding"
+	ReturnRefunded  ReturnStatus = "refunded"
+)
+
+type ReturnReason string
+
+const (
+	ReturnDamaged        ReturnReason = "damaged"
+	ReturnWrongItem      ReturnReason = "wrong_item"
+	ReturnNotAsDescribed ReturnReason = "not_as_described"
+	ReturnNoLongerNeeded ReturnReason = "no_longer_needed"
+	ReturnOtherReason    ReturnReason = "other"
+)
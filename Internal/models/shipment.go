@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Shipment is a parcel a shop sent for its part of an order. A shop order may
+// go out in several shipments, each carrying some units of its items.
+type Shipment struct {
+	gorm.Model
+	OrderID        uint   `gorm:"not null;index"`
+	ShopOrderID    uint   `gorm:"not null;index"`
+	ShopID         uint   `gorm:"not null;index"`
+	Carrier        string `gorm:"type:varchar(100);not null"`
+	TrackingNumber string `gorm:"type:varchar(100);not null"`
+	TrackingURL    string `gorm:"type:varchar(500);not null;default:''"`
+	ShippedAt      time.Time
+	DeliveredAt    *time.Time
+	Items          []ShipmentItem `gorm:"foreignKey:ShipmentID"`
+}
+
+type ShipmentItem struct {
+	gorm.Model
+	ShipmentID  uint `gorm:"not null;index"`
+	OrderItemID uint `gorm:"not null;index"`
+	ProductID   uint `gorm:"not null"`
+	Quantity    int  `gorm:"not null"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// ShipmentPayload records a parcel. Without Items it carries every unit that
+// has not shipped yet.
+type ShipmentPayload struct {
+	Carrier        string                `json:"carrier" binding:"required,max=100"`
+	TrackingNumber string                `json:"tracking_number" binding:"required,max=100"`
+	TrackingURL    string                `json:"tracking_url" binding:"omitempty,max=500,url"`
+	Items          []ShipmentItemPayload `json:"items" binding:"omitempty,dive"`
+}
+
+type ShipmentItemPayload struct {
+	OrderItemID uint `json:"order_item_id" binding:"required"`
+	Quantity    int  `json:"quantity" binding:"required,gte=1"`
+}
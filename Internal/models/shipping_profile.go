@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ShippingProfile is a shipping method a shop offers, e.g. a flat rate for
+// domestic parcels or a weight-based rate abroad.
+type ShippingProfile struct {
+	gorm.Model
+	ShopID uint   `gorm:"not null;index"`
+	Name   string `gorm:"type:varchar(100);not null"`
+	Type   string `gorm:"type:varchar(20);not null"`
+	// Countries is a comma-separated list of ISO country codes the profile
+	// ships to; empty means everywhere.
+	Countries   string  `gorm:"type:varchar(1000);not null;default:''"`
+	BaseAmount  float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	PerKgAmount float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	// FreeOver makes shipping free from this order value; nil disables it.
+	FreeOver *float64 `gorm:"type:decimal(10,2)"`
+	MinDays  int      `gorm:"not null;default:0"`
+	MaxDays  int      `gorm:"not null;default:0"`
+	Active   bool     `gorm:"not null"`
+}
+
+func (p *ShippingProfile) CountryList() []string {
+	if p.Countries == "" {
+		return []string{}
+	}
+	return strings.Split(p.Countries, ",")
+}
+
+func (p *ShippingProfile) SetCountryList(countries []string) {
+	normalized := make([]string, len(countries))
+	for i, country := range countries {
+		normalized[i] = strings.ToUpper(strings.TrimSpace(country))
+	}
+	p.Countries = strings.Join(normalized, ",")
+}
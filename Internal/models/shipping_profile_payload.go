@@ -0,0 +1,18 @@
+package models
+
+type ShippingProfilePayload struct {
+	Name        string   `json:"name" binding:"required,max=100"`
+	Type        string   `json:"type" binding:"required,oneof=flat weight"`
+	Countries   []string `json:"countries" binding:"omitempty,max=250,dive,len=2,alpha"`
+	BaseAmount  float64  `json:"base_amount" binding:"gte=0"`
+	PerKgAmount float64  `json:"per_kg_amount" binding:"gte=0"`
+	FreeOver    *float64 `json:"free_over" binding:"omitempty,gt=0"`
+	MinDays     int      `json:"min_days" binding:"gte=0"`
+	MaxDays     int      `json:"max_days" binding:"gte=0,gtefield=MinDays"`
+	Active      *bool    `json:"active"`
+}
+
+// SelectShippingPayload picks one quote per shop in the cart, by quote ID.
+type SelectShippingPayload struct {
+	QuoteIDs []string `json:"quote_ids" binding:"required,min=1,dive,required"`
+}
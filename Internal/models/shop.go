@@ -6,9 +6,12 @@ import (
 
 type Shop struct {
 	gorm.Model
-	Name         string    `gorm:"type:varchar(150);unique;not null"`
-	Description  string    `gorm:"type:text"`
-	ShopImageUrl string    `gorm:"type:varchar(255)"`
-	UserID       uint      `gorm:"type:int;not null"`
-	Products     []Product `gorm:"foreignKey:ShopID"`
+	Name          string    `gorm:"type:varchar(150);unique;not null"`
+	Description   string    `gorm:"type:text"`
+	Policies      string    `gorm:"type:text"`
+	PayoutDetails string    `gorm:"type:text"`
+	ShopImageUrl  string    `gorm:"type:varchar(255)"`
+	ShopImageKey  string    `gorm:"type:varchar(255)"`
+	UserID        uint      `gorm:"type:int;not null;uniqueIndex:idx_shops_user_id,where:deleted_at IS NULL"`
+	Products      []Product `gorm:"foreignKey:ShopID"`
 }
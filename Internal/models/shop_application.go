@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ShopApplication is a customer's request to become a seller.
+// Once approved it is linked to the shop that was created for the applicant.
+type ShopApplication struct {
+	gorm.Model
+	UserID          uint   `gorm:"not null;index"`
+	Name            string `gorm:"type:varchar(150);not null"`
+	Description     string `gorm:"type:text"`
+	Policies        string `gorm:"type:text"`
+	PayoutDetails   string `gorm:"type:text;not null"`
+	Status          string `gorm:"type:varchar(20);default:'pending';not null;index"`
+	RejectionReason string `gorm:"type:text"`
+	ReviewerID      *uint
+	ReviewedAt      *time.Time
+	ShopID          *uint
+}
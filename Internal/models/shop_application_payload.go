@@ -0,0 +1,12 @@
+package models
+
+type ShopApplicationPayload struct {
+	Name          string `json:"name" binding:"required,min=2"`
+	Description   string `json:"description" binding:"omitempty"`
+	Policies      string `json:"policies" binding:"omitempty"`
+	PayoutDetails string `json:"payout_details" binding:"required"`
+}
+
+type ReviewShopApplicationPayload struct {
+	Reason string `json:"reason" binding:"omitempty"`
+}
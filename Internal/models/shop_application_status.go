@@ -0,0 +1,9 @@
+package models
+
+type ShopApplicationStatus string
+
+const (
+	ApplicationPending  ShopApplicationStatus = "pending"
+	ApplicationApproved ShopApplicationStatus = "approved"
+	ApplicationRejected ShopApplicationStatus = "rejected"
+)
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ShopOrder is the part of an order one shop fulfils. Checkout splits the
+// order into one per shop; the customer still sees and pays the parent order.
+type ShopOrder struct {
+	gorm.Model
+	OrderID uint   `gorm:"not null;index"`
+	ShopID  uint   `gorm:"not null;index"`
+	Status  string `gorm:"type:varchar(20);not null;index"`
+	// The amounts are the shop's share of the parent order's. Order-wide
+	// discounts are spread over the shops by subtotal.
+	Subtotal      float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	DiscountTotal float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	TaxTotal      float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	ShippingTotal float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	Total         float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	// ShippingMethod is the name of the shipping line chosen for the shop.
+	ShippingMethod string `gorm:"type:varchar(100);not null;default:''"`
+	// Carrier and the tracking fields are those of the latest shipment
+	// unless the seller sets them by hand.
+	Carrier        string `gorm:"type:varchar(100);not null;default:''"`
+	TrackingNumber string `gorm:"type:varchar(100);not null;default:''"`
+	TrackingURL    string `gorm:"type:varchar(500);not null;default:''"`
+	ShippedAt      *time.Time
+	DeliveredAt    *time.Time
+	CancelledAt    *time.Time
+	Items          []OrderItem `gorm:"foreignKey:ShopOrderID"`
+	Shipments      []Shipment  `gorm:"foreignKey:ShopOrderID"`
+	Order          Order       `gorm:"foreignKey:OrderID"`
+}
+
+// SplitByShop groups the order's items into one ShopOrder per shop. shopOf
+// maps each product to its shop. OrderItems, Discounts and ShippingLines must
+// be loaded and the order priced; the shares add up to the order's totals.
+// Each shop's discount is its items' share of the coupons that cover them.
+func (o *Order) SplitByShop(shopOf map[uint]uint) []ShopOrder {
+	var shopOrders []ShopOrder
+	index := make(map[uint]int)
+	for _, item := range o.OrderItems {
+		shopID := shopOf[item.ProductID]
+		i, ok := index[shopID]
+		if !ok {
+			i = len(shopOrders)
+			index[shopID] = i
+			shopOrders = append(shopOrders, ShopOrder{
+				OrderID: o.ID,
+				ShopID:  shopID,
+				Status:  string(ShopOrderPending),
+			})
+		}
+		shopOrders[i].Subtotal += item.LineTotal()
+		shopOrders[i].DiscountTotal += item.CouponDiscount
+		shopOrders[i].TaxTotal += item.Tax
+		shopOrders[i].Items = append(shopOrders[i].Items, item)
+	}
+	for _, line := range o.ShippingLines {
+		i, ok := index[line.ShopID]
+		if !ok {
+			continue
+		}
+		shopOrders[i].ShippingMethod = line.Name
+		shopOrders[i].Carrier = line.Carrier
+		if !line.Waived {
+			shopOrders[i].ShippingTotal += line.Amount
+		}
+	}
+
+	for i := range shopOrders {
+		shopOrder := &shopOrders[i]
+		shopOrder.Subtotal = RoundMoney(shopOrder.Subtotal)
+		shopOrder.DiscountTotal = RoundMoney(shopOrder.DiscountTotal)
+		shopOrder.TaxTotal = RoundMoney(shopOrder.TaxTotal)
+		shopOrder.ShippingTotal = RoundMoney(shopOrder.ShippingTotal)
+		shopOrder.Total = RoundMoney(shopOrder.Subtotal - shopOrder.DiscountTotal + shopOrder.ShippingTotal)
+		if !o.PricesIncludeTax {
+			shopOrder.Total = RoundMoney(shopOrder.Total + shopOrder.TaxTotal)
+		}
+	}
+	return shopOrders
+}
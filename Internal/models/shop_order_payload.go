@@ -0,0 +1,10 @@
+package models
+
+// UpdateShopOrderPayload moves a shop order on and records its tracking.
+// Fields left out are not changed.
+type UpdateShopOrderPayload struct {
+	Status         *string `json:"status" binding:"omitempty,oneof=processing shipped delivered cancelled"`
+	Carrier        *string `json:"carrier" binding:"omitempty,max=100"`
+	TrackingNumber *string `json:"tracking_number" binding:"omitempty,max=100"`
+	TrackingURL    *string `json:"tracking_url" binding:"omitempty,max=500,url"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type ShopOrderStatus string
+
+const (
+	ShopOrderPending    ShopOrderStatus = "pending"
+	ShopOrderProcessing ShopOrderStatus = "processing"
+	ShopOrderShipped    ShopOrderStatus = "shipped"
+	ShopOrderDelivered  ShopOrderStatus = "delivered"
+	ShopOrderCancelled  ShopOrderStatus = "cancelled"
+)
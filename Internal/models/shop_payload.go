@@ -4,11 +4,14 @@ type ShopPayload struct {
 	ID           uint   `json:"id"`
 	Name         string `json:"name" binding:"required,min=2"`
 	Description  string `json:"description" binding:"omitempty"`
+	Policies     string `json:"policies" binding:"omitempty"`
 	ShopImageUrl string `json:"shop_image_url" binding:"omitempty,url"`
+	UserID       uint   `json:"user_id" binding:"required"`
 }
 
 type UpdateShopPayload struct {
 	Name         string `json:"name" binding:"omitempty,min=2"`
 	Description  string `json:"description" binding:"omitempty"`
+	Policies     string `json:"policies" binding:"omitempty"`
 	ShopImageUrl string `json:"shop_image_url" binding:"omitempty,url"`
 }
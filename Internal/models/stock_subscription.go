@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StockSubscription is a customer's "notify me" request for an out-of-stock
+// product. It fires once, when the product is restocked.
+type StockSubscription struct {
+	gorm.Model
+	ProductID  uint `gorm:"not null;index"`
+	UserID     uint `gorm:"not null;index"`
+	NotifiedAt *time.Time
+}
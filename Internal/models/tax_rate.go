@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// TaxRate is a row of the tax table used to price orders. An empty Region
+// covers the whole country and an empty TaxCategory every product.
+type TaxRate struct {
+	gorm.Model
+	Country     string  `gorm:"type:varchar(2);not null;index"`
+	Region      string  `gorm:"type:varchar(50);not null;default:''"`
+	TaxCategory string  `gorm:"type:varchar(50);not null;default:''"`
+	Name        string  `gorm:"type:varchar(100);not null"`
+	Percent     float64 `gorm:"type:decimal(6,3);not null"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type TaxRatePayload struct {
+	Country     string  `json:"country" binding:"required,len=2,alpha"`
+	Region      string  `json:"region" binding:"omitempty,max=50"`
+	TaxCategory string  `json:"tax_category" binding:"omitempty,max=50"`
+	Name        string  `json:"name" binding:"required,max=100"`
+	Percent     float64 `json:"percent" binding:"gte=0,lte=100"`
+}
@@ -15,6 +15,7 @@ type User struct {
 	Email                 string `gorm:"type:varchar(255);unique;not null"`
 	Password              string
 	ProfileImageURL       string `gorm:"type:varchar(255)"`
+	ProfileImageKey       string `gorm:"type:varchar(255)"`
 	Role                  string `gorm:"type:varchar(20);default:'customer'"`
 	RefreshToken          string
 	RefreshTokenExpiresAt time.Time
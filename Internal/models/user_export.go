@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// UserExport is everything the platform stores about a single user, in the
+// shape handed out by the data export endpoint. Secrets are never included.
+type UserExport struct {
+	GeneratedAt        time.Time                 `json:"generated_at"`
+	Profile            ExportProfile             `json:"profile"`
+	Shop               *ExportShop               `json:"shop,omitempty"`
+	ShopApplications   []ExportShopApplication   `json:"shop_applications"`
+	Addresses          []ExportAddress           `json:"addresses"`
+	Orders             []ExportOrder             `json:"orders"`
+	Reviews            []ExportReview            `json:"reviews"`
+	APIKeys            []ExportAPIKey            `json:"api_keys"`
+	StockSubscriptions []ExportStockSubscription `json:"stock_subscriptions"`
+}
+
+type ExportProfile struct {
+	ID              uint      `json:"id"`
+	Email           string    `json:"email"`
+	FirstName       string    `json:"first_name"`
+	LastName        string    `json:"last_name"`
+	ProfileImageURL string    `json:"profile_image_url"`
+	Role            string    `json:"role"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type ExportShop struct {
+	ID            uint      `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Policies      string    `json:"policies"`
+	PayoutDetails string    `json:"payout_details"`
+	ShopImageUrl  string    `json:"shop_image_url"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ExportShopApplication struct {
+	ID              uint      `json:"id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	Policies        string    `json:"policies"`
+	PayoutDetails   string    `json:"payout_details"`
+	Status          string    `json:"status"`
+	RejectionReason string    `json:"rejection_reason"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type ExportOrder struct {
+	ID              uint              `json:"id"`
+	Number          *string           `json:"number"`
+	TotalAmount     float64           `json:"total_amount"`
+	Status          string            `json:"status"`
+	ShippingAddress string            `json:"shipping_address"`
+	BillingAddress  string            `json:"billing_address"`
+	Items           []ExportOrderItem `json:"items"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+type ExportAddress struct {
+	Label             string    `json:"label"`
+	FullName          string    `json:"full_name"`
+	Line1             string    `json:"line1"`
+	Line2             string    `json:"line2"`
+	City              string    `json:"city"`
+	Region            string    `json:"region"`
+	PostalCode        string    `json:"postal_code"`
+	Country           string    `json:"country"`
+	Phone             string    `json:"phone"`
+	IsDefaultShipping bool      `json:"is_default_shipping"`
+	IsDefaultBilling  bool      `json:"is_default_billing"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type ExportOrderItem struct {
+	ProductID uint    `json:"product_id"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+type ExportReview struct {
+	ID        uint      `json:"id"`
+	ProductID uint      `json:"product_id"`
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportAPIKey struct {
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     string     `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ExportStockSubscription struct {
+	ProductID  uint       `json:"product_id"`
+	NotifiedAt *time.Time `json:"notified_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileNotifier appends each notification as a JSON line to a file, so local
+// setups can inspect what would have been sent.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) (*FileNotifier, error) {
+	// Fail at startup rather than on the first notification.
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	return &FileNotifier{path: path}, nil
+}
+
+func (n *FileNotifier) Send(ctx context.Context, notification Notification) error {
+	if notification.SentAt.IsZero() {
+		notification.SentAt = time.Now()
+	}
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
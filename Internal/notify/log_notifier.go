@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogNotifier writes notifications to the application log. It is meant for
+// local development.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Send(ctx context.Context, notification Notification) error {
+	slog.InfoContext(ctx, "notification",
+		"to", notification.To,
+		"subject", notification.Subject,
+		"body", notification.Body)
+	return nil
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Notification is a message for a single recipient.
+type Notification struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Notifier delivers notifications, e.g. by email. Implementations must be
+// safe for concurrent use.
+type Notifier interface {
+	Send(ctx context.Context, notification Notification) error
+}
+
+// NewNotifierFromEnv picks the notifier configured through the environment.
+// NOTIFIER=file appends to NOTIFY_FILE (default ./notifications.log); anything
+// else writes notifications to the application log.
+func NewNotifierFromEnv() (Notifier, error) {
+	if os.Getenv("NOTIFIER") == "file" {
+		path := os.Getenv("NOTIFY_FILE")
+		if path == "" {
+			path = "./notifications.log"
+		}
+		return NewFileNotifier(path)
+	}
+	return NewLogNotifier(), nil
+}
@@ -0,0 +1,204 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FakeScenario decides how the fake provider treats a payment.
+type FakeScenario string
+
+const (
+	FakeSuccess FakeScenario = "success"
+	FakeDecline FakeScenario = "decline"
+	// Fake3DS requires an action that succeeds when the intent is confirmed.
+	Fake3DS FakeScenario = "3ds"
+	// Fake3DSDecline requires an action that fails when the intent is confirmed.
+	Fake3DSDecline FakeScenario = "3ds_decline"
+)
+
+func (s FakeScenario) valid() bool {
+	switch s {
+	case FakeSuccess, FakeDecline, Fake3DS, Fake3DSDecline:
+		return true
+	}
+	return false
+}
+
+// FakeProvider is an in-memory gateway for local development and tests. A
+// payment method token naming a scenario, e.g. "3ds", picks that scenario;
+// any other token gets the provider's default. Intents do not survive a
+// restart.
+//
+// Webhooks are signed with SignWebhook and carry a JSON body such as
+//
+//	{"id": "evt_1", "type": "payment.captured",
+//	 "intent": {"id": "fake_pi_…", "status": "captured", "amount": 20, "captured_amount": 20}}
+type FakeProvider struct {
+	defaultScenario FakeScenario
+	webhookSecret   string
+
+	mu        sync.Mutex
+	intents   map[string]*Intent
+	scenarios map[string]FakeScenario
+}
+
+func NewFakeProvider(defaultScenario FakeScenario, webhookSecret string) *FakeProvider {
+	return &FakeProvider{
+		defaultScenario: defaultScenario,
+		webhookSecret:   webhookSecret,
+		intents:         make(map[string]*Intent),
+		scenarios:       make(map[string]FakeScenario),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, request IntentRequest) (*Intent, error) {
+	scenario := FakeScenario(request.PaymentMethod)
+	if !scenario.valid() {
+		scenario = p.defaultScenario
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	intent := &Intent{ID: "fake_pi_" + hex.EncodeToString(id), Amount: request.Amount}
+	switch scenario {
+	case FakeSuccess:
+		intent.Status = Authorized
+	case FakeDecline:
+		intent.Status = Declined
+		intent.DeclineReason = "Your card was declined."
+	case Fake3DS, Fake3DSDecline:
+		intent.Status = RequiresAction
+		intent.ActionURL = "https://payments.fake.invalid/3ds/" + intent.ID
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intents[intent.ID] = intent
+	p.scenarios[intent.ID] = scenario
+	copied := *intent
+	return &copied, nil
+}
+
+func (p *FakeProvider) ConfirmIntent(ctx context.Context, intentID string) (*Intent, error) {
+	return p.update(intentID, func(intent *Intent, scenario FakeScenario) error {
+		if intent.Status != RequiresAction {
+			return ErrIntentState
+		}
+		intent.ActionURL = ""
+		if scenario == Fake3DSDecline {
+			intent.Status = Declined
+			intent.DeclineReason = "The card could not be authenticated."
+			return nil
+		}
+		intent.Status = Authorized
+		return nil
+	})
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, intentID string, amount float64) (*Intent, error) {
+	return p.update(intentID, func(intent *Intent, _ FakeScenario) error {
+		if intent.Status != Authorized {
+			return ErrIntentState
+		}
+		if amount > intent.Amount+0.005 {
+			return ErrAmount
+		}
+		intent.Status = Captured
+		intent.CapturedAmount = amount
+		return nil
+	})
+}
+
+func (p *FakeProvider) Void(ctx context.Context, intentID string) (*Intent, error) {
+	return p.update(intentID, func(intent *Intent, _ FakeScenario) error {
+		if intent.Status != Authorized && intent.Status != RequiresAction {
+			return ErrIntentState
+		}
+		intent.Status = Voided
+		intent.ActionURL = ""
+		return nil
+	})
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, intentID string, amount float64) (*Intent, error) {
+	return p.update(intentID, func(intent *Intent, _ FakeScenario) error {
+		if intent.Status != Captured {
+			return ErrIntentState
+		}
+		if amount > intent.CapturedAmount-intent.RefundedAmount+0.005 {
+			return ErrAmount
+		}
+		intent.RefundedAmount = math.Round((intent.RefundedAmount+amount)*100) / 100
+		return nil
+	})
+}
+
+func (p *FakeProvider) VerifyWebhook(header http.Header, payload []byte, now time.Time) error {
+	if p.webhookSecret == "" {
+		return ErrInvalidSignature
+	}
+	return VerifyWebhook(p.webhookSecret, header.Get(SignatureHeader), payload, now)
+}
+
+type fakeEvent struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Intent struct {
+		ID             string  `json:"id"`
+		Status         string  `json:"status"`
+		Amount         float64 `json:"amount"`
+		CapturedAmount float64 `json:"captured_amount"`
+		RefundedAmount float64 `json:"refunded_amount"`
+		DeclineReason  string  `json:"decline_reason"`
+	} `json:"intent"`
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte) (*Event, error) {
+	var decoded fakeEvent
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, err
+	}
+	if decoded.ID == "" || decoded.Type == "" || decoded.Intent.ID == "" {
+		return nil, errors.New("webhook event needs an id, a type and an intent id")
+	}
+	return &Event{
+		ID:   decoded.ID,
+		Type: EventType(decoded.Type),
+		Intent: Intent{
+			ID:             decoded.Intent.ID,
+			Status:         Status(decoded.Intent.Status),
+			Amount:         decoded.Intent.Amount,
+			CapturedAmount: decoded.Intent.CapturedAmount,
+			RefundedAmount: decoded.Intent.RefundedAmount,
+			DeclineReason:  decoded.Intent.DeclineReason,
+		},
+	}, nil
+}
+
+func (p *FakeProvider) update(intentID string, change func(*Intent, FakeScenario) error) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if err := change(intent, p.scenarios[intentID]); err != nil {
+		return nil, err
+	}
+	copied := *intent
+	return &copied, nil
+}
@@ -0,0 +1,104 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+var (
+	ErrIntentNotFound = errors.New("payment intent not found")
+	// ErrIntentState is returned for operations the intent's status does not
+	// allow, such as capturing a declined payment.
+	ErrIntentState = errors.New("payment intent is not in a state that allows this")
+	// ErrAmount is returned when capturing or refunding more than is left.
+	ErrAmount = errors.New("amount exceeds what is available on the payment")
+)
+
+type Status string
+
+const (
+	// RequiresAction means the customer must complete a step with their bank,
+	// such as 3-D Secure, before the payment is authorized.
+	RequiresAction Status = "requires_action"
+	Authorized     Status = "authorized"
+	Declined       Status = "declined"
+	Captured       Status = "captured"
+	Voided         Status = "voided"
+)
+
+// IntentRequest asks for a payment of Amount to be authorized.
+type IntentRequest struct {
+	OrderID  uint
+	Amount   float64
+	Currency string
+	// PaymentMethod is the provider's token for the card or wallet the
+	// customer entered on the client.
+	PaymentMethod string
+}
+
+// Intent is a payment as the provider sees it.
+type Intent struct {
+	ID     string
+	Status Status
+	Amount float64
+	// CapturedAmount and RefundedAmount are running totals.
+	CapturedAmount float64
+	RefundedAmount float64
+	// ActionURL is where the customer completes a RequiresAction step.
+	ActionURL string
+	// DeclineReason explains a Declined status to the customer.
+	DeclineReason string
+}
+
+// Provider talks to a payment gateway. Authorization and capture are separate
+// so money is only taken once the order can be fulfilled. Implementations
+// must be safe for concurrent use.
+type Provider interface {
+	// Name identifies the provider on stored payments.
+	Name() string
+	CreateIntent(ctx context.Context, request IntentRequest) (*Intent, error)
+	// ConfirmIntent finishes an intent once the customer completed the
+	// required action.
+	ConfirmIntent(ctx context.Context, intentID string) (*Intent, error)
+	Capture(ctx context.Context, intentID string, amount float64) (*Intent, error)
+	// Void releases an authorization that was not captured.
+	Void(ctx context.Context, intentID string) (*Intent, error)
+	Refund(ctx context.Context, intentID string, amount float64) (*Intent, error)
+	// VerifyWebhook checks that a webhook delivery is signed by the provider
+	// and recent. It returns ErrInvalidSignature or ErrStaleWebhook if not.
+	VerifyWebhook(header http.Header, payload []byte, now time.Time) error
+	// ParseWebhook decodes a verified webhook payload.
+	ParseWebhook(payload []byte) (*Event, error)
+}
+
+// NewProviderFromEnv picks the provider configured through the environment.
+// The fake provider is the only one bundled; PAYMENT_PROVIDER defaults to it
+// and FAKE_PAYMENT_SCENARIO sets its default scenario. PAYMENT_WEBHOOK_SECRET
+// signs webhooks; without it every webhook is rejected.
+func NewProviderFromEnv() (Provider, error) {
+	switch name := os.Getenv("PAYMENT_PROVIDER"); name {
+	case "", "fake":
+		scenario := FakeScenario(os.Getenv("FAKE_PAYMENT_SCENARIO"))
+		if scenario == "" {
+			scenario = FakeSuccess
+		}
+		if !scenario.valid() {
+			return nil, fmt.Errorf("unknown fake payment scenario %q", scenario)
+		}
+		return NewFakeProvider(scenario, os.Getenv("PAYMENT_WEBHOOK_SECRET")), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+}
+
+// CurrencyFromEnv returns PAYMENT_CURRENCY, an ISO 4217 code, or USD.
+func CurrencyFromEnv() string {
+	if currency := os.Getenv("PAYMENT_CURRENCY"); currency != "" {
+		return currency
+	}
+	return "USD"
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *APIKeyRepository) FindByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) FindForUser(userID, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("user_id = ?", userID).First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) FindAllForUser(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, key *models.APIKey, at time.Time) error {
+	return r.db.WithContext(ctx).Model(key).Update("revoked_at", at).Error
+}
+
+func (r *APIKeyRepository) TouchLastUsed(key *models.APIKey, at time.Time) error {
+	return r.db.Model(key).UpdateColumn("last_used_at", at).Error
+}
@@ -0,0 +1,371 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Keys the audit callbacks read from the statement context. The API layer
+// stores them on the gin.Context, which handlers pass to GORM via WithContext.
+const (
+	AuditActorKey        = "userID"
+	AuditImpersonatorKey = "impersonatorID"
+	AuditRequestIDKey    = "requestID"
+	AuditClientIPKey     = "clientIP"
+)
+
+type auditRedactKey struct{}
+
+// WithAuditRedaction marks writes made with ctx so their audit events record
+// which columns changed but not the values, e.g. when erasing personal data.
+func WithAuditRedaction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditRedactKey{}, true)
+}
+
+// DetachAuditContext copies the audit keys from ctx onto a fresh context, so
+// background work started by a request is still attributed to its caller.
+func DetachAuditContext(ctx context.Context) context.Context {
+	detached := context.Background()
+	for _, key := range []string{AuditActorKey, AuditImpersonatorKey, AuditRequestIDKey, AuditClientIPKey} {
+		if value := ctx.Value(key); value != nil {
+			detached = context.WithValue(detached, key, value)
+		}
+	}
+	return detached
+}
+
+var (
+	ErrAuditLogImmutable        = errors.New("audit events cannot be modified or deleted")
+	ErrInventoryLedgerImmutable = errors.New("inventory movements cannot be modified or deleted")
+	ErrSellerLedgerImmutable    = errors.New("ledger postings cannot be modified or deleted")
+	ErrInvoiceImmutable         = errors.New("issued invoices cannot be modified or deleted")
+)
+
+// redactedColumns are recorded as changed but never written in clear text.
+var redactedColumns = map[string]bool{
+	"password":       true,
+	"refresh_token":  true,
+	"payout_details": true,
+}
+
+// ignoredColumns change on every write and only add noise to a diff.
+var ignoredColumns = map[string]bool{
+	"updated_at":   true,
+	"last_used_at": true,
+}
+
+const (
+	auditBeforeKey = "audit:before"
+	// auditSnapshotLimit caps how many rows a single bulk statement records.
+	auditSnapshotLimit = 500
+)
+
+var auditEventType = reflect.TypeOf(models.AuditEvent{})
+
+// appendOnlyModels may be inserted into but never updated or deleted.
+var appendOnlyModels = map[reflect.Type]error{
+	auditEventType: ErrAuditLogImmutable,
+	reflect.TypeOf(models.InventoryMovement{}): ErrInventoryLedgerImmutable,
+	reflect.TypeOf(models.LedgerTransaction{}): ErrSellerLedgerImmutable,
+	reflect.TypeOf(models.LedgerEntry{}):       ErrSellerLedgerImmutable,
+	reflect.TypeOf(models.Invoice{}):           ErrInvoiceImmutable,
+}
+
+// unauditedModels are caches and counters rather than records and are not
+// audited.
+var unauditedModels = map[reflect.Type]bool{
+	reflect.TypeOf(models.IdempotencyKey{}):   true,
+	reflect.TypeOf(models.DocumentSequence{}): true,
+}
+
+// RegisterAuditCallbacks hooks into GORM so that every create, update and
+// delete also appends an AuditEvent in the same transaction. If the event
+// cannot be written the whole statement is rolled back.
+func RegisterAuditCallbacks(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_create", auditAfterCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:before_update").
+		Register("audit:before_update", auditCaptureBefore); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_update", auditAfterUpdate); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:before_delete").
+		Register("audit:before_delete", auditCaptureBefore); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_delete", auditAfterDelete)
+}
+
+func auditAfterCreate(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+
+	ids := primaryKeys(db.Statement)
+	if len(ids) == 0 {
+		return
+	}
+
+	events := make([]models.AuditEvent, 0, len(ids))
+	for _, row := range snapshot(db, nil, ids) {
+		events = append(events, newAuditEvent(db, models.AuditCreate, row, nil, redactRow(row)))
+	}
+	writeAuditEvents(db, events)
+}
+
+// auditCaptureBefore stores the rows a statement is about to touch so the
+// after callbacks can diff against them.
+func auditCaptureBefore(db *gorm.DB) {
+	if db.Statement.Schema != nil {
+		if err, ok := appendOnlyModels[db.Statement.Schema.ModelType]; ok {
+			db.AddError(err)
+			return
+		}
+	}
+	if !auditable(db) {
+		return
+	}
+
+	var where *clause.Where
+	if c, ok := db.Statement.Clauses["WHERE"]; ok {
+		if w, ok := c.Expression.(clause.Where); ok && len(w.Exprs) > 0 {
+			where = &w
+		}
+	}
+
+	ids := primaryKeys(db.Statement)
+	if where == nil && len(ids) == 0 {
+		return
+	}
+	db.InstanceSet(auditBeforeKey, snapshot(db, where, ids))
+}
+
+func auditAfterUpdate(db *gorm.DB) {
+	before, ok := capturedRows(db)
+	if !ok {
+		return
+	}
+
+	pk := db.Statement.Schema.PrioritizedPrimaryField.DBName
+	ids := make([]interface{}, len(before))
+	for i, row := range before {
+		ids[i] = row[pk]
+	}
+
+	afterByID := make(map[uint]map[string]interface{}, len(before))
+	for _, row := range snapshot(db, nil, ids) {
+		afterByID[toUint(row[pk])] = row
+	}
+
+	events := make([]models.AuditEvent, 0, len(before))
+	for _, oldRow := range before {
+		newRow, found := afterByID[toUint(oldRow[pk])]
+		if !found {
+			continue
+		}
+		oldValues, newValues := diffRows(oldRow, newRow)
+		if len(newValues) == 0 {
+			continue
+		}
+		events = append(events, newAuditEvent(db, models.AuditUpdate, oldRow, oldValues, newValues))
+	}
+	writeAuditEvents(db, events)
+}
+
+func auditAfterDelete(db *gorm.DB) {
+	before, ok := capturedRows(db)
+	if !ok {
+		return
+	}
+
+	events := make([]models.AuditEvent, 0, len(before))
+	for _, row := range before {
+		events = append(events, newAuditEvent(db, models.AuditDelete, row, redactRow(row), nil))
+	}
+	writeAuditEvents(db, events)
+}
+
+func auditable(db *gorm.DB) bool {
+	stmt := db.Statement
+	return db.Error == nil &&
+		stmt.Schema != nil &&
+		stmt.Schema.PrioritizedPrimaryField != nil &&
+		stmt.Schema.ModelType != auditEventType &&
+		!unauditedModels[stmt.Schema.ModelType]
+}
+
+func capturedRows(db *gorm.DB) ([]map[string]interface{}, bool) {
+	if db.Error != nil || db.Statement.RowsAffected == 0 {
+		return nil, false
+	}
+	value, ok := db.InstanceGet(auditBeforeKey)
+	if !ok {
+		return nil, false
+	}
+	rows, ok := value.([]map[string]interface{})
+	return rows, ok && len(rows) > 0
+}
+
+// primaryKeys returns the non-zero primary key values held by the statement's
+// model, which may be a single struct or a slice of them.
+func primaryKeys(stmt *gorm.Statement) []interface{} {
+	field := stmt.Schema.PrioritizedPrimaryField
+	rv := reflect.Indirect(stmt.ReflectValue)
+
+	var ids []interface{}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if id, zero := field.ValueOf(stmt.Context, reflect.Indirect(rv.Index(i))); !zero {
+				ids = append(ids, id)
+			}
+		}
+	case reflect.Struct:
+		if id, zero := field.ValueOf(stmt.Context, rv); !zero {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// snapshot reads the current state of the matching rows as column maps. It
+// runs on the statement's connection so it sees uncommitted changes.
+func snapshot(db *gorm.DB, where *clause.Where, ids []interface{}) []map[string]interface{} {
+	stmt := db.Statement
+	// A fresh model value keeps the schema (needed to resolve primary key
+	// conditions) without leaking the statement's own key values.
+	model := reflect.New(stmt.Schema.ModelType).Interface()
+	tx := db.Session(&gorm.Session{NewDB: true}).Model(model).Table(stmt.Table).Limit(auditSnapshotLimit)
+	if where != nil {
+		tx = tx.Clauses(*where)
+	}
+	if len(ids) > 0 {
+		tx = tx.Where(clause.IN{Column: clause.Column{Name: stmt.Schema.PrioritizedPrimaryField.DBName}, Values: ids})
+	}
+
+	var rows []map[string]interface{}
+	if err := tx.Find(&rows).Error; err != nil {
+		db.AddError(fmt.Errorf("audit snapshot of %s: %w", stmt.Table, err))
+		return nil
+	}
+	return rows
+}
+
+func diffRows(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	oldValues := map[string]interface{}{}
+	newValues := map[string]interface{}{}
+	for column, value := range after {
+		if ignoredColumns[column] || reflect.DeepEqual(before[column], value) {
+			continue
+		}
+		oldValues[column] = redact(column, before[column])
+		newValues[column] = redact(column, value)
+	}
+	return oldValues, newValues
+}
+
+func redactRow(row map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(row))
+	for column, value := range row {
+		redacted[column] = redact(column, value)
+	}
+	return redacted
+}
+
+func redactValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(values))
+	for column := range values {
+		redacted[column] = "[redacted]"
+	}
+	return redacted
+}
+
+func redact(column string, value interface{}) interface{} {
+	if redactedColumns[column] && value != nil && value != "" {
+		return "[redacted]"
+	}
+	return value
+}
+
+func newAuditEvent(db *gorm.DB, action models.AuditAction, row, before, after map[string]interface{}) models.AuditEvent {
+	stmt := db.Statement
+	event := models.AuditEvent{
+		Action:     string(action),
+		EntityType: stmt.Table,
+		EntityID:   toUint(row[stmt.Schema.PrioritizedPrimaryField.DBName]),
+		Before:     encodeAuditValues(before),
+		After:      encodeAuditValues(after),
+	}
+
+	if ctx := stmt.Context; ctx != nil {
+		if redactAll, _ := ctx.Value(auditRedactKey{}).(bool); redactAll {
+			event.Before = encodeAuditValues(redactValues(before))
+			event.After = encodeAuditValues(redactValues(after))
+		}
+		if actorID, ok := ctx.Value(AuditActorKey).(uint); ok {
+			event.ActorID = &actorID
+		}
+		if impersonatorID, ok := ctx.Value(AuditImpersonatorKey).(uint); ok {
+			event.ImpersonatorID = &impersonatorID
+		}
+		event.RequestID, _ = ctx.Value(AuditRequestIDKey).(string)
+		event.IPAddress, _ = ctx.Value(AuditClientIPKey).(string)
+	}
+	return event
+}
+
+func encodeAuditValues(values map[string]interface{}) string {
+	if values == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func writeAuditEvents(db *gorm.DB, events []models.AuditEvent) {
+	if len(events) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&events).Error; err != nil {
+		db.AddError(fmt.Errorf("write audit events: %w", err))
+	}
+}
+
+func toUint(value interface{}) uint {
+	switch v := value.(type) {
+	case int64:
+		return uint(v)
+	case int32:
+		return uint(v)
+	case int:
+		return uint(v)
+	case uint:
+		return v
+	case uint32:
+		return uint(v)
+	case uint64:
+		return uint(v)
+	}
+	return 0
+}
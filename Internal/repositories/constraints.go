@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Constraints that callers turn into conflicts instead of server errors.
+const (
+	ShopNameConstraint   = "uni_shops_name"
+	ShopOwnerConstraint  = "idx_shops_user_id"
+	ProductSKUConstraint = "idx_products_shop_sku"
+)
+
+// IsUniqueViolation reports whether err is the database rejecting a write
+// that breaks the named unique constraint or index.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
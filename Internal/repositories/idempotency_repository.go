@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve saves key unless the user or guest already has a key with that
+// name, and reports whether it did. Otherwise key is overwritten with the
+// stored one.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key *models.IdempotencyKey) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}, {Name: "guest_id"}, {Name: "key"}}, DoNothing: true}).
+		Create(key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+	err := r.db.WithContext(ctx).Where("user_id = ? AND guest_id = ? AND key = ?", key.UserID, key.GuestID, key.Key).
+		First(key).Error
+	return false, err
+}
+
+// Complete stores the response for the key if it is still reserved under
+// the key's ID and unanswered, and reports whether it did.
+func (r *IdempotencyRepository) Complete(ctx context.Context, key *models.IdempotencyKey) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("id = ? AND user_id = ? AND guest_id = ? AND key = ? AND response_status = 0",
+			key.ID, key.UserID, key.GuestID, key.Key).
+		Select("response_status", "content_type", "response_body").Updates(key)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Release deletes the key if it is the one with the given ID, so the request
+// can be tried again or the name reused.
+func (r *IdempotencyRepository) Release(ctx context.Context, key *models.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Where("id = ?", key.ID).Delete(&models.IdempotencyKey{}).Error
+}
+
+// ReleaseStale deletes the key if it expired, or if its first request
+// started before abandonedBefore and never finished, and reports whether it
+// did.
+func (r *IdempotencyRepository) ReleaseStale(ctx context.Context, key *models.IdempotencyKey, now, abandonedBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND (expires_at <= ? OR (response_status = 0 AND created_at < ?))", key.ID, now, abandonedBefore).
+		Delete(&models.IdempotencyKey{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
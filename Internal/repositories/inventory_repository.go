@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientStock = errors.New("not enough stock")
+
+type InventoryRepository struct {
+	db *gorm.DB
+}
+
+func NewInventoryRepository(db *gorm.DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// RecordMovement applies movement.Quantity to the product's stock and appends
+// the movement in one transaction.
+func (r *InventoryRepository) RecordMovement(ctx context.Context, movement *models.InventoryMovement) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return RecordInventoryMovement(tx, movement)
+	})
+}
+
+// RecordInventoryMovement does the work of RecordMovement inside an existing
+// transaction. It fails with ErrInsufficientStock rather than let stock go
+// negative, and fills in movement.StockAfter.
+func RecordInventoryMovement(tx *gorm.DB, movement *models.InventoryMovement) error {
+	result := tx.Model(&models.Product{}).
+		Where("id = ? AND stock + ? >= 0", movement.ProductID, movement.Quantity).
+		Update("stock", gorm.Expr("stock + ?", movement.Quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := tx.Model(&models.Product{}).Where("id = ?", movement.ProductID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return ErrInsufficientStock
+	}
+
+	// The update above holds the row lock, so this reads our own write.
+	err := tx.Model(&models.Product{}).Where("id = ?", movement.ProductID).
+		Select("stock").Scan(&movement.StockAfter).Error
+	if err != nil {
+		return err
+	}
+	return tx.Create(movement).Error
+}
+
+// FindMovements returns a product's movements, newest first.
+func (r *InventoryRepository) FindMovements(productID uint, limit, offset int) ([]models.InventoryMovement, int64, error) {
+	query := r.db.Model(&models.InventoryMovement{}).Where("product_id = ?", productID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var movements []models.InventoryMovement
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&movements).Error
+	return movements, total, err
+}
+
+// FindProductOwner returns the product with the user who owns its shop.
+func (r *InventoryRepository) FindProductOwner(productID uint) (*models.Product, *models.User, error) {
+	var product models.Product
+	if err := r.db.First(&product, productID).Error; err != nil {
+		return nil, nil, err
+	}
+	var owner models.User
+	err := r.db.Joins("JOIN shops ON shops.user_id = users.id AND shops.deleted_at IS NULL").
+		Where("shops.id = ?", product.ShopID).First(&owner).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	return &product, &owner, nil
+}
+
+func (r *InventoryRepository) FindSubscription(productID, userID uint) (*models.StockSubscription, error) {
+	var subscription models.StockSubscription
+	err := r.db.Where("product_id = ? AND user_id = ? AND notified_at IS NULL", productID, userID).
+		First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *InventoryRepository) CreateSubscription(ctx context.Context, subscription *models.StockSubscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (r *InventoryRepository) DeleteSubscription(ctx context.Context, productID, userID uint) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("product_id = ? AND user_id = ? AND notified_at IS NULL", productID, userID).
+		Delete(&models.StockSubscription{})
+	return result.RowsAffected, result.Error
+}
+
+// ClaimPendingSubscriptions marks every pending subscription of the product as
+// notified and returns them with their users, so each fires only once.
+func (r *InventoryRepository) ClaimPendingSubscriptions(ctx context.Context, productID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var subscriptions []models.StockSubscription
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("product_id = ? AND notified_at IS NULL", productID).Find(&subscriptions).Error
+		if err != nil || len(subscriptions) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(subscriptions))
+		userIDs := make([]uint, len(subscriptions))
+		for i, subscription := range subscriptions {
+			ids[i] = subscription.ID
+			userIDs[i] = subscription.UserID
+		}
+		if err := tx.Model(&models.StockSubscription{}).Where("id IN ?", ids).
+			Update("notified_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", userIDs).Find(&users).Error
+	})
+	return users, err
+}
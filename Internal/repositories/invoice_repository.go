@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{db: db}
+}
+
+// NextSequence hands out the next number in the named series, starting at 1.
+// The counter row stays locked until tx ends and a rollback returns the
+// number, so a series has no gaps.
+func NextSequence(tx *gorm.DB, name string) (uint, error) {
+	sequence := models.DocumentSequence{Name: name, Last: 1}
+	err := tx.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.Assignments(map[string]any{"last": gorm.Expr("document_sequences.last + 1")}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "last"}}},
+	).Create(&sequence).Error
+	return sequence.Last, err
+}
+
+// FindInvoiceOrder loads the order with everything an invoice prints.
+func (r *InvoiceRepository) FindInvoiceOrder(orderID uint) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Preload("OrderItems.Taxes").Preload("Discounts").Preload("ShippingLines").
+		Preload("Payments").First(&order, orderID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindProductsByID includes deleted products, which still appear on old
+// invoices.
+func (r *InvoiceRepository) FindProductsByID(ids []uint) (map[uint]models.Product, error) {
+	return FindProductsByID(r.db.Unscoped(), ids)
+}
+
+// FindShopsByID includes deleted shops, which still appear on old invoices.
+func (r *InvoiceRepository) FindShopsByID(ids []uint) (map[uint]models.Shop, error) {
+	var shops []models.Shop
+	if err := r.db.Unscoped().Where("id IN ?", ids).Find(&shops).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Shop, len(shops))
+	for _, shop := range shops {
+		byID[shop.ID] = shop
+	}
+	return byID, nil
+}
+
+func (r *InvoiceRepository) FindUser(userID uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.Unscoped().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindInvoice returns the order's invoice.
+func (r *InvoiceRepository) FindInvoice(orderID uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.db.Where("order_id = ?", orderID).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// IssueInvoice returns the order's invoice, issuing it first if there is
+// none. The number is the next in the year's series, as prefix-YYYY-000001;
+// issue then checks the order can be invoiced and fills in the rest. If it
+// fails the number is handed out again.
+func (r *InvoiceRepository) IssueInvoice(ctx context.Context, orderID uint, prefix string, issue func(order *models.Order, invoice *models.Invoice) error) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+			return err
+		}
+		err := tx.Where("order_id = ?", orderID).First(&invoice).Error
+		if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		now := time.Now()
+		number, err := NextSequence(tx, fmt.Sprintf("invoice:%d", now.Year()))
+		if err != nil {
+			return err
+		}
+		invoice = models.Invoice{
+			OrderID:  orderID,
+			IssuedAt: now,
+			Number:   fmt.Sprintf("%s-%d-%06d", prefix, now.Year(), number),
+		}
+		if err := issue(&order, &invoice); err != nil {
+			return err
+		}
+		return tx.Create(&invoice).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
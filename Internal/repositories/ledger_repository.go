@@ -0,0 +1,321 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnbalancedTransaction = errors.New("ledger transaction does not balance")
+	// ErrPayoutChanged is returned when a payout was settled by someone else
+	// while it was being updated.
+	ErrPayoutChanged = errors.New("the payout was changed by someone else")
+)
+
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// AccountBalance is the sum of an account's entries, positive for a debit
+// balance.
+type AccountBalance struct {
+	Account string
+	Balance float64
+}
+
+// PostLedgerTransaction saves a balanced transaction and its entries unless
+// one with the same reference was posted before, and reports whether it was
+// saved.
+func PostLedgerTransaction(tx *gorm.DB, transaction *models.LedgerTransaction) (bool, error) {
+	var sum float64
+	for _, entry := range transaction.Entries {
+		sum += entry.Amount
+	}
+	if len(transaction.Entries) < 2 || models.RoundMoney(sum) != 0 {
+		return false, ErrUnbalancedTransaction
+	}
+
+	result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "reference"}}, DoNothing: true}).
+		Omit("Entries").Create(transaction)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	for i := range transaction.Entries {
+		transaction.Entries[i].TransactionID = transaction.ID
+	}
+	if err := tx.Create(&transaction.Entries).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Post saves the transactions together; those already posted are skipped.
+func (r *LedgerRepository) Post(ctx context.Context, transactions []*models.LedgerTransaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, transaction := range transactions {
+			if _, err := PostLedgerTransaction(tx, transaction); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindOrderShopOrders loads the order's shop orders with their items.
+func (r *LedgerRepository) FindOrderShopOrders(orderID uint) ([]models.ShopOrder, error) {
+	var shopOrders []models.ShopOrder
+	err := r.db.Preload("Items").Where("order_id = ?", orderID).Order("id").Find(&shopOrders).Error
+	return shopOrders, err
+}
+
+// FindOrderTransactions loads the order's postings of the given kinds.
+func (r *LedgerRepository) FindOrderTransactions(orderID uint, kinds ...models.LedgerKind) ([]models.LedgerTransaction, error) {
+	var transactions []models.LedgerTransaction
+	err := r.db.Preload("Entries").Where("order_id = ? AND kind IN ?", orderID, kinds).
+		Order("id").Find(&transactions).Error
+	return transactions, err
+}
+
+// RefundedTotal is what has been posted as refunded for the payment.
+func (r *LedgerRepository) RefundedTotal(paymentID uint) (float64, error) {
+	var total float64
+	err := r.db.Model(&models.LedgerEntry{}).
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_entries.transaction_id").
+		Where("ledger_transactions.payment_id = ? AND ledger_transactions.kind = ? AND ledger_entries.account = ?",
+			paymentID, models.LedgerRefund, models.LedgerShopPayable).
+		Select("COALESCE(SUM(ledger_entries.amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+func (r *LedgerRepository) FindCommissionRates() ([]models.CommissionRate, error) {
+	var rates []models.CommissionRate
+	err := r.db.Find(&rates).Error
+	return rates, err
+}
+
+// ShopBalance is what the platform owes the shop as of before, or now if
+// before is zero.
+func (r *LedgerRepository) ShopBalance(shopID uint, before time.Time) (float64, error) {
+	return shopBalance(r.db, shopID, before)
+}
+
+// ShopHeld is the shop's sales, net of commission, posted since the given
+// time. They are not paid out until the hold period has passed.
+func (r *LedgerRepository) ShopHeld(shopID uint, since time.Time) (float64, error) {
+	return shopHeld(r.db, shopID, since)
+}
+
+// PendingPayouts is the total of the shop's payouts not yet settled.
+func (r *LedgerRepository) PendingPayouts(shopID uint) (float64, error) {
+	var total float64
+	err := r.db.Model(&models.Payout{}).Where("shop_id = ? AND status = ?", shopID, models.PayoutPending).
+		Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+// FindShopEntries lists the shop's payable entries posted in [from, to),
+// oldest first, with their transactions.
+func (r *LedgerRepository) FindShopEntries(shopID uint, from, to time.Time) ([]models.LedgerEntry, error) {
+	var entries []models.LedgerEntry
+	err := r.db.Preload("Transaction").
+		Where("account = ? AND shop_id = ? AND created_at >= ? AND created_at < ?",
+			models.LedgerShopPayable, shopID, from, to).
+		Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// TrialBalance sums every account. The balances add up to zero.
+func (r *LedgerRepository) TrialBalance() ([]AccountBalance, error) {
+	var balances []AccountBalance
+	err := r.db.Model(&models.LedgerEntry{}).Select("account, SUM(amount) AS balance").
+		Group("account").Order("account").Scan(&balances).Error
+	return balances, err
+}
+
+func (r *LedgerRepository) LatestPayoutBatch() (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	if err := r.db.Order("id DESC").First(&batch).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *LedgerRepository) FindPayoutBatches(limit, offset int) ([]models.PayoutBatch, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.PayoutBatch{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var batches []models.PayoutBatch
+	err := r.db.Order("id DESC").Limit(limit).Offset(offset).Find(&batches).Error
+	return batches, total, err
+}
+
+func (r *LedgerRepository) FindPayoutBatch(batchID uint) (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	if err := r.db.Preload("Payouts").First(&batch, batchID).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *LedgerRepository) FindPayout(payoutID uint) (*models.Payout, error) {
+	var payout models.Payout
+	if err := r.db.First(&payout, payoutID).Error; err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+func (r *LedgerRepository) FindShopPayouts(shopID uint) ([]models.Payout, error) {
+	var payouts []models.Payout
+	err := r.db.Where("shop_id = ?", shopID).Order("id DESC").Find(&payouts).Error
+	return payouts, err
+}
+
+// CreatePayoutBatch pays every shop its balance less what is still held:
+// sales posted after heldSince. Shops owed less than minimum are skipped.
+// The shops are locked while their balances are read, so concurrent runs
+// cannot pay the same money twice. It returns nil if no shop is due a
+// payout.
+func (r *LedgerRepository) CreatePayoutBatch(ctx context.Context, heldSince time.Time, minimum float64) (*models.PayoutBatch, error) {
+	var batch *models.PayoutBatch
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var shopIDs []uint
+		err := tx.Model(&models.LedgerEntry{}).Where("account = ?", models.LedgerShopPayable).
+			Group("shop_id").Having("SUM(amount) < 0").Order("shop_id").Pluck("shop_id", &shopIDs).Error
+		if err != nil || len(shopIDs) == 0 {
+			return err
+		}
+		var shops []models.Shop
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", shopIDs).Order("id").Find(&shops).Error; err != nil {
+			return err
+		}
+
+		for _, shop := range shops {
+			balance, err := shopBalance(tx, shop.ID, time.Time{})
+			if err != nil {
+				return err
+			}
+			held, err := shopHeld(tx, shop.ID, heldSince)
+			if err != nil {
+				return err
+			}
+			amount := models.RoundMoney(balance - max(held, 0))
+			if amount <= 0 || amount < minimum {
+				continue
+			}
+
+			if batch == nil {
+				batch = &models.PayoutBatch{}
+				if err := tx.Create(batch).Error; err != nil {
+					return err
+				}
+			}
+			payout := models.Payout{
+				BatchID: batch.ID,
+				ShopID:  shop.ID,
+				Amount:  amount,
+				Status:  string(models.PayoutPending),
+			}
+			if err := tx.Create(&payout).Error; err != nil {
+				return err
+			}
+			transaction := payoutTransaction(&payout, models.LedgerPayout,
+				models.LedgerShopPayable, models.LedgerPayoutsInTransit, "Payout")
+			if _, err := PostLedgerTransaction(tx, transaction); err != nil {
+				return err
+			}
+			batch.Total = models.RoundMoney(batch.Total + amount)
+			batch.Payouts = append(batch.Payouts, payout)
+		}
+		if batch == nil {
+			return nil
+		}
+		return tx.Model(batch).Update("total", batch.Total).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// SettlePayout moves a pending payout to paid, clearing it from payouts in
+// transit, or to failed, crediting it back to the shop.
+func (r *LedgerRepository) SettlePayout(ctx context.Context, payout *models.Payout) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(payout).Where("status = ?", models.PayoutPending).
+			Select("status", "failure_reason", "paid_at").Updates(payout)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPayoutChanged
+		}
+
+		transaction := payoutTransaction(payout, models.LedgerPayoutSettled,
+			models.LedgerPayoutsInTransit, models.LedgerPlatformCash, "Payout settled")
+		if payout.Status == string(models.PayoutFailed) {
+			transaction = payoutTransaction(payout, models.LedgerPayoutFailed,
+				models.LedgerPayoutsInTransit, models.LedgerShopPayable, "Payout failed: "+payout.FailureReason)
+		}
+		_, err := PostLedgerTransaction(tx, transaction)
+		return err
+	})
+}
+
+// payoutTransaction moves the payout's amount from the credited account to
+// the debited one.
+func payoutTransaction(payout *models.Payout, kind models.LedgerKind, debit, credit models.LedgerAccount, description string) *models.LedgerTransaction {
+	entry := func(account models.LedgerAccount, amount float64) models.LedgerEntry {
+		entry := models.LedgerEntry{Account: string(account), Amount: amount}
+		if account == models.LedgerShopPayable {
+			entry.ShopID = &payout.ShopID
+		}
+		return entry
+	}
+	if len(description) > 255 {
+		description = description[:255]
+	}
+	return &models.LedgerTransaction{
+		Kind:        string(kind),
+		Reference:   fmt.Sprintf("%s:%d", kind, payout.ID),
+		ShopID:      payout.ShopID,
+		PayoutID:    &payout.ID,
+		Description: description,
+		Entries:     []models.LedgerEntry{entry(debit, payout.Amount), entry(credit, -payout.Amount)},
+	}
+}
+
+func shopBalance(tx *gorm.DB, shopID uint, before time.Time) (float64, error) {
+	query := tx.Model(&models.LedgerEntry{}).Where("account = ? AND shop_id = ?", models.LedgerShopPayable, shopID)
+	if !before.IsZero() {
+		query = query.Where("created_at < ?", before)
+	}
+	var sum float64
+	err := query.Select("COALESCE(SUM(amount), 0)").Scan(&sum).Error
+	// The platform owes the shop its credit balance.
+	return models.RoundMoney(-sum), err
+}
+
+func shopHeld(tx *gorm.DB, shopID uint, since time.Time) (float64, error) {
+	var sum float64
+	err := tx.Model(&models.LedgerEntry{}).
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_entries.transaction_id").
+		Where("ledger_entries.account = ? AND ledger_entries.shop_id = ? AND ledger_entries.created_at >= ? AND ledger_transactions.kind IN ?",
+			models.LedgerShopPayable, shopID, since, []models.LedgerKind{models.LedgerSale, models.LedgerCommission}).
+		Select("COALESCE(SUM(ledger_entries.amount), 0)").Scan(&sum).Error
+	return models.RoundMoney(-sum), err
+}
@@ -0,0 +1,509 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrCouponUsageLimit = errors.New("this coupon has reached its usage limit")
+
+var ErrOrderChanged = errors.New("the order changed while the payment was being authorized")
+
+// ErrOrderPlaced is returned by CommitCheckout when the payment already placed
+// the order, e.g. when both the customer and a webhook confirmed it.
+var ErrOrderPlaced = errors.New("the order was already placed with this payment")
+
+// ErrProductUnavailable is an item whose product was deleted or is not
+// published, so it can no longer be bought.
+var ErrProductUnavailable = errors.New("a product in the order is no longer available")
+
+// CheckPurchasable returns ErrProductUnavailable unless every item's product
+// is in products and published.
+func CheckPurchasable(items []models.OrderItem, products map[uint]models.Product) error {
+	for _, item := range items {
+		product, ok := products[item.ProductID]
+		if !ok || product.Status != string(models.ProductPublished) {
+			return ErrProductUnavailable
+		}
+	}
+	return nil
+}
+
+type OrderRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderRepository(db *gorm.DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+func (r *OrderRepository) FindByID(orderID uint) (*models.Order, error) {
+	var order models.Order
+	if err := r.db.Preload("OrderItems.Adjustments").Preload("OrderItems.Taxes").Preload("Discounts").Preload("ShippingLines").Preload("Payments").First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Reprice locks the order, loads its items and shipping lines and calls
+// price, which updates item prices and sets order.Discounts, the shipping
+// lines and the totals. The results are saved in the same transaction.
+func (r *OrderRepository) Reprice(ctx context.Context, orderID uint, price func(tx *gorm.DB, order *models.Order) error) (*models.Order, error) {
+	return r.EditAndReprice(ctx, orderID, nil, price)
+}
+
+// EditAndReprice is Reprice with edit, such as adding an item, called on the
+// locked order first. A failed edit or reprice leaves the order unchanged.
+func (r *OrderRepository) EditAndReprice(ctx context.Context, orderID uint, edit, price func(tx *gorm.DB, order *models.Order) error) (*models.Order, error) {
+	var order models.Order
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if edit != nil {
+			var locked models.Order
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, orderID).Error; err != nil {
+				return err
+			}
+			if err := edit(tx, &locked); err != nil {
+				return err
+			}
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems.Adjustments").Preload("OrderItems.Taxes").
+			Preload("ShippingLines").First(&order, orderID).Error
+		if err != nil {
+			return err
+		}
+		before := make(map[uint]string, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			before[item.ID] = itemPricing(item)
+		}
+		if err := price(tx, &order); err != nil {
+			return err
+		}
+
+		for i := range order.OrderItems {
+			item := &order.OrderItems[i]
+			if itemPricing(*item) == before[item.ID] {
+				continue
+			}
+			if err := tx.Model(item).Select("price", "discount", "coupon_discount", "tax").Updates(item).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("order_item_id = ?", item.ID).Delete(&models.OrderItemAdjustment{}).Error; err != nil {
+				return err
+			}
+			for j := range item.Adjustments {
+				item.Adjustments[j].ID = 0
+				item.Adjustments[j].OrderItemID = item.ID
+				if err := tx.Create(&item.Adjustments[j]).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Unscoped().Where("order_item_id = ?", item.ID).Delete(&models.OrderItemTax{}).Error; err != nil {
+				return err
+			}
+			for j := range item.Taxes {
+				item.Taxes[j].ID = 0
+				item.Taxes[j].OrderItemID = item.ID
+				if err := tx.Create(&item.Taxes[j]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Unscoped().Where("order_id = ?", order.ID).Delete(&models.OrderDiscount{}).Error; err != nil {
+			return err
+		}
+		for i := range order.Discounts {
+			order.Discounts[i].OrderID = order.ID
+			if err := tx.Create(&order.Discounts[i]).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("order_id = ?", order.ID).Delete(&models.OrderShippingLine{}).Error; err != nil {
+			return err
+		}
+		for i := range order.ShippingLines {
+			order.ShippingLines[i].ID = 0
+			order.ShippingLines[i].OrderID = order.ID
+			if err := tx.Create(&order.ShippingLines[i]).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&order).Select("subtotal", "discount_total", "tax_total", "shipping_total", "free_shipping",
+			"prices_include_tax", "total_amount").
+			Updates(&order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// itemPricing summarises an item's price, discounts and taxes so Reprice only
+// writes the items that changed.
+func itemPricing(item models.OrderItem) string {
+	summary := fmt.Sprintf("%.2f/%.2f/%.2f/%.2f", item.Price, item.Discount, item.CouponDiscount, item.Tax)
+	for _, adjustment := range item.Adjustments {
+		ruleID := uint(0)
+		if adjustment.PriceRuleID != nil {
+			ruleID = *adjustment.PriceRuleID
+		}
+		summary += fmt.Sprintf("|%d:%.2f:%s", ruleID, adjustment.Amount, adjustment.Description)
+	}
+	for _, line := range item.Taxes {
+		summary += fmt.Sprintf("|tax:%s:%.3f:%.2f", line.Name, line.Percent, line.Amount)
+	}
+	return summary
+}
+
+// FindProductsByID loads the given products, keyed by ID.
+func FindProductsByID(tx *gorm.DB, ids []uint) (map[uint]models.Product, error) {
+	products := make(map[uint]models.Product, len(ids))
+	if len(ids) == 0 {
+		return products, nil
+	}
+	var found []models.Product
+	if err := tx.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, product := range found {
+		products[product.ID] = product
+	}
+	return products, nil
+}
+
+func (r *OrderRepository) FindProductsByID(ids []uint) (map[uint]models.Product, error) {
+	return FindProductsByID(r.db, ids)
+}
+
+// FindAppliedCoupons returns the coupons applied to the order, in the order
+// they were applied.
+func FindAppliedCoupons(tx *gorm.DB, orderID uint) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	err := tx.Joins("JOIN coupon_redemptions ON coupon_redemptions.coupon_id = coupons.id").
+		Where("coupon_redemptions.order_id = ?", orderID).
+		Order("coupon_redemptions.id").Find(&coupons).Error
+	return coupons, err
+}
+
+func (r *OrderRepository) FindAppliedCoupons(orderID uint) ([]models.Coupon, error) {
+	return FindAppliedCoupons(r.db, orderID)
+}
+
+func (r *OrderRepository) FindCouponByCode(code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := r.db.Where("code = ?", code).First(&coupon).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// RedeemCoupon applies the coupon to the cart as a pending redemption unless
+// its global or per-user limit is already used up. The limits are checked
+// again when the order is placed, which is when the redemption counts.
+func (r *OrderRepository) RedeemCoupon(ctx context.Context, coupon *models.Coupon, redemption *models.CouponRedemption) error {
+	if err := checkCouponLimits(r.db.WithContext(ctx), coupon, redemption.UserID); err != nil {
+		return err
+	}
+	redemption.Pending = true
+	return r.db.WithContext(ctx).Create(redemption).Error
+}
+
+// checkCouponLimits returns ErrCouponUsageLimit if the placed orders using
+// the coupon, overall or by userID, have reached its limits.
+func checkCouponLimits(tx *gorm.DB, coupon *models.Coupon, userID *uint) error {
+	if coupon.UsageLimit != nil {
+		var used int64
+		err := tx.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND pending = ?", coupon.ID, false).
+			Count(&used).Error
+		if err != nil {
+			return err
+		}
+		if used >= int64(*coupon.UsageLimit) {
+			return ErrCouponUsageLimit
+		}
+	}
+	if coupon.PerUserLimit != nil {
+		var used int64
+		err := tx.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ? AND pending = ?", coupon.ID, userID, false).Count(&used).Error
+		if err != nil {
+			return err
+		}
+		if used >= int64(*coupon.PerUserLimit) {
+			return ErrCouponUsageLimit
+		}
+	}
+	return nil
+}
+
+// redeemCoupons counts the order's coupons against their limits. Each coupon
+// row is locked so concurrent checkouts cannot overshoot a limit.
+func redeemCoupons(tx *gorm.DB, order *models.Order) error {
+	var redemptions []models.CouponRedemption
+	err := tx.Where("order_id = ? AND pending = ?", order.ID, true).Order("coupon_id").Find(&redemptions).Error
+	if err != nil {
+		return err
+	}
+	for _, redemption := range redemptions {
+		var coupon models.Coupon
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, redemption.CouponID).Error; err != nil {
+			return err
+		}
+		if err := checkCouponLimits(tx, &coupon, order.UserID); err != nil {
+			return err
+		}
+		if err := tx.Model(&redemption).Update("pending", false).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OrderRepository) DeleteRedemption(ctx context.Context, orderID, couponID uint) (int64, error) {
+	result := r.db.WithContext(ctx).Where("order_id = ? AND coupon_id = ?", orderID, couponID).
+		Delete(&models.CouponRedemption{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *OrderRepository) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	return r.db.WithContext(ctx).Create(payment).Error
+}
+
+func (r *OrderRepository) UpdatePayment(ctx context.Context, payment *models.Payment) error {
+	return r.db.WithContext(ctx).Save(payment).Error
+}
+
+// UpdatePaymentByIntent locks the payment of the provider's intent, calls
+// update on it and saves the result. An error from update leaves the payment
+// unchanged.
+func (r *OrderRepository) UpdatePaymentByIntent(ctx context.Context, provider, intentID string, update func(payment *models.Payment) error) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND intent_id = ?", provider, intentID).First(&payment).Error
+		if err != nil {
+			return err
+		}
+		if err := update(&payment); err != nil {
+			return err
+		}
+		return tx.Save(&payment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *OrderRepository) FindPayment(orderID, paymentID uint) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.Where("order_id = ?", orderID).First(&payment, paymentID).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// TransitionOrder moves the order to status if it is in one of from, and
+// reports whether it did. Orders in any other status are left alone, so
+// repeated events change nothing.
+func (r *OrderRepository) TransitionOrder(ctx context.Context, orderID uint, status models.OrderStatus, from ...models.OrderStatus) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Order{}).Where("id = ? AND status IN ?", orderID, from).
+		Update("status", status)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteOrder deletes the order if check accepts it, with its coupon
+// redemptions.
+func (r *OrderRepository) DeleteOrder(ctx context.Context, orderID uint, check func(order *models.Order) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+			return err
+		}
+		if err := check(&order); err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", order.ID).Delete(&models.CouponRedemption{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&order).Error
+	})
+}
+
+// UpdateCartEmail sets the email of an order that is still a cart.
+func (r *OrderRepository) UpdateCartEmail(ctx context.Context, orderID uint, email string) error {
+	return r.db.WithContext(ctx).Model(&models.Order{}).Where("id = ? AND status = ?", orderID, models.Cart).
+		Update("email", email).Error
+}
+
+// FindGuestOrder returns the placed order with the number and email that no
+// account has claimed yet.
+func (r *OrderRepository) FindGuestOrder(number, email string) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Where("user_id IS NULL AND number = ? AND lower(email) = lower(?)", number, email).First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ClaimGuestOrders moves the guest orders matching the conditions, and the
+// coupons they used, to the user's account. It returns how many orders it
+// moved.
+func (r *OrderRepository) ClaimGuestOrders(ctx context.Context, userID uint, query any, args ...any) (int, error) {
+	var claimed []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Order{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id IS NULL").Where(query, args...).Pluck("id", &claimed).Error
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+		err = tx.Model(&models.Order{}).Where("id IN ?", claimed).
+			Updates(map[string]any{"user_id": userID, "guest_id": nil}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.CouponRedemption{}).Where("order_id IN ?", claimed).
+			Update("user_id", userID).Error
+	})
+	return len(claimed), err
+}
+
+// FindPaymentsByStatus returns the order's payments in any of the statuses.
+func (r *OrderRepository) FindPaymentsByStatus(orderID uint, statuses ...models.PaymentStatus) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Where("order_id = ? AND status IN ?", orderID, statuses).Order("id").Find(&payments).Error
+	return payments, err
+}
+
+// CommitCheckout takes the stock for an order whose payment was authorized,
+// splits it into one shop order per shop, numbers it and moves it to
+// pending. It fails with ErrOrderChanged if the order left the cart or its
+// total no longer matches the payment, and with ErrInsufficientStock if an
+// item sold out; nothing is committed then.
+func (r *OrderRepository) CommitCheckout(ctx context.Context, payment *models.Payment) ([]models.InventoryMovement, error) {
+	var movements []models.InventoryMovement
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems").
+			Preload("Discounts").Preload("ShippingLines").First(&order, payment.OrderID).Error
+		if err != nil {
+			return err
+		}
+		if order.Status != string(models.Cart) {
+			// Checkout voids earlier attempts, so the latest payment is the
+			// one that placed the order.
+			var latest models.Payment
+			if err := tx.Where("order_id = ?", order.ID).Order("id DESC").First(&latest).Error; err != nil {
+				return err
+			}
+			if latest.ID == payment.ID && order.Status != string(models.Cancelled) {
+				return ErrOrderPlaced
+			}
+			return ErrOrderChanged
+		}
+		if models.RoundMoney(order.TotalAmount) != models.RoundMoney(payment.Amount) {
+			return ErrOrderChanged
+		}
+		productIDs := make([]uint, len(order.OrderItems))
+		for i, item := range order.OrderItems {
+			productIDs[i] = item.ProductID
+		}
+		products, err := FindProductsByID(tx, productIDs)
+		if err != nil {
+			return err
+		}
+		if err := CheckPurchasable(order.OrderItems, products); err != nil {
+			return err
+		}
+		if err := redeemCoupons(tx, &order); err != nil {
+			return err
+		}
+
+		for _, item := range order.OrderItems {
+			movement := models.InventoryMovement{
+				ProductID: item.ProductID,
+				Quantity:  -item.Quantity,
+				Reason:    string(models.InventorySale),
+				OrderID:   &order.ID,
+			}
+			if err := RecordInventoryMovement(tx, &movement); err != nil {
+				return err
+			}
+			movements = append(movements, movement)
+		}
+		if err := createShopOrders(tx, &order); err != nil {
+			return err
+		}
+		number, err := orderNumber(tx, time.Now())
+		if err != nil {
+			return err
+		}
+		return tx.Model(&order).Updates(map[string]any{"status": models.Pending, "number": number}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// orderNumberAlphabet leaves out letters that are easily mistaken for digits.
+const orderNumberAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// orderNumber gives an order its number, e.g. ORD-2026-000042-K7QM: the year,
+// the order's place in the year's series and a random suffix, so that one
+// customer's order number does not lead to another's.
+func orderNumber(tx *gorm.DB, now time.Time) (string, error) {
+	sequence, err := NextSequence(tx, fmt.Sprintf("order:%d", now.Year()))
+	if err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	for i, b := range suffix {
+		suffix[i] = orderNumberAlphabet[int(b)%len(orderNumberAlphabet)]
+	}
+	return fmt.Sprintf("ORD-%d-%06d-%s", now.Year(), sequence, suffix), nil
+}
+
+// createShopOrders saves the order's split by shop and links each item to
+// its shop order.
+func createShopOrders(tx *gorm.DB, order *models.Order) error {
+	productIDs := make([]uint, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		productIDs[i] = item.ProductID
+	}
+	products, err := FindProductsByID(tx, productIDs)
+	if err != nil {
+		return err
+	}
+	shopOf := make(map[uint]uint, len(products))
+	for _, product := range products {
+		shopOf[product.ID] = product.ShopID
+	}
+
+	for _, shopOrder := range order.SplitByShop(shopOf) {
+		if err := tx.Omit(clause.Associations).Create(&shopOrder).Error; err != nil {
+			return err
+		}
+		itemIDs := make([]uint, len(shopOrder.Items))
+		for i, item := range shopOrder.Items {
+			itemIDs[i] = item.ID
+		}
+		err := tx.Model(&models.OrderItem{}).Where("id IN ?", itemIDs).
+			Update("shop_order_id", shopOrder.ID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentEventRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentEventRepository(db *gorm.DB) *PaymentEventRepository {
+	return &PaymentEventRepository{db: db}
+}
+
+// SaveEvent stores event unless the provider already delivered it, in which
+// case event is replaced by the stored copy and created is false.
+func (r *PaymentEventRepository) SaveEvent(ctx context.Context, event *models.PaymentEvent) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+	err := r.db.Where("provider = ? AND event_id = ?", event.Provider, event.EventID).First(event).Error
+	return false, err
+}
+
+// ClaimEvent moves a received, failed or ignored event to processing, so that only
+// one delivery or replay applies it. A claim made before staleBefore is
+// taken over, as whoever made it is presumed to have died. If the event
+// cannot be claimed it is reloaded and false is returned.
+func (r *PaymentEventRepository) ClaimEvent(ctx context.Context, event *models.PaymentEvent, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.PaymentEvent{}).
+		Where("id = ?", event.ID).
+		Where("status IN ? OR (status = ? AND updated_at < ?)",
+			[]models.PaymentEventStatus{models.PaymentEventReceived, models.PaymentEventFailed, models.PaymentEventIgnored},
+			models.PaymentEventProcessing, staleBefore).
+		Updates(map[string]any{"status": models.PaymentEventProcessing, "attempts": gorm.Expr("attempts + 1")})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, r.db.WithContext(ctx).First(event, event.ID).Error
+	}
+	event.Status = string(models.PaymentEventProcessing)
+	event.Attempts++
+	return true, nil
+}
+
+func (r *PaymentEventRepository) UpdateEvent(ctx context.Context, event *models.PaymentEvent) error {
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+func (r *PaymentEventRepository) FindEvent(eventID uint) (*models.PaymentEvent, error) {
+	var event models.PaymentEvent
+	if err := r.db.First(&event, eventID).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FindEvents returns events, newest first, optionally only those in status.
+func (r *PaymentEventRepository) FindEvents(status string, limit, offset int) ([]models.PaymentEvent, int64, error) {
+	query := r.db.Model(&models.PaymentEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var events []models.PaymentEvent
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, total, err
+}
+
+// FindFailedEvents returns every failed event, oldest first, so replays
+// reach the order service in the order the provider sent them.
+func (r *PaymentEventRepository) FindFailedEvents() ([]models.PaymentEvent, error) {
+	var events []models.PaymentEvent
+	err := r.db.Where("status = ?", models.PaymentEventFailed).Order("id").Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+)
+
+// FindActivePriceRules returns the rules whose sale window includes now,
+// optionally limited to the given types.
+func FindActivePriceRules(tx *gorm.DB, now time.Time, types ...models.PriceRuleType) ([]models.PriceRule, error) {
+	query := tx.Where("active = ?", true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now)
+	if len(types) > 0 {
+		query = query.Where("type IN ?", types)
+	}
+
+	var rules []models.PriceRule
+	err := query.Order("id").Find(&rules).Error
+	return rules, err
+}
@@ -0,0 +1,284 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+)
+
+type PrivacyRepository struct {
+	db *gorm.DB
+}
+
+func NewPrivacyRepository(db *gorm.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+// LoadUserData gathers every record that belongs to the user.
+func (r *PrivacyRepository) LoadUserData(userID uint) (*models.UserExport, error) {
+	var user models.User
+	if err := r.db.Preload("Shop").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	if err := r.db.Preload("OrderItems").Where("user_id = ?", userID).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	var addresses []models.Address
+	if err := r.db.Where("user_id = ?", userID).Find(&addresses).Error; err != nil {
+		return nil, err
+	}
+	var reviews []models.Review
+	if err := r.db.Where("user_id = ?", userID).Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	var applications []models.ShopApplication
+	if err := r.db.Where("user_id = ?", userID).Find(&applications).Error; err != nil {
+		return nil, err
+	}
+	var keys []models.APIKey
+	if err := r.db.Where("user_id = ?", userID).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	var subscriptions []models.StockSubscription
+	if err := r.db.Where("user_id = ?", userID).Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	export := &models.UserExport{
+		GeneratedAt: time.Now(),
+		Profile: models.ExportProfile{
+			ID:              user.ID,
+			Email:           user.Email,
+			FirstName:       user.FirstName,
+			LastName:        user.LastName,
+			ProfileImageURL: user.ProfileImageURL,
+			Role:            user.Role,
+			CreatedAt:       user.CreatedAt,
+			UpdatedAt:       user.UpdatedAt,
+		},
+		ShopApplications:   make([]models.ExportShopApplication, len(applications)),
+		Addresses:          make([]models.ExportAddress, len(addresses)),
+		Orders:             make([]models.ExportOrder, len(orders)),
+		Reviews:            make([]models.ExportReview, len(reviews)),
+		APIKeys:            make([]models.ExportAPIKey, len(keys)),
+		StockSubscriptions: make([]models.ExportStockSubscription, len(subscriptions)),
+	}
+
+	if user.Shop.ID != 0 {
+		export.Shop = &models.ExportShop{
+			ID:            user.Shop.ID,
+			Name:          user.Shop.Name,
+			Description:   user.Shop.Description,
+			Policies:      user.Shop.Policies,
+			PayoutDetails: user.Shop.PayoutDetails,
+			ShopImageUrl:  user.Shop.ShopImageUrl,
+			CreatedAt:     user.Shop.CreatedAt,
+		}
+	}
+	for i, application := range applications {
+		export.ShopApplications[i] = models.ExportShopApplication{
+			ID:              application.ID,
+			Name:            application.Name,
+			Description:     application.Description,
+			Policies:        application.Policies,
+			PayoutDetails:   application.PayoutDetails,
+			Status:          application.Status,
+			RejectionReason: application.RejectionReason,
+			CreatedAt:       application.CreatedAt,
+		}
+	}
+	for i, address := range addresses {
+		export.Addresses[i] = models.ExportAddress{
+			Label:             address.Label,
+			FullName:          address.FullName,
+			Line1:             address.Line1,
+			Line2:             address.Line2,
+			City:              address.City,
+			Region:            address.Region,
+			PostalCode:        address.PostalCode,
+			Country:           address.Country,
+			Phone:             address.Phone,
+			IsDefaultShipping: address.IsDefaultShipping,
+			IsDefaultBilling:  address.IsDefaultBilling,
+			CreatedAt:         address.CreatedAt,
+		}
+	}
+	for i, order := range orders {
+		export.Orders[i] = models.ExportOrder{
+			ID:              order.ID,
+			Number:          order.Number,
+			TotalAmount:     order.TotalAmount,
+			Status:          order.Status,
+			ShippingAddress: order.ShippingAddress,
+			BillingAddress:  order.Billing.Format(),
+			Items:           make([]models.ExportOrderItem, len(order.OrderItems)),
+			CreatedAt:       order.CreatedAt,
+		}
+		for j, item := range order.OrderItems {
+			export.Orders[i].Items[j] = models.ExportOrderItem{
+				ProductID: item.ProductID,
+				Quantity:  item.Quantity,
+				Price:     item.Price,
+			}
+		}
+	}
+	for i, review := range reviews {
+		export.Reviews[i] = models.ExportReview{
+			ID:        review.ID,
+			ProductID: review.ProductID,
+			Rating:    review.Rating,
+			Comment:   review.Comment,
+			CreatedAt: review.CreatedAt,
+		}
+	}
+	for i, key := range keys {
+		export.APIKeys[i] = models.ExportAPIKey{
+			Name:       key.Name,
+			Prefix:     key.Prefix,
+			Scopes:     key.Scopes,
+			LastUsedAt: key.LastUsedAt,
+			RevokedAt:  key.RevokedAt,
+			CreatedAt:  key.CreatedAt,
+		}
+	}
+
+	for i, subscription := range subscriptions {
+		export.StockSubscriptions[i] = models.ExportStockSubscription{
+			ProductID:  subscription.ProductID,
+			NotifiedAt: subscription.NotifiedAt,
+			CreatedAt:  subscription.CreatedAt,
+		}
+	}
+
+	return export, nil
+}
+
+// AnonymizeUser strips personal data from the user and everything attached to
+// them while keeping orders (amounts, items, dates) for accounting. Audit events
+// that captured the erased values are scrubbed as well.
+func (r *PrivacyRepository) AnonymizeUser(ctx context.Context, userID uint) error {
+	ctx = WithAuditRedaction(ctx)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Scrub history first so the redacted events written below survive.
+		if err := r.scrubAuditEvents(tx, userID); err != nil {
+			return err
+		}
+
+		err := tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"email":                    fmt.Sprintf("erased-%d@erased.invalid", userID),
+			"first_name":               "",
+			"last_name":                "",
+			"profile_image_url":        "",
+			"profile_image_key":        "",
+			"password":                 "",
+			"refresh_token":            "",
+			"refresh_token_expires_at": time.Time{},
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// Soft-deleted rows are scrubbed too, as they still hold the data.
+		// Country and region stay on orders for tax reporting.
+		erasedAddress := map[string]interface{}{"shipping_address": "[erased]", "email": ""}
+		for _, prefix := range []string{"shipping_", "billing_"} {
+			for _, column := range []string{"full_name", "line1", "line2", "city", "postal_code", "phone"} {
+				erasedAddress[prefix+column] = ""
+			}
+		}
+		if err := tx.Unscoped().Model(&models.Order{}).Where("user_id = ?", userID).
+			Updates(erasedAddress).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Address{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.ReturnItem{}).
+			Where("return_id IN (?)", tx.Model(&models.Return{}).Unscoped().Select("id").Where("user_id = ?", userID)).
+			Update("comment", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Return{}).Where("user_id = ?", userID).
+			Update("note", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Review{}).Where("user_id = ?", userID).
+			Update("comment", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.ShopApplication{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"payout_details": "[erased]", "policies": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Shop{}).Where("user_id = ?", userID).
+			Update("payout_details", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.StockSubscription{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.User{}, userID).Error
+	})
+}
+
+// scrubAuditEvents blanks the stored values of audit events for rows that held
+// the user's personal data. The events themselves are kept. Raw SQL is used on
+// purpose: the audit callbacks refuse regular updates to audit_events.
+func (r *PrivacyRepository) scrubAuditEvents(tx *gorm.DB, userID uint) error {
+	scrub := `UPDATE audit_events SET "before" = '', "after" = '' WHERE entity_type = ? AND entity_id IN (?)`
+
+	orders := tx.Model(&models.Order{}).Unscoped().Select("id").Where("user_id = ?", userID)
+	intents := tx.Model(&models.Payment{}).Unscoped().Select("intent_id").Where("order_id IN (?)", orders)
+	for table, ids := range map[string]interface{}{
+		"users":               []uint{userID},
+		"orders":              orders,
+		"addresses":           tx.Model(&models.Address{}).Unscoped().Select("id").Where("user_id = ?", userID),
+		"returns":             tx.Model(&models.Return{}).Unscoped().Select("id").Where("user_id = ?", userID),
+		"reviews":             tx.Model(&models.Review{}).Unscoped().Select("id").Where("user_id = ?", userID),
+		"shop_applications":   tx.Model(&models.ShopApplication{}).Unscoped().Select("id").Where("user_id = ?", userID),
+		"api_keys":            tx.Model(&models.APIKey{}).Unscoped().Select("id").Where("user_id = ?", userID),
+		"shops":               tx.Model(&models.Shop{}).Unscoped().Select("id").Where("user_id = ?", userID),
+		"shop_orders":         tx.Model(&models.ShopOrder{}).Unscoped().Select("id").Where("order_id IN (?)", orders),
+		"payments":            tx.Model(&models.Payment{}).Unscoped().Select("id").Where("order_id IN (?)", orders),
+		"payment_events":      tx.Model(&models.PaymentEvent{}).Unscoped().Select("id").Where("intent_id IN (?)", intents),
+		"stock_subscriptions": tx.Model(&models.StockSubscription{}).Unscoped().Select("id").Where("user_id = ?", userID),
+	} {
+		if err := tx.Exec(scrub, table, ids).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PrivacyRepository) CreateErasureRequest(ctx context.Context, request *models.ErasureRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *PrivacyRepository) UpdateErasureRequest(ctx context.Context, request *models.ErasureRequest) error {
+	return r.db.WithContext(ctx).Save(request).Error
+}
+
+// FindScheduledErasure returns the user's open erasure request, if any.
+func (r *PrivacyRepository) FindScheduledErasure(userID uint) (*models.ErasureRequest, error) {
+	var request models.ErasureRequest
+	err := r.db.Where("user_id = ? AND status = ?", userID, models.ErasureScheduled).First(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *PrivacyRepository) FindDueErasures(now time.Time) ([]models.ErasureRequest, error) {
+	var requests []models.ErasureRequest
+	err := r.db.Where("status = ? AND scheduled_for <= ?", models.ErasureScheduled, now).Find(&requests).Error
+	return requests, err
+}
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProductRepository struct {
+	db *gorm.DB
+}
+
+func NewProductRepository(db *gorm.DB) *ProductRepository {
+	return &ProductRepository{db: db}
+}
+
+// PublishDue publishes every scheduled product whose PublishAt has passed.
+func (r *ProductRepository) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Product{}).
+		Where("status = ? AND publish_at <= ?", models.ProductScheduled, now).
+		Update("status", models.ProductPublished)
+	return result.RowsAffected, result.Error
+}
+
+func (r *ProductRepository) FindBySKU(shopID uint, sku string) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.Where("shop_id = ? AND sku = ?", shopID, sku).First(&product).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *ProductRepository) FindCategories() ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.Find(&categories).Error
+	return categories, err
+}
+
+// SaveImported creates or updates an imported product, records a ledger
+// adjustment if its stock differs from stock, and attaches any of imageURLs
+// it does not have yet, after its existing images. The adjustment, if any,
+// is returned.
+func (r *ProductRepository) SaveImported(ctx context.Context, product *models.Product, stock int, actorID uint, imageURLs []string) (*models.InventoryMovement, error) {
+	var movement *models.InventoryMovement
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Stock is left to the ledger so concurrent sales are not overwritten.
+		if err := tx.Omit("stock").Save(product).Error; err != nil {
+			return err
+		}
+		// The stock the import started from may be stale by now; the adjustment
+		// is worked out from the locked row.
+		err := tx.Model(&models.Product{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", product.ID).Select("stock").Scan(&product.Stock).Error
+		if err != nil {
+			return err
+		}
+		if stock != product.Stock {
+			movement = &models.InventoryMovement{
+				ProductID: product.ID,
+				Quantity:  stock - product.Stock,
+				Reason:    string(models.InventoryAdjustment),
+				Note:      "bulk import",
+				ActorID:   &actorID,
+			}
+			if err := RecordInventoryMovement(tx, movement); err != nil {
+				return err
+			}
+			product.Stock = movement.StockAfter
+		}
+		if len(imageURLs) == 0 {
+			return nil
+		}
+
+		var existing []models.ProductImage
+		if err := tx.Where("product_id = ?", product.ID).Find(&existing).Error; err != nil {
+			return err
+		}
+		known := make(map[string]bool, len(existing))
+		position, hasPrimary := 0, false
+		for _, image := range existing {
+			known[image.ImageURL] = true
+			position = max(position, image.Position+1)
+			hasPrimary = hasPrimary || image.IsPrimary
+		}
+
+		for _, url := range imageURLs {
+			if known[url] {
+				continue
+			}
+			known[url] = true
+			image := models.ProductImage{
+				ProductID: product.ID,
+				ImageURL:  url,
+				Position:  position,
+				IsPrimary: !hasPrimary,
+			}
+			if err := tx.Create(&image).Error; err != nil {
+				return err
+			}
+			position++
+			hasPrimary = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+// EachShopProduct calls fn with the shop's products in batches, with their
+// images in order, so large catalogs can be streamed.
+func (r *ProductRepository) EachShopProduct(ctx context.Context, shopID uint, fn func([]models.Product) error) error {
+	var batch []models.Product
+	return r.db.WithContext(ctx).
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Where("shop_id = ?", shopID).
+		FindInBatches(&batch, 200, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+func (r *ProductRepository) CreateImportJob(ctx context.Context, job *models.ProductImportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *ProductRepository) UpdateImportJob(ctx context.Context, job *models.ProductImportJob) error {
+	return r.db.WithContext(ctx).Save(job).Error
+}
+
+func (r *ProductRepository) FindImportJob(shopID, jobID uint) (*models.ProductImportJob, error) {
+	var job models.ProductImportJob
+	if err := r.db.Where("shop_id = ?", shopID).First(&job, jobID).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReturnChanged is returned when the return left the expected status while
+// it was being updated, e.g. when it is received twice at once.
+var ErrReturnChanged = errors.New("the return was changed by someone else")
+
+type ReturnRepository struct {
+	db *gorm.DB
+}
+
+func NewReturnRepository(db *gorm.DB) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+
+// CreateReturn locks the order and calls build with it and the quantity of
+// each order item already in open or finished returns, then saves the
+// return build produced. Locking keeps concurrent requests from returning
+// the same units twice.
+func (r *ReturnRepository) CreateReturn(ctx context.Context, orderID uint, build func(order *models.Order, returned map[uint]int) (*models.Return, error)) (*models.Return, error) {
+	var created *models.Return
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems").First(&order, orderID).Error
+		if err != nil {
+			return err
+		}
+
+		var rows []struct {
+			OrderItemID uint
+			Quantity    int
+		}
+		err = tx.Model(&models.ReturnItem{}).
+			Select("return_items.order_item_id, SUM(return_items.quantity) AS quantity").
+			Joins("JOIN returns ON returns.id = return_items.return_id AND returns.deleted_at IS NULL").
+			Where("returns.order_id = ? AND returns.status NOT IN ?", orderID,
+				[]models.ReturnStatus{models.ReturnRejected, models.ReturnCancelled}).
+			Group("return_items.order_item_id").Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+		returned := make(map[uint]int, len(rows))
+		for _, row := range rows {
+			returned[row.OrderItemID] = row.Quantity
+		}
+
+		if created, err = build(&order, returned); err != nil {
+			return err
+		}
+		return tx.Create(created).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *ReturnRepository) FindReturn(returnID uint) (*models.Return, error) {
+	var found models.Return
+	if err := r.db.Preload("Items").First(&found, returnID).Error; err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+// FindReturns lists returns, newest first. Unless all is set, only returns
+// the user asked for or that were sold by shopID are included.
+func (r *ReturnRepository) FindReturns(all bool, userID, shopID uint, status string) ([]models.Return, error) {
+	query := r.db.Preload("Items").Order("id DESC")
+	if !all {
+		query = query.Where("user_id = ? OR shop_id = ?", userID, shopID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var returns []models.Return
+	err := query.Find(&returns).Error
+	return returns, err
+}
+
+// UpdateReturn saves the return's status and refund details if it is still
+// in one of the from statuses, and fails with ErrReturnChanged otherwise.
+func (r *ReturnRepository) UpdateReturn(ctx context.Context, ret *models.Return, from ...models.ReturnStatus) error {
+	result := r.db.WithContext(ctx).Model(ret).Where("status IN ?", from).
+		Select("status", "rejection_reason", "refund_amount", "payment_id", "refunded_at").Updates(ret)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReturnChanged
+	}
+	return nil
+}
+
+// ReceiveReturn marks an approved return received and, if restock is set,
+// puts its items back in stock in the same transaction.
+func (r *ReturnRepository) ReceiveReturn(ctx context.Context, ret *models.Return, restock bool) ([]models.InventoryMovement, error) {
+	var movements []models.InventoryMovement
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Return{}).Where("id = ? AND status = ?", ret.ID, models.ReturnApproved).
+			Updates(map[string]interface{}{"status": models.ReturnReceived, "restocked": restock, "received_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrReturnChanged
+		}
+
+		if restock {
+			for _, item := range ret.Items {
+				movement := models.InventoryMovement{
+					ProductID: item.ProductID,
+					Quantity:  item.Quantity,
+					Reason:    string(models.InventoryReturn),
+					OrderID:   &ret.OrderID,
+				}
+				if err := RecordInventoryMovement(tx, &movement); err != nil {
+					return err
+				}
+				movements = append(movements, movement)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret.Status = string(models.ReturnReceived)
+	ret.Restocked = restock
+	ret.ReceivedAt = &now
+	return movements, nil
+}
@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrShipmentDelivered is returned when a shipment is marked delivered twice.
+var ErrShipmentDelivered = errors.New("the shipment was already delivered")
+
+type ShipmentRepository struct {
+	db *gorm.DB
+}
+
+func NewShipmentRepository(db *gorm.DB) *ShipmentRepository {
+	return &ShipmentRepository{db: db}
+}
+
+func (r *ShipmentRepository) FindShipments(shopOrderID uint) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	err := r.db.Preload("Items").Where("shop_order_id = ?", shopOrderID).Order("id").Find(&shipments).Error
+	return shipments, err
+}
+
+func (r *ShipmentRepository) FindShipment(shopOrderID, shipmentID uint) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.db.Preload("Items").Where("shop_order_id = ?", shopOrderID).First(&shipment, shipmentID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// CreateShipment locks the parent order, reloads the shop order and calls
+// build with both and the units of each order item already shipped, then
+// saves the shipment build produced. The shop order becomes shipped once
+// every unit has shipped and processing before that, and the parent order
+// follows, all in the same transaction. shopOrder is updated to match.
+func (r *ShipmentRepository) CreateShipment(ctx context.Context, shopOrder *models.ShopOrder, build func(order *models.Order, shopOrder *models.ShopOrder, shipped map[uint]int) (*models.Shipment, error)) (*models.Shipment, error) {
+	var created *models.Shipment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, shopOrder.OrderID).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Items").First(shopOrder, shopOrder.ID).Error; err != nil {
+			return err
+		}
+		shipped, err := shippedQuantities(tx, shopOrder.ID)
+		if err != nil {
+			return err
+		}
+
+		if created, err = build(&order, shopOrder, shipped); err != nil {
+			return err
+		}
+		if err := tx.Create(created).Error; err != nil {
+			return err
+		}
+
+		for _, item := range created.Items {
+			shipped[item.OrderItemID] += item.Quantity
+		}
+		complete := true
+		for _, item := range shopOrder.Items {
+			if shipped[item.ID] < item.Quantity {
+				complete = false
+			}
+		}
+		shopOrder.Carrier = created.Carrier
+		shopOrder.TrackingNumber = created.TrackingNumber
+		shopOrder.TrackingURL = created.TrackingURL
+		switch {
+		case complete:
+			shopOrder.Status = string(models.ShopOrderShipped)
+			shopOrder.ShippedAt = &created.ShippedAt
+		case shopOrder.Status == string(models.ShopOrderPending):
+			shopOrder.Status = string(models.ShopOrderProcessing)
+		}
+		err = tx.Model(shopOrder).
+			Select("status", "carrier", "tracking_number", "tracking_url", "shipped_at").Updates(shopOrder).Error
+		if err != nil {
+			return err
+		}
+		return syncOrderStatus(tx, &order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// DeliverShipment marks the shipment delivered. Once every shipment of a
+// shipped shop order is delivered, the shop order is delivered too and the
+// parent order follows.
+func (r *ShipmentRepository) DeliverShipment(ctx context.Context, shipment *models.Shipment) error {
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, shipment.OrderID).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.Shipment{}).Where("id = ? AND delivered_at IS NULL", shipment.ID).
+			Update("delivered_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrShipmentDelivered
+		}
+
+		var undelivered int64
+		err := tx.Model(&models.Shipment{}).
+			Where("shop_order_id = ? AND delivered_at IS NULL", shipment.ShopOrderID).Count(&undelivered).Error
+		if err != nil {
+			return err
+		}
+		if undelivered > 0 {
+			return nil
+		}
+		err = tx.Model(&models.ShopOrder{}).
+			Where("id = ? AND status = ?", shipment.ShopOrderID, models.ShopOrderShipped).
+			Updates(map[string]interface{}{"status": models.ShopOrderDelivered, "delivered_at": now}).Error
+		if err != nil {
+			return err
+		}
+		return syncOrderStatus(tx, &order)
+	})
+	if err != nil {
+		return err
+	}
+	shipment.DeliveredAt = &now
+	return nil
+}
+
+// shippedQuantities sums the units of each order item in the shop order's
+// shipments.
+func shippedQuantities(tx *gorm.DB, shopOrderID uint) (map[uint]int, error) {
+	var rows []struct {
+		OrderItemID uint
+		Quantity    int
+	}
+	err := tx.Model(&models.ShipmentItem{}).
+		Select("shipment_items.order_item_id, SUM(shipment_items.quantity) AS quantity").
+		Joins("JOIN shipments ON shipments.id = shipment_items.shipment_id AND shipments.deleted_at IS NULL").
+		Where("shipments.shop_order_id = ?", shopOrderID).
+		Group("shipment_items.order_item_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	shipped := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		shipped[row.OrderItemID] = row.Quantity
+	}
+	return shipped, nil
+}
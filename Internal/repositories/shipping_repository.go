@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/shipping"
+	"gorm.io/gorm"
+)
+
+// ShippingRepository serves shop shipping profiles to shipping.TableProvider.
+type ShippingRepository struct {
+	db *gorm.DB
+}
+
+func NewShippingRepository(db *gorm.DB) *ShippingRepository {
+	return &ShippingRepository{db: db}
+}
+
+func (r *ShippingRepository) ShippingProfiles(ctx context.Context, shopIDs []uint) ([]shipping.Profile, error) {
+	if len(shopIDs) == 0 {
+		return nil, nil
+	}
+
+	var rows []models.ShippingProfile
+	err := r.db.WithContext(ctx).Where("shop_id IN ? AND active = ?", shopIDs, true).Order("id").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]shipping.Profile, len(rows))
+	for i, row := range rows {
+		profiles[i] = shipping.Profile{
+			ID:          row.ID,
+			ShopID:      row.ShopID,
+			Name:        row.Name,
+			Type:        row.Type,
+			Countries:   row.CountryList(),
+			BaseAmount:  row.BaseAmount,
+			PerKgAmount: row.PerKgAmount,
+			MinDays:     row.MinDays,
+			MaxDays:     row.MaxDays,
+		}
+		if row.FreeOver != nil {
+			profiles[i].FreeOver = *row.FreeOver
+		}
+	}
+	return profiles, nil
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrShopOrderChanged is returned when the shop order left the expected
+// status while it was being updated.
+var ErrShopOrderChanged = errors.New("the shop order was changed by someone else")
+
+type ShopOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewShopOrderRepository(db *gorm.DB) *ShopOrderRepository {
+	return &ShopOrderRepository{db: db}
+}
+
+// FindShopOrders lists the shop's orders, newest first, optionally filtered
+// by status.
+func (r *ShopOrderRepository) FindShopOrders(shopID uint, status string) ([]models.ShopOrder, error) {
+	query := r.db.Preload("Items").Preload("Order").Preload("Shipments.Items").Where("shop_id = ?", shopID).Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var shopOrders []models.ShopOrder
+	err := query.Find(&shopOrders).Error
+	return shopOrders, err
+}
+
+func (r *ShopOrderRepository) FindShopOrder(shopID, shopOrderID uint) (*models.ShopOrder, error) {
+	var shopOrder models.ShopOrder
+	err := r.db.Preload("Items").Preload("Order").Preload("Shipments.Items").Where("shop_id = ?", shopID).First(&shopOrder, shopOrderID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shopOrder, nil
+}
+
+// UpdateShopOrder locks the parent order and calls check with it, then saves
+// the shop order's status and tracking if it is still in one of the from
+// statuses. Cancelled shop orders put their items back in stock. The parent
+// order's status is brought in line with its shop orders in the same
+// transaction.
+func (r *ShopOrderRepository) UpdateShopOrder(ctx context.Context, shopOrder *models.ShopOrder, check func(order *models.Order) error, from ...models.ShopOrderStatus) ([]models.InventoryMovement, error) {
+	var movements []models.InventoryMovement
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, shopOrder.OrderID).Error; err != nil {
+			return err
+		}
+		if err := check(&order); err != nil {
+			return err
+		}
+
+		result := tx.Model(shopOrder).Where("status IN ?", from).
+			Select("status", "carrier", "tracking_number", "tracking_url", "shipped_at", "delivered_at", "cancelled_at").
+			Updates(shopOrder)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrShopOrderChanged
+		}
+
+		if shopOrder.Status == string(models.ShopOrderCancelled) {
+			for _, item := range shopOrder.Items {
+				movement := models.InventoryMovement{
+					ProductID: item.ProductID,
+					Quantity:  item.Quantity,
+					Reason:    string(models.InventoryRestock),
+					OrderID:   &order.ID,
+				}
+				if err := RecordInventoryMovement(tx, &movement); err != nil {
+					return err
+				}
+				movements = append(movements, movement)
+			}
+		}
+		return syncOrderStatus(tx, &order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// syncOrderStatus moves the order on from its shop orders: to processing
+// once one is being fulfilled, to completed when every shop order that was
+// not cancelled is delivered, and to cancelled when all were cancelled
+// before the payment was captured. Paid orders whose shop orders are all
+// cancelled wait for a refund.
+func syncOrderStatus(tx *gorm.DB, order *models.Order) error {
+	var statuses []string
+	if err := tx.Model(&models.ShopOrder{}).Where("order_id = ?", order.ID).Pluck("status", &statuses).Error; err != nil {
+		return err
+	}
+
+	counts := make(map[models.ShopOrderStatus]int)
+	for _, status := range statuses {
+		counts[models.ShopOrderStatus(status)]++
+	}
+	current := models.OrderStatus(order.Status)
+	var next models.OrderStatus
+	switch {
+	case counts[models.ShopOrderCancelled] == len(statuses):
+		if current == models.Pending || current == models.PaymentFailed {
+			next = models.Cancelled
+		}
+	case counts[models.ShopOrderDelivered]+counts[models.ShopOrderCancelled] == len(statuses):
+		if current == models.Pending || current == models.Paid || current == models.Processing {
+			next = models.Completed
+		}
+	case counts[models.ShopOrderPending]+counts[models.ShopOrderCancelled] < len(statuses):
+		if current == models.Pending || current == models.Paid {
+			next = models.Processing
+		}
+	}
+	if next == "" {
+		return nil
+	}
+	order.Status = string(next)
+	return tx.Model(order).Update("status", next).Error
+}
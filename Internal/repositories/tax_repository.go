@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/tax"
+	"gorm.io/gorm"
+)
+
+// TaxRepository serves the tax table to tax.TableCalculator.
+type TaxRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxRepository(db *gorm.DB) *TaxRepository {
+	return &TaxRepository{db: db}
+}
+
+func (r *TaxRepository) TaxRates(ctx context.Context, country string) ([]tax.Rate, error) {
+	var rows []models.TaxRate
+	if err := r.db.WithContext(ctx).Where("country = ?", country).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	rates := make([]tax.Rate, len(rows))
+	for i, row := range rows {
+		rates[i] = tax.Rate{
+			Country:  row.Country,
+			Region:   row.Region,
+			Category: row.TaxCategory,
+			Name:     row.Name,
+			Percent:  row.Percent,
+		}
+	}
+	return rates, nil
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"context"
+
 	"github.com/Archnick/go-ecommerce/Internal/models"
 	"gorm.io/gorm"
 )
@@ -13,8 +15,8 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
 func (r *UserRepository) FindByID(id uint) (*models.User, error) {
@@ -25,6 +27,15 @@ func (r *UserRepository) FindByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// FindByIDUnscoped also finds deleted users, e.g. to finish erasing one.
+func (r *UserRepository) FindByIDUnscoped(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.Unscoped().First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("email = ?", email).First(&user).Error
@@ -37,16 +48,16 @@ func (r *UserRepository) FindAll() ([]models.User, error) {
 	return users, err
 }
 
-func (r *UserRepository) UpdateWithPayload(user *models.User, payload models.UpdateUserPayload) error {
+func (r *UserRepository) UpdateWithPayload(ctx context.Context, user *models.User, payload models.UpdateUserPayload) error {
 	// GORM's Updates method will only update non-zero fields
-	return r.db.Model(user).Updates(payload).Error
+	return r.db.WithContext(ctx).Model(user).Updates(payload).Error
 }
 
-func (r *UserRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
 }
 
-func (r *UserRepository) Delete(id uint) error {
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
 	// GORM will perform a soft delete
-	return r.db.Delete(&models.User{}, id).Error
+	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
 }
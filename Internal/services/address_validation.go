@@ -0,0 +1,62 @@
+package services
+
+import (
+	"regexp"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+)
+
+// countryAddressRules describes what a valid address looks like in a
+// country. Countries without rules only need the fields every address has.
+type countryAddressRules struct {
+	// postalCode is matched against the upper-cased postal code.
+	postalCode         *regexp.Regexp
+	postalCodeOptional bool
+	regionRequired     bool
+}
+
+var addressRules = map[string]countryAddressRules{
+	"AU": {postalCode: regexp.MustCompile(`^\d{4}$`), regionRequired: true},
+	"BR": {postalCode: regexp.MustCompile(`^\d{5}-?\d{3}$`), regionRequired: true},
+	"CA": {postalCode: regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`), regionRequired: true},
+	"DE": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+	"ES": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+	"FR": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+	"GB": {postalCode: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`)},
+	"IE": {postalCode: regexp.MustCompile(`^[A-Z\d]{3} ?[A-Z\d]{4}$`), postalCodeOptional: true},
+	"IN": {postalCode: regexp.MustCompile(`^\d{6}$`), regionRequired: true},
+	"IT": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+	"JP": {postalCode: regexp.MustCompile(`^\d{3}-?\d{4}$`), regionRequired: true},
+	"NL": {postalCode: regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`)},
+	"US": {postalCode: regexp.MustCompile(`^\d{5}(-\d{4})?$`), regionRequired: true},
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9 ()\-]{6,30}$`)
+
+// ValidatePostalAddress checks an address against its country's rules and
+// returns an error message per invalid field, keyed by JSON field name, or
+// nil if the address is valid.
+func ValidatePostalAddress(address models.PostalAddress) map[string]string {
+	problems := make(map[string]string)
+
+	rules, ok := addressRules[address.Country]
+	if ok {
+		switch {
+		case address.PostalCode == "" && !rules.postalCodeOptional:
+			problems["postal_code"] = "postal_code is required for " + address.Country
+		case address.PostalCode != "" && !rules.postalCode.MatchString(address.PostalCode):
+			problems["postal_code"] = "postal_code is not valid for " + address.Country
+		}
+		if rules.regionRequired && address.Region == "" {
+			problems["region"] = "region is required for " + address.Country
+		}
+	}
+	if address.Phone != "" && !phonePattern.MatchString(address.Phone) {
+		problems["phone"] = "phone must be a valid phone number"
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+)
+
+// APIKeyPrefix marks a credential as an API key rather than a JWT.
+const APIKeyPrefix = "gek_"
+
+// lastUsedResolution limits how often a busy key writes its last-used time.
+const lastUsedResolution = time.Minute
+
+var ErrInvalidAPIKey = errors.New("invalid or expired API key")
+
+type APIKeyService struct {
+	keyRepo  *repositories.APIKeyRepository
+	userRepo *repositories.UserRepository
+}
+
+func NewAPIKeyService(keyRepo *repositories.APIKeyRepository, userRepo *repositories.UserRepository) *APIKeyService {
+	return &APIKeyService{keyRepo: keyRepo, userRepo: userRepo}
+}
+
+// CreateKey generates a new key for the user. The plain-text key is only
+// returned here; afterwards only its prefix can be shown.
+func (s *APIKeyService) CreateKey(ctx context.Context, userID uint, payload models.APIKeyPayload) (*models.APIKey, string, error) {
+	publicPart, err := randomHex(4)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+
+	prefix := APIKeyPrefix + publicPart
+	plain := prefix + "_" + secret
+
+	key := &models.APIKey{
+		UserID:  userID,
+		Name:    payload.Name,
+		Prefix:  prefix,
+		KeyHash: hashAPIKey(plain),
+		Scopes:  strings.Join(payload.Scopes, ","),
+	}
+	if payload.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, payload.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.keyRepo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+func (s *APIKeyService) ListKeys(userID uint) ([]models.APIKey, error) {
+	return s.keyRepo.FindAllForUser(userID)
+}
+
+func (s *APIKeyService) RevokeKey(ctx context.Context, userID, keyID uint) error {
+	key, err := s.keyRepo.FindForUser(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return s.keyRepo.Revoke(ctx, key, time.Now())
+}
+
+// Authenticate resolves a plain-text key to the key record and its owner.
+func (s *APIKeyService) Authenticate(plain string) (*models.APIKey, *models.User, error) {
+	separator := strings.LastIndex(plain, "_")
+	if !strings.HasPrefix(plain, APIKeyPrefix) || separator <= len(APIKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.keyRepo.FindByPrefix(plain[:separator])
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashAPIKey(plain))) != 1 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.FindByID(key.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		s.keyRepo.TouchLastUsed(key, now)
+	}
+
+	return key, user, nil
+}
+
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
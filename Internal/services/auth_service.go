@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"time"
 
 	"github.com/Archnick/go-ecommerce/Internal/models"
@@ -24,15 +25,15 @@ func (s *UserService) GetAllUsers() ([]models.User, error) {
 	return s.userRepo.FindAll()
 }
 
-func (s *UserService) UpdateUser(user *models.User, payload models.UpdateUserPayload) error {
-	return s.userRepo.UpdateWithPayload(user, payload)
+func (s *UserService) UpdateUser(ctx context.Context, user *models.User, payload models.UpdateUserPayload) error {
+	return s.userRepo.UpdateWithPayload(ctx, user, payload)
 }
 
-func (s *UserService) DeleteUser(id uint) error {
-	return s.userRepo.Delete(id)
+func (s *UserService) DeleteUser(ctx context.Context, id uint) error {
+	return s.userRepo.Delete(ctx, id)
 }
 
-func (s *UserService) RegisterUser(payload models.UserPayload) (*models.User, error) {
+func (s *UserService) RegisterUser(ctx context.Context, payload models.UserPayload) (*models.User, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
@@ -43,7 +44,7 @@ func (s *UserService) RegisterUser(payload models.UserPayload) (*models.User, er
 		Password: string(hashedPassword),
 		Role:     string(models.CustomerRole)}
 
-	err = s.userRepo.Create(user)
+	err = s.userRepo.Create(ctx, user)
 	return user, err
 }
 
@@ -60,7 +61,7 @@ func (s *UserService) AuthorizeUser(payload models.UserPayload) (*models.User, e
 	return user, err
 }
 
-func (s *UserService) SetRefreshToken(user *models.User, refreshToken string) error {
+func (s *UserService) SetRefreshToken(ctx context.Context, user *models.User, refreshToken string) error {
 	expiry := time.Now().Add(7 * 24 * time.Hour)
 	if refreshToken == "" {
 		expiry = time.Time{}
@@ -68,5 +69,11 @@ func (s *UserService) SetRefreshToken(user *models.User, refreshToken string) er
 
 	user.RefreshToken = refreshToken
 	user.RefreshTokenExpiresAt = expiry
-	return s.userRepo.Update(user)
+	return s.userRepo.Update(ctx, user)
+}
+
+func (s *UserService) SetProfileImage(ctx context.Context, user *models.User, url, key string) error {
+	user.ProfileImageURL = url
+	user.ProfileImageKey = key
+	return s.userRepo.Update(ctx, user)
 }
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/payment"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderEmpty              = errors.New("the order has no items")
+	ErrShippingAddressRequired = errors.New("the order needs a shipping address")
+	ErrEmailRequired           = errors.New("the order needs an email address")
+	ErrPaymentDeclined         = errors.New("the payment was declined")
+	ErrPaymentState            = errors.New("the payment cannot be changed in its current state")
+	ErrUnknownPaymentIntent    = errors.New("no payment was made with this intent")
+)
+
+// Checkout authorizes payment for the cart and, once it is authorized, takes
+// the stock and moves the order to pending. A payment that needs 3-D Secure
+// is returned as requires_action and finished by ConfirmPayment. If the stock
+// cannot be taken the authorization is voided, so the customer is never
+// charged for an order that was not placed. email is where the customer hears
+// about the order; it is kept on the order.
+func (s *OrderService) Checkout(ctx context.Context, order *models.Order, paymentMethod, email string) (*models.Payment, error) {
+	if order.Status != string(models.Cart) {
+		return nil, ErrOrderNotEditable
+	}
+	if len(order.OrderItems) == 0 {
+		return nil, ErrOrderEmpty
+	}
+	if order.Shipping.Country == "" {
+		return nil, ErrShippingAddressRequired
+	}
+	if email == "" {
+		return nil, ErrEmailRequired
+	}
+	if email != order.Email {
+		if err := s.orderRepo.UpdateCartEmail(ctx, order.ID, email); err != nil {
+			return nil, err
+		}
+	}
+
+	// An earlier attempt may still be waiting on the customer's bank.
+	open, err := s.orderRepo.FindPaymentsByStatus(order.ID, models.PaymentRequiresAction)
+	if err != nil {
+		return nil, err
+	}
+	for i := range open {
+		s.voidPayment(ctx, &open[i])
+	}
+
+	order, err = s.Reprice(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	intent, err := s.payments.CreateIntent(ctx, payment.IntentRequest{
+		OrderID:       order.ID,
+		Amount:        order.TotalAmount,
+		Currency:      s.currency,
+		PaymentMethod: paymentMethod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.Payment{
+		OrderID:  order.ID,
+		Provider: s.payments.Name(),
+		IntentID: intent.ID,
+		Amount:   intent.Amount,
+		Currency: s.currency,
+	}
+	applyIntent(record, intent)
+	if err := s.orderRepo.CreatePayment(ctx, record); err != nil {
+		s.voidPayment(ctx, record)
+		return nil, err
+	}
+	return s.finishCheckout(ctx, record)
+}
+
+// ConfirmPayment finishes a checkout after the customer completed the action
+// their payment required.
+func (s *OrderService) ConfirmPayment(ctx context.Context, order *models.Order, paymentID uint) (*models.Payment, error) {
+	record, err := s.orderRepo.FindPayment(order.ID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status == string(models.PaymentAuthorized) {
+		// A webhook got here first.
+		return s.finishCheckout(ctx, record)
+	}
+	if record.Status != string(models.PaymentRequiresAction) {
+		return nil, ErrPaymentState
+	}
+
+	intent, err := s.payments.ConfirmIntent(ctx, record.IntentID)
+	if err != nil {
+		return nil, err
+	}
+	applyIntent(record, intent)
+	if err := s.orderRepo.UpdatePayment(ctx, record); err != nil {
+		return nil, err
+	}
+	return s.finishCheckout(ctx, record)
+}
+
+// finishCheckout commits the order once its payment is authorized.
+func (s *OrderService) finishCheckout(ctx context.Context, record *models.Payment) (*models.Payment, error) {
+	switch models.PaymentStatus(record.Status) {
+	case models.PaymentDeclined:
+		return record, ErrPaymentDeclined
+	case models.PaymentRequiresAction:
+		return record, nil
+	}
+
+	movements, err := s.orderRepo.CommitCheckout(ctx, record)
+	if errors.Is(err, repositories.ErrOrderPlaced) {
+		return record, nil
+	}
+	if err != nil {
+		s.voidPayment(ctx, record)
+		return record, err
+	}
+	for i := range movements {
+		s.inventory.StockChanged(ctx, &movements[i])
+	}
+	return record, nil
+}
+
+// ApplyPaymentEvent records what a provider event says about a payment and
+// moves the order along: an authorization places a cart waiting on 3-D
+// Secure, a capture marks a pending order paid, a failure after the order
+// was placed marks it payment_failed, and a full refund marks it refunded.
+//
+// Providers do not guarantee delivery order, so an event older than what the
+// payment already shows, e.g. an authorization arriving after the capture,
+// is ignored.
+func (s *OrderService) ApplyPaymentEvent(ctx context.Context, event *payment.Event) error {
+	var previous models.PaymentStatus
+	record, err := s.orderRepo.UpdatePaymentByIntent(ctx, s.payments.Name(), event.Intent.ID, func(record *models.Payment) error {
+		previous = models.PaymentStatus(record.Status)
+		if intentIsBehind(record, &event.Intent) {
+			return errStalePaymentEvent
+		}
+		applyIntent(record, &event.Intent)
+		return nil
+	})
+	if errors.Is(err, errStalePaymentEvent) {
+		slog.Info("ignored stale payment event", "type", event.Type, "payment_intent", event.Intent.ID)
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUnknownPaymentIntent
+	}
+	if err != nil {
+		return err
+	}
+
+	if event.Type == payment.EventAuthorized {
+		if previous != models.PaymentRequiresAction && previous != models.PaymentAuthorized {
+			return nil
+		}
+		// A cart that changed, sold out, lost a product or whose coupon ran
+		// out meanwhile has had the payment voided; that is the expected
+		// outcome, not a failed event.
+		_, err := s.finishCheckout(ctx, record)
+		if errors.Is(err, repositories.ErrOrderChanged) || errors.Is(err, repositories.ErrInsufficientStock) ||
+			errors.Is(err, repositories.ErrProductUnavailable) || errors.Is(err, repositories.ErrCouponUsageLimit) {
+			return nil
+		}
+		return err
+	}
+	return s.advanceOrder(ctx, record, event.Type)
+}
+
+// advanceOrder moves a placed order along after its payment changed, posts
+// captures and refunds to the seller ledger and invoices captured orders.
+func (s *OrderService) advanceOrder(ctx context.Context, record *models.Payment, change payment.EventType) error {
+	switch change {
+	case payment.EventCaptured:
+		moved, err := s.orderRepo.TransitionOrder(ctx, record.OrderID, models.Paid, models.Pending, models.PaymentFailed)
+		if err != nil {
+			return err
+		}
+		if !moved {
+			// Either the capture was already applied, or the order was
+			// cancelled meanwhile and its shops sold nothing.
+			order, err := s.orderRepo.FindByID(record.OrderID)
+			if err != nil {
+				return err
+			}
+			if order.Status == string(models.Cancelled) {
+				slog.Error("payment captured on a cancelled order, refund it", "order_id", order.ID, "payment_id", record.ID)
+				return nil
+			}
+		}
+		if err := s.ledger.RecordSales(ctx, record.OrderID); err != nil {
+			return err
+		}
+		_, err = s.invoices.Issue(ctx, record.OrderID)
+		return err
+	case payment.EventFailed:
+		_, err := s.orderRepo.TransitionOrder(ctx, record.OrderID, models.PaymentFailed, models.Pending)
+		return err
+	case payment.EventRefunded:
+		if err := s.ledger.RecordRefund(ctx, record, nil); err != nil {
+			return err
+		}
+		if record.Status != string(models.PaymentRefunded) {
+			return nil
+		}
+		_, err := s.orderRepo.TransitionOrder(ctx, record.OrderID, models.Refunded,
+			models.Paid, models.Processing, models.Completed)
+		return err
+	}
+	return nil
+}
+
+// CapturePayment takes an authorized payment, in full unless amount is given.
+func (s *OrderService) CapturePayment(ctx context.Context, order *models.Order, paymentID uint, amount *float64) (*models.Payment, error) {
+	record, err := s.orderRepo.FindPayment(order.ID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != string(models.PaymentAuthorized) {
+		return nil, ErrPaymentState
+	}
+	capture := record.Amount
+	if amount != nil {
+		capture = models.RoundMoney(*amount)
+	}
+
+	intent, err := s.payments.Capture(ctx, record.IntentID, capture)
+	if err != nil {
+		return nil, err
+	}
+	applyIntent(record, intent)
+	if err := s.orderRepo.UpdatePayment(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, s.advanceOrder(ctx, record, payment.EventCaptured)
+}
+
+// VoidPayment releases an authorized payment that was not captured.
+func (s *OrderService) VoidPayment(ctx context.Context, order *models.Order, paymentID uint) (*models.Payment, error) {
+	record, err := s.orderRepo.FindPayment(order.ID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != string(models.PaymentAuthorized) {
+		return nil, ErrPaymentState
+	}
+
+	intent, err := s.payments.Void(ctx, record.IntentID)
+	if err != nil {
+		return nil, err
+	}
+	applyIntent(record, intent)
+	return record, s.orderRepo.UpdatePayment(ctx, record)
+}
+
+// RefundPayment returns money from a captured payment, by default all that
+// has not been refunded yet. The seller ledger spreads it over the order's
+// shops.
+func (s *OrderService) RefundPayment(ctx context.Context, order *models.Order, paymentID uint, amount *float64) (*models.Payment, error) {
+	return s.refundPayment(ctx, order, paymentID, amount, nil)
+}
+
+// RefundShopPayment is RefundPayment charged to a single shop in the seller
+// ledger, e.g. for a return of its items.
+func (s *OrderService) RefundShopPayment(ctx context.Context, order *models.Order, shopID, paymentID uint, amount *float64) (*models.Payment, error) {
+	return s.refundPayment(ctx, order, paymentID, amount, &shopID)
+}
+
+func (s *OrderService) refundPayment(ctx context.Context, order *models.Order, paymentID uint, amount *float64, shopID *uint) (*models.Payment, error) {
+	record, err := s.orderRepo.FindPayment(order.ID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != string(models.PaymentCaptured) {
+		return nil, ErrPaymentState
+	}
+	refund := models.RoundMoney(record.CapturedAmount - record.RefundedAmount)
+	if amount != nil {
+		refund = models.RoundMoney(*amount)
+	}
+
+	intent, err := s.payments.Refund(ctx, record.IntentID, refund)
+	if err != nil {
+		return nil, err
+	}
+	applyIntent(record, intent)
+	if err := s.orderRepo.UpdatePayment(ctx, record); err != nil {
+		return nil, err
+	}
+	if shopID != nil {
+		if err := s.ledger.RecordRefund(ctx, record, shopID); err != nil {
+			return nil, err
+		}
+	}
+	return record, s.advanceOrder(ctx, record, payment.EventRefunded)
+}
+
+// IsPaymentError reports whether err is the provider or the payment's state
+// rejecting an operation, as opposed to a failure to reach the provider.
+func IsPaymentError(err error) bool {
+	return errors.Is(err, ErrPaymentState) || errors.Is(err, payment.ErrIntentState) ||
+		errors.Is(err, payment.ErrAmount)
+}
+
+// voidPayment releases an authorization that will not be used. Failures are
+// logged: the authorization lapses at the provider eventually anyway.
+func (s *OrderService) voidPayment(ctx context.Context, record *models.Payment) {
+	intent, err := s.payments.Void(ctx, record.IntentID)
+	if err != nil {
+		slog.Error("failed to void payment", "payment_intent", record.IntentID, "error", err)
+		return
+	}
+	applyIntent(record, intent)
+	if record.ID == 0 {
+		return
+	}
+	if err := s.orderRepo.UpdatePayment(ctx, record); err != nil {
+		slog.Error("failed to record voided payment", "payment_id", record.ID, "error", err)
+	}
+}
+
+var errStalePaymentEvent = errors.New("the payment event is older than the payment")
+
+// paymentStage orders payment statuses by how far along a payment is.
+// Declines, voids and captures all end an authorization, so they share a
+// stage.
+var paymentStage = map[models.PaymentStatus]int{
+	models.PaymentRequiresAction: 0,
+	models.PaymentAuthorized:     1,
+	models.PaymentDeclined:       2,
+	models.PaymentVoided:         2,
+	models.PaymentCaptured:       2,
+	models.PaymentRefunded:       3,
+}
+
+// intentIsBehind reports whether intent is an older view of the payment than
+// the record, going by its status and then by its running totals.
+func intentIsBehind(record *models.Payment, intent *payment.Intent) bool {
+	next := *record
+	applyIntent(&next, intent)
+	current, known := paymentStage[models.PaymentStatus(record.Status)]
+	stage, nextKnown := paymentStage[models.PaymentStatus(next.Status)]
+	if !known || !nextKnown {
+		return false
+	}
+	if stage != current {
+		return stage < current
+	}
+	return models.RoundMoney(next.CapturedAmount) < models.RoundMoney(record.CapturedAmount) ||
+		models.RoundMoney(next.RefundedAmount) < models.RoundMoney(record.RefundedAmount)
+}
+
+// applyIntent copies the provider's view of the payment onto the record.
+func applyIntent(record *models.Payment, intent *payment.Intent) {
+	record.Status = string(intent.Status)
+	record.CapturedAmount = intent.CapturedAmount
+	record.RefundedAmount = intent.RefundedAmount
+	record.ActionURL = intent.ActionURL
+	record.DeclineReason = intent.DeclineReason
+	if intent.Status == payment.Captured && intent.CapturedAmount > 0 &&
+		models.RoundMoney(intent.RefundedAmount) >= models.RoundMoney(intent.CapturedAmount) {
+		record.Status = string(models.PaymentRefunded)
+	}
+}
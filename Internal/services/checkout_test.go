@@ -0,0 +1,469 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Archnick/go-ecommerce/Internal/invoice"
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/notify"
+	"github.com/Archnick/go-ecommerce/Internal/payment"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/Archnick/go-ecommerce/Internal/shipping"
+	"github.com/Archnick/go-ecommerce/Internal/tax"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type noTaxRates struct{}
+
+func (noTaxRates) TaxRates(ctx context.Context, country string) ([]tax.Rate, error) {
+	return nil, nil
+}
+
+type noShippingProfiles struct{}
+
+func (noShippingProfiles) ShippingProfiles(ctx context.Context, shopIDs []uint) ([]shipping.Profile, error) {
+	return nil, nil
+}
+
+type checkoutFixture struct {
+	db       *gorm.DB
+	orders   *OrderService
+	payments *payment.FakeProvider
+	invoices *InvoiceService
+	product  models.Product
+}
+
+// newCheckoutFixture sets up an in-memory database with a shop selling one
+// product, and an order service paying through the fake provider.
+func newCheckoutFixture(t *testing.T) *checkoutFixture {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	err = db.AutoMigrate(
+		&models.User{}, &models.Shop{}, &models.Product{},
+		&models.Order{}, &models.OrderItem{}, &models.OrderItemAdjustment{}, &models.OrderItemTax{},
+		&models.OrderDiscount{}, &models.Coupon{}, &models.CouponRedemption{}, &models.PriceRule{},
+		&models.OrderShippingLine{}, &models.ShopOrder{}, &models.Payment{},
+		&models.InventoryMovement{}, &models.StockSubscription{}, &models.DocumentSequence{},
+		&models.LedgerTransaction{}, &models.LedgerEntry{}, &models.CommissionRate{}, &models.Invoice{},
+	)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	owner := models.User{Email: "seller@example.com", Role: string(models.ShopRole)}
+	db.Create(&owner)
+	shop := models.Shop{Name: "Shop", UserID: owner.ID}
+	db.Create(&shop)
+	product := models.Product{Name: "Mug", Price: 12.5, Stock: 5, ShopID: shop.ID, CategoryID: 1,
+		Status: string(models.ProductPublished)}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	orderRepo := repositories.NewOrderRepository(db)
+	payments := payment.NewFakeProvider(payment.FakeSuccess, "secret")
+	invoices := NewInvoiceService(repositories.NewInvoiceRepository(db), invoice.Party{Name: "Marketplace"}, "INV", "EUR")
+	orders := NewOrderService(orderRepo, tax.NewTableCalculator(noTaxRates{}), false,
+		shipping.NewTableProvider(noShippingProfiles{}), payments, "EUR",
+		NewInventoryService(repositories.NewInventoryRepository(db), notify.NewLogNotifier()),
+		NewLedgerService(repositories.NewLedgerRepository(db), orderRepo, LedgerSettings{}), invoices)
+	return &checkoutFixture{db: db, orders: orders, payments: payments, invoices: invoices, product: product}
+}
+
+// cart creates a cart for quantity of the fixture's product. The stored item
+// price is deliberately wrong: pricing must take it from the product.
+func (f *checkoutFixture) cart(t *testing.T, quantity int) *models.Order {
+	t.Helper()
+	order := models.Order{
+		Status:   string(models.Cart),
+		Shipping: models.PostalAddress{FullName: "Ada", Line1: "1 Main St", City: "Berlin", Country: "DE"},
+		Email:    "ada@example.com",
+		OrderItems: []models.OrderItem{
+			{ProductID: f.product.ID, Quantity: quantity, Price: 0.01},
+		},
+	}
+	if err := f.db.Create(&order).Error; err != nil {
+		t.Fatalf("create cart: %v", err)
+	}
+	return f.load(t, order.ID)
+}
+
+func (f *checkoutFixture) load(t *testing.T, orderID uint) *models.Order {
+	t.Helper()
+	var order models.Order
+	if err := f.db.Preload("OrderItems").Preload("ShopOrders").First(&order, orderID).Error; err != nil {
+		t.Fatalf("load order: %v", err)
+	}
+	return &order
+}
+
+func (f *checkoutFixture) stock(t *testing.T) int {
+	t.Helper()
+	var product models.Product
+	if err := f.db.First(&product, f.product.ID).Error; err != nil {
+		t.Fatalf("load product: %v", err)
+	}
+	return product.Stock
+}
+
+func TestCheckoutPlacesOrder(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 2)
+
+	record, err := f.orders.Checkout(context.Background(), order, "success", order.Email)
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	if record.Status != string(models.PaymentAuthorized) || record.Amount != 25 {
+		t.Errorf("payment = %s %.2f, want authorized 25.00", record.Status, record.Amount)
+	}
+
+	placed := f.load(t, order.ID)
+	if placed.Status != string(models.Pending) || placed.Number == nil {
+		t.Errorf("order status = %s, number = %v; want pending with a number", placed.Status, placed.Number)
+	}
+	if placed.OrderItems[0].Price != 12.5 {
+		t.Errorf("item price = %.2f, want the product price 12.50", placed.OrderItems[0].Price)
+	}
+	if len(placed.ShopOrders) != 1 || placed.OrderItems[0].ShopOrderID == nil {
+		t.Errorf("got %d shop orders, want the item in one", len(placed.ShopOrders))
+	}
+	if got := f.stock(t); got != 3 {
+		t.Errorf("stock = %d, want 3", got)
+	}
+	var movements int64
+	f.db.Model(&models.InventoryMovement{}).Where("order_id = ? AND quantity = -2", order.ID).Count(&movements)
+	if movements != 1 {
+		t.Errorf("got %d sale movements, want 1", movements)
+	}
+}
+
+func TestCheckoutDeclined(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 1)
+
+	record, err := f.orders.Checkout(context.Background(), order, "decline", order.Email)
+	if !errors.Is(err, ErrPaymentDeclined) {
+		t.Fatalf("Checkout error = %v, want ErrPaymentDeclined", err)
+	}
+	if record.Status != string(models.PaymentDeclined) {
+		t.Errorf("payment status = %s, want declined", record.Status)
+	}
+	if status := f.load(t, order.ID).Status; status != string(models.Cart) {
+		t.Errorf("order status = %s, want cart", status)
+	}
+	if got := f.stock(t); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+}
+
+func TestCheckoutConfirmsActionRequired(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 1)
+
+	record, err := f.orders.Checkout(context.Background(), order, "3ds", order.Email)
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	if record.Status != string(models.PaymentRequiresAction) || record.ActionURL == "" {
+		t.Fatalf("payment = %s, want requires_action with an action URL", record.Status)
+	}
+	if status := f.load(t, order.ID).Status; status != string(models.Cart) {
+		t.Errorf("order status before confirming = %s, want cart", status)
+	}
+
+	record, err = f.orders.ConfirmPayment(context.Background(), order, record.ID)
+	if err != nil {
+		t.Fatalf("ConfirmPayment: %v", err)
+	}
+	if record.Status != string(models.PaymentAuthorized) {
+		t.Errorf("payment status = %s, want authorized", record.Status)
+	}
+	if status := f.load(t, order.ID).Status; status != string(models.Pending) {
+		t.Errorf("order status = %s, want pending", status)
+	}
+}
+
+func TestCheckoutVoidsPaymentWhenOutOfStock(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 6)
+
+	record, err := f.orders.Checkout(context.Background(), order, "success", order.Email)
+	if !errors.Is(err, repositories.ErrInsufficientStock) {
+		t.Fatalf("Checkout error = %v, want ErrInsufficientStock", err)
+	}
+	if record.Status != string(models.PaymentVoided) {
+		t.Errorf("payment status = %s, want voided", record.Status)
+	}
+	if status := f.load(t, order.ID).Status; status != string(models.Cart) {
+		t.Errorf("order status = %s, want cart", status)
+	}
+	if got := f.stock(t); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+}
+
+func TestCheckoutRejectsUnavailableProduct(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 1)
+	f.db.Model(&f.product).Update("status", models.ProductArchived)
+
+	_, err := f.orders.Checkout(context.Background(), order, "success", order.Email)
+	if !errors.Is(err, repositories.ErrProductUnavailable) {
+		t.Fatalf("Checkout error = %v, want ErrProductUnavailable", err)
+	}
+	var payments int64
+	f.db.Model(&models.Payment{}).Where("order_id = ?", order.ID).Count(&payments)
+	if payments != 0 {
+		t.Errorf("got %d payments, want none", payments)
+	}
+}
+
+func TestCommitCheckoutRejectsUnavailableProduct(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 1)
+
+	record, err := f.orders.Checkout(context.Background(), order, "3ds", order.Email)
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	// The product is withdrawn while the customer is at their bank.
+	f.db.Delete(&f.product)
+
+	record, err = f.orders.ConfirmPayment(context.Background(), order, record.ID)
+	if !errors.Is(err, repositories.ErrProductUnavailable) {
+		t.Fatalf("ConfirmPayment error = %v, want ErrProductUnavailable", err)
+	}
+	if record.Status != string(models.PaymentVoided) {
+		t.Errorf("payment status = %s, want voided", record.Status)
+	}
+	if status := f.load(t, order.ID).Status; status != string(models.Cart) {
+		t.Errorf("order status = %s, want cart", status)
+	}
+}
+
+func TestCheckoutRejectsInvalidOrders(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	empty := f.cart(t, 1)
+	f.db.Where("order_id = ?", empty.ID).Delete(&models.OrderItem{})
+	empty = f.load(t, empty.ID)
+	if _, err := f.orders.Checkout(context.Background(), empty, "success", empty.Email); !errors.Is(err, ErrOrderEmpty) {
+		t.Errorf("empty cart: error = %v, want ErrOrderEmpty", err)
+	}
+
+	noAddress := f.cart(t, 1)
+	noAddress.Shipping = models.PostalAddress{}
+	if _, err := f.orders.Checkout(context.Background(), noAddress, "success", noAddress.Email); !errors.Is(err, ErrShippingAddressRequired) {
+		t.Errorf("no address: error = %v, want ErrShippingAddressRequired", err)
+	}
+
+	placed := f.cart(t, 1)
+	if _, err := f.orders.Checkout(context.Background(), placed, "success", placed.Email); err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	placed = f.load(t, placed.ID)
+	if _, err := f.orders.Checkout(context.Background(), placed, "success", placed.Email); !errors.Is(err, ErrOrderNotEditable) {
+		t.Errorf("placed order: error = %v, want ErrOrderNotEditable", err)
+	}
+}
+
+func TestEditCartOnlyChangesCarts(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 1)
+
+	edited, err := f.orders.EditCart(context.Background(), order.ID, func(tx *gorm.DB, order *models.Order) error {
+		return tx.Model(&models.OrderItem{}).Where("order_id = ?", order.ID).Update("quantity", 3).Error
+	})
+	if err != nil {
+		t.Fatalf("EditCart: %v", err)
+	}
+	if edited.TotalAmount != 37.5 {
+		t.Errorf("total = %.2f, want 37.50", edited.TotalAmount)
+	}
+
+	if _, err := f.orders.Checkout(context.Background(), f.load(t, order.ID), "success", order.Email); err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	_, err = f.orders.EditCart(context.Background(), order.ID, func(tx *gorm.DB, order *models.Order) error {
+		t.Error("edit called on a placed order")
+		return nil
+	})
+	if !errors.Is(err, ErrOrderNotEditable) {
+		t.Errorf("EditCart error = %v, want ErrOrderNotEditable", err)
+	}
+	if err := f.orders.DeleteCart(context.Background(), order.ID); !errors.Is(err, ErrOrderNotEditable) {
+		t.Errorf("DeleteCart error = %v, want ErrOrderNotEditable", err)
+	}
+}
+
+func TestApplyPaymentEventIgnoresStaleEvents(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 1)
+
+	record, err := f.orders.Checkout(context.Background(), order, "3ds", order.Email)
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	authorized := payment.Intent{ID: record.IntentID, Status: payment.Authorized, Amount: record.Amount}
+	if _, err := f.orders.ConfirmPayment(context.Background(), order, record.ID); err != nil {
+		t.Fatalf("ConfirmPayment: %v", err)
+	}
+	if _, err := f.orders.VoidPayment(context.Background(), order, record.ID); err != nil {
+		t.Fatalf("VoidPayment: %v", err)
+	}
+
+	// The authorization webhook arrives after the payment was voided.
+	event := &payment.Event{ID: "evt_late", Type: payment.EventAuthorized, Intent: authorized}
+	if err := f.orders.ApplyPaymentEvent(context.Background(), event); err != nil {
+		t.Fatalf("ApplyPaymentEvent: %v", err)
+	}
+	var stored models.Payment
+	f.db.First(&stored, record.ID)
+	if stored.Status != string(models.PaymentVoided) {
+		t.Errorf("payment status = %s, want voided", stored.Status)
+	}
+}
+
+func TestApplyPaymentEventRejectsUnknownIntent(t *testing.T) {
+	f := newCheckoutFixture(t)
+	event := &payment.Event{ID: "evt_other", Type: payment.EventCaptured,
+		Intent: payment.Intent{ID: "pi_other", Status: payment.Captured, Amount: 10}}
+	if err := f.orders.ApplyPaymentEvent(context.Background(), event); !errors.Is(err, ErrUnknownPaymentIntent) {
+		t.Errorf("ApplyPaymentEvent error = %v, want ErrUnknownPaymentIntent", err)
+	}
+}
+
+func TestCouponCountsOnlyOncePlaced(t *testing.T) {
+	f := newCheckoutFixture(t)
+	limit := 1
+	coupon := models.Coupon{Code: "ONCE", Type: string(models.FixedCoupon), Value: 2,
+		Scope: string(models.StoreScope), UsageLimit: &limit, Active: true}
+	f.db.Create(&coupon)
+
+	first, second := f.cart(t, 1), f.cart(t, 1)
+	if _, err := f.orders.ApplyCoupon(context.Background(), first, "ONCE"); err != nil {
+		t.Fatalf("ApplyCoupon to first cart: %v", err)
+	}
+	// The first cart has not been placed, so the coupon is still available.
+	if _, err := f.orders.ApplyCoupon(context.Background(), second, "ONCE"); err != nil {
+		t.Fatalf("ApplyCoupon to second cart: %v", err)
+	}
+
+	if _, err := f.orders.Checkout(context.Background(), f.load(t, first.ID), "success", first.Email); err != nil {
+		t.Fatalf("Checkout first cart: %v", err)
+	}
+	record, err := f.orders.Checkout(context.Background(), f.load(t, second.ID), "success", second.Email)
+	if !errors.Is(err, repositories.ErrCouponUsageLimit) {
+		t.Fatalf("Checkout second cart error = %v, want ErrCouponUsageLimit", err)
+	}
+	if record.Status != string(models.PaymentVoided) {
+		t.Errorf("payment status = %s, want voided", record.Status)
+	}
+	if status := f.load(t, second.ID).Status; status != string(models.Cart) {
+		t.Errorf("second order status = %s, want cart", status)
+	}
+}
+
+func TestCouponDiscountsOnlyCoveredItems(t *testing.T) {
+	f := newCheckoutFixture(t)
+	other := models.Product{Name: "Plate", Price: 20, Stock: 5, ShopID: f.product.ShopID, CategoryID: 2,
+		Status: string(models.ProductPublished)}
+	f.db.Create(&other)
+	coupon := models.Coupon{Code: "MUGS", Type: string(models.FixedCoupon), Value: 10,
+		Scope: string(models.CategoryScope), ScopeID: &f.product.CategoryID, Active: true}
+	f.db.Create(&coupon)
+
+	cart := f.cart(t, 2)
+	f.db.Create(&models.OrderItem{OrderID: cart.ID, ProductID: other.ID, Quantity: 1})
+	order, err := f.orders.ApplyCoupon(context.Background(), f.load(t, cart.ID), "MUGS")
+	if err != nil {
+		t.Fatalf("ApplyCoupon: %v", err)
+	}
+
+	if order.DiscountTotal != 10 {
+		t.Errorf("discount total = %.2f, want 10.00", order.DiscountTotal)
+	}
+	for _, item := range f.load(t, cart.ID).OrderItems {
+		want := 0.0
+		if item.ProductID == f.product.ID {
+			want = 10
+		}
+		if item.CouponDiscount != want {
+			t.Errorf("product %d coupon discount = %.2f, want %.2f", item.ProductID, item.CouponDiscount, want)
+		}
+	}
+}
+
+func TestSplitByShopGivesCouponToCoveredShop(t *testing.T) {
+	f := newCheckoutFixture(t)
+	shop := models.Shop{Name: "Other shop", UserID: 1}
+	f.db.Create(&shop)
+	other := models.Product{Name: "Plate", Price: 20, Stock: 5, ShopID: shop.ID, CategoryID: 1,
+		Status: string(models.ProductPublished)}
+	f.db.Create(&other)
+	coupon := models.Coupon{Code: "SHOP", Type: string(models.PercentageCoupon), Value: 10,
+		Scope: string(models.ShopScope), ScopeID: &f.product.ShopID, Active: true}
+	f.db.Create(&coupon)
+
+	cart := f.cart(t, 2)
+	f.db.Create(&models.OrderItem{OrderID: cart.ID, ProductID: other.ID, Quantity: 1})
+	if _, err := f.orders.ApplyCoupon(context.Background(), f.load(t, cart.ID), "SHOP"); err != nil {
+		t.Fatalf("ApplyCoupon: %v", err)
+	}
+	if _, err := f.orders.Checkout(context.Background(), f.load(t, cart.ID), "success", cart.Email); err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	order := f.load(t, cart.ID)
+	if len(order.ShopOrders) != 2 {
+		t.Fatalf("shop orders = %d, want 2", len(order.ShopOrders))
+	}
+	for _, shopOrder := range order.ShopOrders {
+		want := 0.0
+		if shopOrder.ShopID == f.product.ShopID {
+			want = 2.5
+		}
+		if shopOrder.DiscountTotal != want {
+			t.Errorf("shop %d discount = %.2f, want %.2f", shopOrder.ShopID, shopOrder.DiscountTotal, want)
+		}
+	}
+}
+
+func TestInvoiceIssuedOnCapture(t *testing.T) {
+	f := newCheckoutFixture(t)
+	order := f.cart(t, 2)
+	record, err := f.orders.Checkout(context.Background(), order, "success", order.Email)
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	if _, err := f.invoices.Document(context.Background(), order.ID); !errors.Is(err, ErrInvoiceNotIssued) {
+		t.Fatalf("Document before capture error = %v, want ErrInvoiceNotIssued", err)
+	}
+	if _, err := f.orders.CapturePayment(context.Background(), f.load(t, order.ID), record.ID, nil); err != nil {
+		t.Fatalf("CapturePayment: %v", err)
+	}
+
+	doc, err := f.invoices.Document(context.Background(), order.ID)
+	if err != nil {
+		t.Fatalf("Document: %v", err)
+	}
+	if doc.Number == "" || doc.Total != 25 || doc.Seller.Name != "Marketplace" {
+		t.Errorf("invoice = %q total %.2f seller %q, want a number, 25.00 and the seller", doc.Number, doc.Total, doc.Seller.Name)
+	}
+	// Later changes to the order do not show on the issued invoice.
+	f.db.Model(&models.Order{}).Where("id = ?", order.ID).Update("total_amount", 1)
+	if again, err := f.invoices.Document(context.Background(), order.ID); err != nil || again.Total != 25 {
+		t.Errorf("reprinted total = %.2f (%v), want 25.00", again.Total, err)
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrGuestOrderNotFound = errors.New("no guest order matches this claim")
+
+// ClaimGuestOrders moves the orders of a guest session, carts included, to
+// the user's account. Holding the guest token is proof enough.
+func (s *OrderService) ClaimGuestOrders(ctx context.Context, userID uint, guestID string) (int, error) {
+	return s.orderRepo.ClaimGuestOrders(ctx, userID, "guest_id = ?", guestID)
+}
+
+// FindGuestOrder returns the placed guest order with the number and email,
+// or ErrGuestOrderNotFound.
+func (s *OrderService) FindGuestOrder(number, email string) (*models.Order, error) {
+	order, err := s.orderRepo.FindGuestOrder(NormalizeOrderNumber(number), strings.TrimSpace(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGuestOrderNotFound
+	}
+	return order, err
+}
+
+// ClaimGuestOrder moves a guest order to the user's account. The caller must
+// have proof that the user may take it, such as a claim code mailed to the
+// order's email; knowing the order number and email is not enough.
+func (s *OrderService) ClaimGuestOrder(ctx context.Context, userID, orderID uint) (int, error) {
+	claimed, err := s.orderRepo.ClaimGuestOrders(ctx, userID, "id = ?", orderID)
+	if err == nil && claimed == 0 {
+		return 0, ErrGuestOrderNotFound
+	}
+	return claimed, err
+}
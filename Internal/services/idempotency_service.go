@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("this Idempotency-Key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this Idempotency-Key is still being processed")
+	ErrIdempotencyKeyTakenOver  = errors.New("the Idempotency-Key was taken over by a retry")
+)
+
+// idempotencyLockTimeout is how long a key stays reserved by a request that
+// never finished, e.g. because the server restarted, before a retry may take
+// it over. It is well beyond the slowest guarded request, a checkout waiting
+// on the payment provider, so a request still running is never taken over.
+const idempotencyLockTimeout = 15 * time.Minute
+
+// IdempotencyTTLFromEnv reads IDEMPOTENCY_KEY_TTL_HOURS, how long responses
+// are kept for replay. It defaults to 24 hours.
+func IdempotencyTTLFromEnv() time.Duration {
+	hours, err := strconv.ParseFloat(os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"), 64)
+	if err != nil || hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours * float64(time.Hour))
+}
+
+type IdempotencyService struct {
+	repo *repositories.IdempotencyRepository
+	ttl  time.Duration
+}
+
+func NewIdempotencyService(repo *repositories.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl}
+}
+
+// Begin reserves the key of the user, or of the guest when userID is 0, for
+// a request. It returns the stored key and whether its response should be
+// replayed instead of handling the request. A key used for a different
+// request fails with ErrIdempotencyKeyReused, and one whose first request is
+// still running with ErrIdempotencyKeyInProgress.
+func (s *IdempotencyService) Begin(ctx context.Context, userID uint, guestID, name, method, path string, body []byte) (*models.IdempotencyKey, bool, error) {
+	fingerprint := RequestFingerprint(method, path, body)
+	for attempt := 0; ; attempt++ {
+		now := time.Now()
+		key := &models.IdempotencyKey{
+			UserID:      userID,
+			GuestID:     guestID,
+			Key:         name,
+			Method:      method,
+			Path:        path,
+			Fingerprint: fingerprint,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.ttl),
+		}
+		reserved, err := s.repo.Reserve(ctx, key)
+		if err != nil {
+			return nil, false, err
+		}
+		if reserved {
+			return key, false, nil
+		}
+
+		// An expired or abandoned key is released once and reserved afresh.
+		if attempt == 0 {
+			released, err := s.repo.ReleaseStale(ctx, key, now, now.Add(-idempotencyLockTimeout))
+			if err != nil {
+				return nil, false, err
+			}
+			if released {
+				continue
+			}
+		}
+		if key.Fingerprint != fingerprint {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		if key.ResponseStatus == 0 {
+			return nil, false, ErrIdempotencyKeyInProgress
+		}
+		return key, true, nil
+	}
+}
+
+// Complete stores the response to replay for the key. If a retry took the
+// key over meanwhile, its reservation is left alone and Complete fails with
+// ErrIdempotencyKeyTakenOver.
+func (s *IdempotencyService) Complete(ctx context.Context, key *models.IdempotencyKey, status int, contentType string, body []byte) error {
+	key.ResponseStatus = status
+	key.ContentType = contentType
+	key.ResponseBody = body
+	completed, err := s.repo.Complete(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !completed {
+		return ErrIdempotencyKeyTakenOver
+	}
+	return nil
+}
+
+// Release forgets the key so the request can be retried, e.g. after a
+// server error.
+func (s *IdempotencyService) Release(ctx context.Context, key *models.IdempotencyKey) error {
+	return s.repo.Release(ctx, key)
+}
+
+// RunExpiryWorker deletes expired keys every interval until ctx is done.
+func (s *IdempotencyService) RunExpiryWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if deleted, err := s.repo.DeleteExpired(ctx, time.Now()); err != nil {
+			slog.Error("idempotency key expiry failed", "error", err)
+		} else if deleted > 0 {
+			slog.Info("deleted expired idempotency keys", "count", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RequestFingerprint hashes what makes two requests the same request.
+func RequestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
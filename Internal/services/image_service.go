@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder for image.Decode
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/Archnick/go-ecommerce/Internal/storage"
+)
+
+// MaxImageUploadBytes is the largest image accepted for upload.
+const MaxImageUploadBytes = 10 << 20
+
+// maxImagePixels guards against decompression bombs with tiny file sizes. It
+// is checked before decoding; at 4 bytes a pixel the decoded image and its
+// RGBA copy for the thumbnails take up to about 135MB.
+const maxImagePixels = 4096 * 4096
+
+// ThumbnailSizes are the generated thumbnails, keyed by name, as the maximum
+// length of the longest side in pixels.
+var ThumbnailSizes = map[string]int{
+	"small":  150,
+	"medium": 400,
+	"large":  800,
+}
+
+var (
+	ErrUnsupportedImageType = errors.New("unsupported image type, use JPEG, PNG or GIF")
+	ErrImageTooLarge        = errors.New("image is too large")
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// StoredImage describes an uploaded image and its thumbnails.
+type StoredImage struct {
+	Key         string
+	URL         string
+	ContentType string
+	Width       int
+	Height      int
+	// Thumbnails maps a ThumbnailSizes name to the thumbnail's URL.
+	Thumbnails map[string]string
+}
+
+type ImageService struct {
+	store storage.BlobStore
+}
+
+func NewImageService(store storage.BlobStore) *ImageService {
+	return &ImageService{store: store}
+}
+
+// SaveImage validates an upload by sniffing its content, stores the original
+// under folder and writes a resized thumbnail for each of ThumbnailSizes.
+func (s *ImageService) SaveImage(ctx context.Context, folder string, r io.Reader) (*StoredImage, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxImageUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxImageUploadBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImageType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImageType
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImageType
+	}
+
+	name, err := randomHex(12)
+	if err != nil {
+		return nil, err
+	}
+	key := path.Join(folder, name+extension)
+
+	if err := s.store.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		return nil, err
+	}
+
+	stored := &StoredImage{
+		Key:         key,
+		URL:         s.store.URL(key),
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		Thumbnails:  make(map[string]string, len(ThumbnailSizes)),
+	}
+
+	source := toRGBA(img)
+	for sizeName, maxSide := range ThumbnailSizes {
+		thumbKey := thumbnailKey(key, sizeName)
+		thumb := resizeToFit(source, maxSide)
+
+		var buf bytes.Buffer
+		if err := encodeThumbnail(&buf, thumb, contentType); err != nil {
+			s.DeleteImage(ctx, stored.Key)
+			return nil, err
+		}
+		if err := s.store.Put(ctx, thumbKey, &buf, thumbnailContentType(contentType)); err != nil {
+			s.DeleteImage(ctx, stored.Key)
+			return nil, err
+		}
+		stored.Thumbnails[sizeName] = s.store.URL(thumbKey)
+	}
+
+	return stored, nil
+}
+
+// DeleteImage removes an image and its thumbnails. Failures are logged, since
+// a leftover file is not worth failing the caller's request over.
+func (s *ImageService) DeleteImage(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	keys := []string{key}
+	for sizeName := range ThumbnailSizes {
+		keys = append(keys, thumbnailKey(key, sizeName))
+	}
+	for _, k := range keys {
+		if err := s.store.Delete(ctx, k); err != nil {
+			slog.Error("failed to delete blob", "key", k, "error", err)
+		}
+	}
+}
+
+// Open streams a stored blob back, sniffing its content type.
+func (s *ImageService) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	blob, err := s.store.Open(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	reader := bufio.NewReader(blob)
+	head, _ := reader.Peek(512)
+	return readCloser{Reader: reader, Closer: blob}, http.DetectContentType(head), nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// thumbnailKey names a thumbnail after its original, e.g. "a1b2.jpg" becomes
+// "a1b2_small.jpg". Thumbnails of PNGs and GIFs are stored as PNG since only
+// the first GIF frame is kept.
+func thumbnailKey(key, sizeName string) string {
+	extension := path.Ext(key)
+	base := strings.TrimSuffix(key, extension)
+	if extension != ".jpg" {
+		extension = ".png"
+	}
+	return fmt.Sprintf("%s_%s%s", base, sizeName, extension)
+}
+
+func thumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func encodeThumbnail(w io.Writer, img image.Image, contentType string) error {
+	if contentType == "image/jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return png.Encode(w, img)
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// resizeToFit scales src down so its longest side is at most maxSide, using
+// an area average over the source pixels. Images that already fit are copied.
+func resizeToFit(src *image.RGBA, maxSide int) *image.RGBA {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > maxSide || srcH > maxSide {
+		if srcW >= srcH {
+			dstW, dstH = maxSide, max(1, srcH*maxSide/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*maxSide/srcH), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	origin := src.Bounds().Min
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(origin.X+x0, origin.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[offset])
+					g += uint32(src.Pix[offset+1])
+					b += uint32(src.Pix[offset+2])
+					a += uint32(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
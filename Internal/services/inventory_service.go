@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/notify"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"gorm.io/gorm"
+)
+
+var ErrProductInStock = errors.New("product is in stock")
+
+type InventoryService struct {
+	inventoryRepo *repositories.InventoryRepository
+	notifier      notify.Notifier
+}
+
+func NewInventoryService(inventoryRepo *repositories.InventoryRepository, notifier notify.Notifier) *InventoryService {
+	return &InventoryService{inventoryRepo: inventoryRepo, notifier: notifier}
+}
+
+// AdjustStock records a manual stock change made by actorID.
+func (s *InventoryService) AdjustStock(ctx context.Context, productID, actorID uint, payload models.StockAdjustmentPayload) (*models.InventoryMovement, error) {
+	movement := &models.InventoryMovement{
+		ProductID: productID,
+		Quantity:  payload.Quantity,
+		Reason:    payload.Reason,
+		Note:      payload.Note,
+		ActorID:   &actorID,
+	}
+	if err := s.inventoryRepo.RecordMovement(ctx, movement); err != nil {
+		return nil, err
+	}
+	s.StockChanged(ctx, movement)
+	return movement, nil
+}
+
+func (s *InventoryService) ListMovements(productID uint, limit, offset int) ([]models.InventoryMovement, int64, error) {
+	return s.inventoryRepo.FindMovements(productID, limit, offset)
+}
+
+// StockChanged sends the alerts a committed movement calls for: a low-stock
+// alert to the shop owner when stock falls to the product's threshold, and
+// back-in-stock notices when an out-of-stock product is restocked. Delivery
+// failures are logged, never returned, since the stock change already happened.
+func (s *InventoryService) StockChanged(ctx context.Context, movement *models.InventoryMovement) {
+	before := movement.StockAfter - movement.Quantity
+	after := movement.StockAfter
+	lowStock := movement.Quantity < 0
+	restocked := before <= 0 && after > 0
+	if !lowStock && !restocked {
+		return
+	}
+
+	product, owner, err := s.inventoryRepo.FindProductOwner(movement.ProductID)
+	if err != nil {
+		slog.Error("failed to load product for stock alerts", "product_id", movement.ProductID, "error", err)
+		return
+	}
+
+	threshold := product.LowStockThreshold
+	if lowStock && threshold > 0 && after <= threshold && before > threshold {
+		s.send(ctx, notify.Notification{
+			To:      owner.Email,
+			Subject: fmt.Sprintf("Low stock: %s", product.Name),
+			Body: fmt.Sprintf("Only %d left of %q (product %d). Your alert threshold is %d.",
+				after, product.Name, product.ID, threshold),
+		})
+	}
+
+	if restocked {
+		subscribers, err := s.inventoryRepo.ClaimPendingSubscriptions(ctx, product.ID)
+		if err != nil {
+			slog.Error("failed to load stock subscriptions", "product_id", product.ID, "error", err)
+			return
+		}
+		for _, subscriber := range subscribers {
+			s.send(ctx, notify.Notification{
+				To:      subscriber.Email,
+				Subject: fmt.Sprintf("Back in stock: %s", product.Name),
+				Body:    fmt.Sprintf("%q is available again.", product.Name),
+			})
+		}
+	}
+}
+
+// Subscribe asks to be notified once the out-of-stock product is restocked.
+// Subscribing twice is a no-op.
+func (s *InventoryService) Subscribe(ctx context.Context, product *models.Product, userID uint) (*models.StockSubscription, error) {
+	if product.Stock > 0 {
+		return nil, ErrProductInStock
+	}
+	if subscription, err := s.inventoryRepo.FindSubscription(product.ID, userID); err == nil {
+		return subscription, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	subscription := &models.StockSubscription{ProductID: product.ID, UserID: userID}
+	if err := s.inventoryRepo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Unsubscribe cancels a pending subscription. It returns
+// gorm.ErrRecordNotFound if there is none.
+func (s *InventoryService) Unsubscribe(ctx context.Context, productID, userID uint) error {
+	deleted, err := s.inventoryRepo.DeleteSubscription(ctx, productID, userID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *InventoryService) send(ctx context.Context, notification notify.Notification) {
+	if err := s.notifier.Send(ctx, notification); err != nil {
+		slog.Error("failed to send notification", "to", notification.To, "subject", notification.Subject, "error", err)
+	}
+}
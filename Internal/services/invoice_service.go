@@ -0,0 +1,263 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Archnick/go-ecommerce/Internal/invoice"
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderNotInvoiceable = errors.New("only paid orders can be invoiced")
+	ErrInvoiceNotIssued    = errors.New("the invoice is issued once the payment is captured")
+)
+
+// InvoiceService issues invoices for paid orders. An order is given its
+// invoice number when its payment is captured, and the document is stored as
+// issued so reprints never change.
+type InvoiceService struct {
+	repo     *repositories.InvoiceRepository
+	seller   invoice.Party
+	prefix   string
+	currency string
+}
+
+func NewInvoiceService(repo *repositories.InvoiceRepository, seller invoice.Party, prefix, currency string) *InvoiceService {
+	return &InvoiceService{repo: repo, seller: seller, prefix: prefix, currency: currency}
+}
+
+// Issue invoices the order unless it already has an invoice.
+func (s *InvoiceService) Issue(ctx context.Context, orderID uint) (*models.Invoice, error) {
+	issued, err := s.repo.FindInvoice(orderID)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return issued, err
+	}
+
+	// Placed orders no longer change, so the document can be put together
+	// before the number is taken.
+	doc, err := s.build(orderID, s.seller)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.IssueInvoice(ctx, orderID, s.prefix, func(order *models.Order, inv *models.Invoice) error {
+		if !invoiceable(order) {
+			return ErrOrderNotInvoiceable
+		}
+		inv.SellerName = s.seller.Name
+		inv.SellerAddress = strings.Join(s.seller.Address, "\n")
+		inv.SellerTaxID = s.seller.TaxID
+		doc.Number = inv.Number
+		doc.IssuedAt = inv.IssuedAt
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		inv.Document = string(encoded)
+		return nil
+	})
+}
+
+// Document returns the order's invoice as it was issued, or
+// ErrInvoiceNotIssued if it has none yet.
+func (s *InvoiceService) Document(ctx context.Context, orderID uint) (invoice.Document, error) {
+	issued, err := s.repo.FindInvoice(orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invoice.Document{}, ErrInvoiceNotIssued
+	}
+	if err != nil {
+		return invoice.Document{}, err
+	}
+
+	if issued.Document == "" {
+		// Invoices issued before documents were stored are put together
+		// from the order and the seller details copied at issue.
+		seller := invoice.Party{Name: issued.SellerName, TaxID: issued.SellerTaxID}
+		if issued.SellerAddress != "" {
+			seller.Address = strings.Split(issued.SellerAddress, "\n")
+		}
+		doc, err := s.build(orderID, seller)
+		doc.Number = issued.Number
+		doc.IssuedAt = issued.IssuedAt
+		return doc, err
+	}
+	var doc invoice.Document
+	err = json.Unmarshal([]byte(issued.Document), &doc)
+	return doc, err
+}
+
+// invoiceable reports whether the order has been paid for.
+func invoiceable(order *models.Order) bool {
+	switch models.OrderStatus(order.Status) {
+	case models.Paid, models.Processing, models.Completed, models.Refunded:
+		return true
+	}
+	return false
+}
+
+// build puts the order's invoice together, without its number.
+func (s *InvoiceService) build(orderID uint, seller invoice.Party) (invoice.Document, error) {
+	order, err := s.repo.FindInvoiceOrder(orderID)
+	if err != nil {
+		return invoice.Document{}, err
+	}
+	if !invoiceable(order) {
+		return invoice.Document{}, ErrOrderNotInvoiceable
+	}
+	var user *models.User
+	if order.UserID != nil {
+		if user, err = s.repo.FindUser(*order.UserID); err != nil {
+			return invoice.Document{}, err
+		}
+	}
+	products, err := s.repo.FindProductsByID(orderProductIDs(order))
+	if err != nil {
+		return invoice.Document{}, err
+	}
+	shopIDs := make([]uint, 0, len(products))
+	for _, product := range products {
+		shopIDs = append(shopIDs, product.ShopID)
+	}
+	shops, err := s.repo.FindShopsByID(shopIDs)
+	if err != nil {
+		return invoice.Document{}, err
+	}
+
+	doc := invoice.Document{
+		OrderDate:        order.CreatedAt,
+		Currency:         s.currency,
+		Seller:           seller,
+		Buyer:            invoiceBuyer(order, user),
+		Subtotal:         order.Subtotal,
+		DiscountTotal:    order.DiscountTotal,
+		ShippingTotal:    order.ShippingTotal,
+		TaxTotal:         order.TaxTotal,
+		Total:            order.TotalAmount,
+		PricesIncludeTax: order.PricesIncludeTax,
+	}
+	if order.Number != nil {
+		doc.OrderNumber = *order.Number
+	}
+	// The order was paid in the currency of its payments.
+	if len(order.Payments) > 0 {
+		doc.Currency = order.Payments[len(order.Payments)-1].Currency
+	}
+	for _, line := range order.Discounts {
+		if line.Amount == 0 {
+			continue
+		}
+		description := line.Description
+		if description == "" {
+			description = "Coupon " + line.Code
+		}
+		doc.Discounts = append(doc.Discounts, invoice.Charge{Description: description, Amount: line.Amount})
+	}
+	doc.Sections = invoiceSections(order, products, shops)
+	doc.Taxes = invoiceTaxes(order)
+	return doc, nil
+}
+
+// invoiceBuyer bills the order's billing address, or its shipping address
+// when there is no billing address. user is nil for guest orders.
+func invoiceBuyer(order *models.Order, user *models.User) invoice.Party {
+	address := order.Billing
+	if address.Line1 == "" {
+		address = order.Shipping
+	}
+	buyer := invoice.Party{Name: address.FullName, Email: order.Email}
+	if user != nil && buyer.Email == "" {
+		buyer.Email = user.Email
+	}
+	lines := strings.Split(address.Format(), "\n")
+	if address.Line1 == "" {
+		// Orders from before structured addresses only have the label text.
+		lines = strings.Split(order.ShippingAddress, "\n")
+	} else if address.FullName != "" {
+		lines = lines[1:]
+	}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			buyer.Address = append(buyer.Address, line)
+		}
+	}
+	if buyer.Name == "" && user != nil {
+		buyer.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+	if buyer.Name == "" {
+		buyer.Name = buyer.Email
+	}
+	return buyer
+}
+
+// invoiceSections groups the order's items and shipping by shop.
+func invoiceSections(order *models.Order, products map[uint]models.Product, shops map[uint]models.Shop) []invoice.Section {
+	var sections []invoice.Section
+	index := make(map[uint]int)
+	section := func(shopID uint) *invoice.Section {
+		i, ok := index[shopID]
+		if !ok {
+			i = len(sections)
+			index[shopID] = i
+			soldBy := shops[shopID].Name
+			if soldBy == "" {
+				soldBy = fmt.Sprintf("Shop #%d", shopID)
+			}
+			sections = append(sections, invoice.Section{SoldBy: soldBy})
+		}
+		return &sections[i]
+	}
+
+	for _, item := range order.OrderItems {
+		product := products[item.ProductID]
+		description := product.Name
+		if description == "" {
+			description = fmt.Sprintf("Product #%d", item.ProductID)
+		}
+		s := section(product.ShopID)
+		s.Lines = append(s.Lines, invoice.Line{
+			Description: description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.Price,
+			Discount:    item.Discount,
+			Tax:         item.Tax,
+			Amount:      models.RoundMoney(item.LineTotal()),
+		})
+	}
+	for _, line := range order.ShippingLines {
+		charge := invoice.Charge{Description: "Shipping: " + line.Name, Amount: line.Amount}
+		if line.Waived {
+			charge.Description += " (free)"
+			charge.Amount = 0
+		}
+		section(line.ShopID).Shipping = &charge
+	}
+	return sections
+}
+
+// invoiceTaxes totals each tax across the order's items.
+func invoiceTaxes(order *models.Order) []invoice.TaxLine {
+	var taxes []invoice.TaxLine
+	for _, item := range order.OrderItems {
+		for _, itemTax := range item.Taxes {
+			i := slices.IndexFunc(taxes, func(t invoice.TaxLine) bool {
+				return t.Name == itemTax.Name && t.Percent == itemTax.Percent
+			})
+			if i < 0 {
+				taxes = append(taxes, invoice.TaxLine{Name: itemTax.Name, Percent: itemTax.Percent})
+				i = len(taxes) - 1
+			}
+			taxes[i].Amount = models.RoundMoney(taxes[i].Amount + itemTax.Amount)
+		}
+	}
+	slices.SortFunc(taxes, func(a, b invoice.TaxLine) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), cmp.Compare(a.Percent, b.Percent))
+	})
+	return taxes
+}
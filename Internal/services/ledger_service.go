@@ -0,0 +1,461 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"gorm.io/gorm"
+)
+
+var ErrPayoutState = errors.New("the payout was already settled")
+
+// LedgerSettings control commission and payouts.
+type LedgerSettings struct {
+	// CommissionPercent applies where no commission rate matches.
+	CommissionPercent float64
+	// PayoutPeriod is the time between scheduled payout batches.
+	PayoutPeriod time.Duration
+	// PayoutHold is how long sales wait before they can be paid out, leaving
+	// room for refunds.
+	PayoutHold time.Duration
+	// MinimumPayout skips shops owed less.
+	MinimumPayout float64
+}
+
+// LedgerSettingsFromEnv reads COMMISSION_PERCENT (default 10),
+// PAYOUT_PERIOD_DAYS (7), PAYOUT_HOLD_DAYS (7) and PAYOUT_MINIMUM (1).
+func LedgerSettingsFromEnv() LedgerSettings {
+	number := func(name string, fallback float64) float64 {
+		value, err := strconv.ParseFloat(os.Getenv(name), 64)
+		if err != nil || value < 0 {
+			return fallback
+		}
+		return value
+	}
+	day := 24 * time.Hour
+	return LedgerSettings{
+		CommissionPercent: number("COMMISSION_PERCENT", 10),
+		PayoutPeriod:      time.Duration(number("PAYOUT_PERIOD_DAYS", 7) * float64(day)),
+		PayoutHold:        time.Duration(number("PAYOUT_HOLD_DAYS", 7) * float64(day)),
+		MinimumPayout:     number("PAYOUT_MINIMUM", 1),
+	}
+}
+
+// LedgerService keeps the double-entry ledger of what the platform owes each
+// shop: sales when payments are captured, the platform's commission, refunds
+// and payouts.
+type LedgerService struct {
+	repo      *repositories.LedgerRepository
+	orderRepo *repositories.OrderRepository
+	settings  LedgerSettings
+}
+
+func NewLedgerService(repo *repositories.LedgerRepository, orderRepo *repositories.OrderRepository, settings LedgerSettings) *LedgerService {
+	return &LedgerService{repo: repo, orderRepo: orderRepo, settings: settings}
+}
+
+// ShopBalance summarises a shop's account. Available is what the next
+// payout batch would pay.
+type ShopBalance struct {
+	Balance        float64
+	Held           float64
+	Available      float64
+	PendingPayouts float64
+}
+
+// Statement is a shop's ledger for a period.
+type Statement struct {
+	ShopID  uint
+	From    time.Time
+	To      time.Time
+	Opening float64
+	Closing float64
+	Entries []models.LedgerEntry
+}
+
+// RecordSales posts each shop's sale and the platform's commission on it
+// once the order's payment is captured. Cancelled shop orders are skipped.
+// Posting again changes nothing.
+func (s *LedgerService) RecordSales(ctx context.Context, orderID uint) error {
+	shopOrders, err := s.repo.FindOrderShopOrders(orderID)
+	if err != nil {
+		return err
+	}
+	var productIDs []uint
+	for _, shopOrder := range shopOrders {
+		for _, item := range shopOrder.Items {
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	products, err := s.orderRepo.FindProductsByID(productIDs)
+	if err != nil {
+		return err
+	}
+	rates, err := s.repo.FindCommissionRates()
+	if err != nil {
+		return err
+	}
+
+	var transactions []*models.LedgerTransaction
+	for i := range shopOrders {
+		shopOrder := &shopOrders[i]
+		if shopOrder.Status == string(models.ShopOrderCancelled) || shopOrder.Total <= 0 {
+			continue
+		}
+		transactions = append(transactions, shopTransaction(shopOrder.ShopID, models.LedgerSale,
+			fmt.Sprintf("sale:%d", shopOrder.ID), models.LedgerPlatformCash, shopOrder.Total,
+			fmt.Sprintf("Order %d", orderID), func(t *models.LedgerTransaction) {
+				t.OrderID = &shopOrder.OrderID
+				t.ShopOrderID = &shopOrder.ID
+			}))
+
+		commission := s.commission(shopOrder, products, rates)
+		if commission > 0 {
+			transactions = append(transactions, shopTransaction(shopOrder.ShopID, models.LedgerCommission,
+				fmt.Sprintf("commission:%d", shopOrder.ID), models.LedgerCommissionRevenue, -commission,
+				fmt.Sprintf("Commission on order %d", orderID), func(t *models.LedgerTransaction) {
+					t.OrderID = &shopOrder.OrderID
+					t.ShopOrderID = &shopOrder.ID
+				}))
+		}
+	}
+	return s.repo.Post(ctx, transactions)
+}
+
+// RecordRefund posts what was refunded on the payment since the last
+// posting. With a shop the refund is charged to that shop, e.g. for a
+// return; otherwise it is spread over the order's shops by what each sold.
+// The commission on the refunded share is given back.
+func (s *LedgerService) RecordRefund(ctx context.Context, record *models.Payment, shopID *uint) error {
+	posted, err := s.repo.RefundedTotal(record.ID)
+	if err != nil {
+		return err
+	}
+	refund := models.RoundMoney(record.RefundedAmount - posted)
+	if refund <= 0 {
+		return nil
+	}
+
+	postings, err := s.repo.FindOrderTransactions(record.OrderID, models.LedgerSale, models.LedgerCommission)
+	if err != nil {
+		return err
+	}
+	sales := make(map[uint]float64)
+	commissions := make(map[uint]float64)
+	var shopIDs []uint
+	var totalSales float64
+	for _, posting := range postings {
+		amount := transactionAmount(&posting)
+		if posting.Kind == string(models.LedgerCommission) {
+			commissions[posting.ShopID] += amount
+			continue
+		}
+		if shopID != nil && posting.ShopID != *shopID {
+			continue
+		}
+		if _, ok := sales[posting.ShopID]; !ok {
+			shopIDs = append(shopIDs, posting.ShopID)
+		}
+		sales[posting.ShopID] += amount
+		totalSales += amount
+	}
+	if totalSales <= 0 {
+		// Nothing was posted as sold, e.g. the capture is not recorded yet.
+		return nil
+	}
+
+	// Cents are kept in the reference so each refund on the payment is
+	// posted once, whether it arrives from the API or a webhook.
+	cents := int64(models.RoundMoney(record.RefundedAmount) * 100)
+	remaining := refund
+	var transactions []*models.LedgerTransaction
+	for i, id := range shopIDs {
+		share := models.RoundMoney(refund * sales[id] / totalSales)
+		if i == len(shopIDs)-1 {
+			share = models.RoundMoney(remaining)
+		}
+		remaining -= share
+		if share <= 0 {
+			continue
+		}
+		setPayment := func(t *models.LedgerTransaction) {
+			t.OrderID = &record.OrderID
+			t.PaymentID = &record.ID
+		}
+		transactions = append(transactions, shopTransaction(id, models.LedgerRefund,
+			fmt.Sprintf("refund:%d:%d:%d", record.ID, cents, id), models.LedgerPlatformCash, -share,
+			fmt.Sprintf("Refund on order %d", record.OrderID), setPayment))
+
+		reversal := models.RoundMoney(commissions[id] * min(share/sales[id], 1))
+		if reversal > 0 {
+			transactions = append(transactions, shopTransaction(id, models.LedgerCommissionReversal,
+				fmt.Sprintf("commission_reversal:%d:%d:%d", record.ID, cents, id), models.LedgerCommissionRevenue, reversal,
+				fmt.Sprintf("Commission returned on order %d", record.OrderID), setPayment))
+		}
+	}
+	return s.repo.Post(ctx, transactions)
+}
+
+// ShopRefundable is what is left of the shop's sale on the order after the
+// refunds charged to it so far.
+func (s *LedgerService) ShopRefundable(orderID, shopID uint) (float64, error) {
+	postings, err := s.repo.FindOrderTransactions(orderID, models.LedgerSale, models.LedgerRefund)
+	if err != nil {
+		return 0, err
+	}
+	var refundable float64
+	for _, posting := range postings {
+		if posting.ShopID != shopID {
+			continue
+		}
+		if posting.Kind == string(models.LedgerSale) {
+			refundable += transactionAmount(&posting)
+		} else {
+			refundable -= transactionAmount(&posting)
+		}
+	}
+	return max(models.RoundMoney(refundable), 0), nil
+}
+
+func (s *LedgerService) Balance(shopID uint) (*ShopBalance, error) {
+	balance, err := s.repo.ShopBalance(shopID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	held, err := s.repo.ShopHeld(shopID, time.Now().Add(-s.settings.PayoutHold))
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.repo.PendingPayouts(shopID)
+	if err != nil {
+		return nil, err
+	}
+	return &ShopBalance{
+		Balance:        balance,
+		Held:           max(held, 0),
+		Available:      max(models.RoundMoney(balance-max(held, 0)), 0),
+		PendingPayouts: pending,
+	}, nil
+}
+
+// Statement lists the shop's postings in [from, to) with the balance before
+// and after them.
+func (s *LedgerService) Statement(shopID uint, from, to time.Time) (*Statement, error) {
+	opening, err := s.repo.ShopBalance(shopID, from)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.FindShopEntries(shopID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	statement := &Statement{ShopID: shopID, From: from, To: to, Opening: opening, Closing: opening, Entries: entries}
+	for _, entry := range entries {
+		statement.Closing -= entry.Amount
+	}
+	statement.Closing = models.RoundMoney(statement.Closing)
+	return statement, nil
+}
+
+// WriteStatementCSV writes one row per posting, with amounts from the shop's
+// point of view: positive when the platform owes it more.
+func WriteStatementCSV(w io.Writer, statement *Statement) error {
+	csvWriter := csv.NewWriter(w)
+	money := func(amount float64) string {
+		return strconv.FormatFloat(models.RoundMoney(amount), 'f', 2, 64)
+	}
+	id := func(id *uint) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*id), 10)
+	}
+
+	rows := [][]string{
+		{"date", "type", "reference", "description", "order_id", "payout_id", "amount", "balance"},
+		{statement.From.Format(time.RFC3339), "opening_balance", "", "", "", "", "", money(statement.Opening)},
+	}
+	balance := statement.Opening
+	for _, entry := range statement.Entries {
+		balance -= entry.Amount
+		rows = append(rows, []string{
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.Transaction.Kind,
+			entry.Transaction.Reference,
+			entry.Transaction.Description,
+			id(entry.Transaction.OrderID),
+			id(entry.Transaction.PayoutID),
+			money(-entry.Amount),
+			money(balance),
+		})
+	}
+	rows = append(rows, []string{statement.To.Format(time.RFC3339), "closing_balance", "", "", "", "", "", money(statement.Closing)})
+	if err := csvWriter.WriteAll(rows); err != nil {
+		return err
+	}
+	return csvWriter.Error()
+}
+
+func (s *LedgerService) TrialBalance() ([]repositories.AccountBalance, error) {
+	return s.repo.TrialBalance()
+}
+
+// CreatePayoutBatch pays every shop its available balance now.
+func (s *LedgerService) CreatePayoutBatch(ctx context.Context) (*models.PayoutBatch, error) {
+	return s.repo.CreatePayoutBatch(ctx, time.Now().Add(-s.settings.PayoutHold), s.settings.MinimumPayout)
+}
+
+// CreateDueBatch creates a payout batch if the payout period has passed
+// since the last one.
+func (s *LedgerService) CreateDueBatch(ctx context.Context) (*models.PayoutBatch, error) {
+	latest, err := s.repo.LatestPayoutBatch()
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.settings.PayoutPeriod {
+		return nil, nil
+	}
+	return s.CreatePayoutBatch(ctx)
+}
+
+// RunPayoutScheduler calls CreateDueBatch every interval until ctx is done.
+func (s *LedgerService) RunPayoutScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if batch, err := s.CreateDueBatch(ctx); err != nil {
+			slog.Error("payout scheduler failed", "error", err)
+		} else if batch != nil {
+			slog.Info("created payout batch", "batch_id", batch.ID, "payouts", len(batch.Payouts), "total", batch.Total)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *LedgerService) ListPayoutBatches(limit, offset int) ([]models.PayoutBatch, int64, error) {
+	return s.repo.FindPayoutBatches(limit, offset)
+}
+
+func (s *LedgerService) GetPayoutBatch(batchID uint) (*models.PayoutBatch, error) {
+	return s.repo.FindPayoutBatch(batchID)
+}
+
+func (s *LedgerService) GetPayout(payoutID uint) (*models.Payout, error) {
+	return s.repo.FindPayout(payoutID)
+}
+
+func (s *LedgerService) ListShopPayouts(shopID uint) ([]models.Payout, error) {
+	return s.repo.FindShopPayouts(shopID)
+}
+
+// MarkPayoutPaid records that the shop received the payout.
+func (s *LedgerService) MarkPayoutPaid(ctx context.Context, payout *models.Payout) error {
+	if payout.Status != string(models.PayoutPending) {
+		return ErrPayoutState
+	}
+	now := time.Now()
+	payout.Status = string(models.PayoutPaid)
+	payout.PaidAt = &now
+	return s.settle(ctx, payout)
+}
+
+// MarkPayoutFailed records that the payout bounced; its amount goes back to
+// the shop's balance for the next batch.
+func (s *LedgerService) MarkPayoutFailed(ctx context.Context, payout *models.Payout, reason string) error {
+	if payout.Status != string(models.PayoutPending) {
+		return ErrPayoutState
+	}
+	payout.Status = string(models.PayoutFailed)
+	payout.FailureReason = reason
+	return s.settle(ctx, payout)
+}
+
+func (s *LedgerService) settle(ctx context.Context, payout *models.Payout) error {
+	err := s.repo.SettlePayout(ctx, payout)
+	if errors.Is(err, repositories.ErrPayoutChanged) {
+		return ErrPayoutState
+	}
+	return err
+}
+
+// commission is the platform's cut of the shop order's items after
+// discounts; shipping and tax are not commissioned.
+func (s *LedgerService) commission(shopOrder *models.ShopOrder, products map[uint]models.Product, rates []models.CommissionRate) float64 {
+	if shopOrder.Subtotal <= 0 {
+		return 0
+	}
+	net := shopOrder.Subtotal - shopOrder.DiscountTotal
+	var commission float64
+	for _, item := range shopOrder.Items {
+		percent := CommissionPercent(rates, shopOrder.ShopID, products[item.ProductID].CategoryID, s.settings.CommissionPercent)
+		commission += item.LineTotal() * net / shopOrder.Subtotal * percent / 100
+	}
+	return models.RoundMoney(commission)
+}
+
+// CommissionPercent picks the most specific rate for a shop and category:
+// both matching, then the shop's, then the category's, then fallback.
+func CommissionPercent(rates []models.CommissionRate, shopID, categoryID uint, fallback float64) float64 {
+	best, percent := 0, fallback
+	for _, rate := range rates {
+		if rate.ShopID != nil && *rate.ShopID != shopID || rate.CategoryID != nil && *rate.CategoryID != categoryID {
+			continue
+		}
+		score := 1
+		if rate.ShopID != nil {
+			score += 2
+		}
+		if rate.CategoryID != nil {
+			score++
+		}
+		if score > best {
+			best, percent = score, rate.Percent
+		}
+	}
+	return percent
+}
+
+// shopTransaction moves amount between the shop's payable account and
+// another account: a positive amount credits the shop, a negative one
+// debits it.
+func shopTransaction(shopID uint, kind models.LedgerKind, reference string, other models.LedgerAccount, amount float64, description string, link func(*models.LedgerTransaction)) *models.LedgerTransaction {
+	amount = models.RoundMoney(amount)
+	transaction := &models.LedgerTransaction{
+		Kind:        string(kind),
+		Reference:   reference,
+		ShopID:      shopID,
+		Description: description,
+		Entries: []models.LedgerEntry{
+			{Account: string(other), Amount: amount},
+			{Account: string(models.LedgerShopPayable), ShopID: &shopID, Amount: -amount},
+		},
+	}
+	link(transaction)
+	return transaction
+}
+
+// transactionAmount is the total debited, which equals the total credited.
+func transactionAmount(transaction *models.LedgerTransaction) float64 {
+	var amount float64
+	for _, entry := range transaction.Entries {
+		if entry.Amount > 0 {
+			amount += entry.Amount
+		}
+	}
+	return amount
+}
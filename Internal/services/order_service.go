@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/payment"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/Archnick/go-ecommerce/Internal/shipping"
+	"github.com/Archnick/go-ecommerce/Internal/tax"
+	"gorm.io/gorm"
+)
+
+// Coupon errors are worded for the customer applying the code.
+var (
+	ErrCouponNotFound       = errors.New("this coupon code does not exist")
+	ErrCouponNotActive      = errors.New("this coupon is not active")
+	ErrCouponExpired        = errors.New("this coupon has expired")
+	ErrCouponNotStarted     = errors.New("this coupon is not valid yet")
+	ErrCouponMinOrder       = errors.New("the order does not reach this coupon's minimum value")
+	ErrCouponNotApplicable  = errors.New("this coupon does not apply to any item in the order")
+	ErrCouponNotStackable   = errors.New("this coupon cannot be combined with the coupons already applied")
+	ErrCouponAlreadyApplied = errors.New("this coupon is already applied to the order")
+	ErrCouponNotApplied     = errors.New("this coupon is not applied to the order")
+)
+
+// ErrCouponAccountRequired is a coupon limited per customer used on a guest
+// order, which has no customer to count its uses against.
+var ErrCouponAccountRequired = errors.New("sign in to use this coupon")
+
+var ErrOrderNotEditable = errors.New("only orders in the cart can be changed")
+
+var (
+	ErrShippingUnavailable = errors.New("this shipping method is not available for the order")
+	ErrShippingShopTwice   = errors.New("choose only one shipping method per shop")
+)
+
+// IsCouponError reports whether err is a coupon being rejected, as opposed to
+// a failure to process it.
+func IsCouponError(err error) bool {
+	for _, couponErr := range []error{
+		ErrCouponNotFound, ErrCouponNotActive, ErrCouponExpired, ErrCouponNotStarted,
+		ErrCouponMinOrder, ErrCouponNotApplicable, ErrCouponNotStackable,
+		ErrCouponAlreadyApplied, ErrCouponNotApplied, ErrCouponAccountRequired,
+		repositories.ErrCouponUsageLimit,
+	} {
+		if errors.Is(err, couponErr) {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderService struct {
+	orderRepo        *repositories.OrderRepository
+	taxes            tax.Calculator
+	pricesIncludeTax bool
+	shipping         shipping.RateProvider
+	payments         payment.Provider
+	currency         string
+	inventory        *InventoryService
+	ledger           *LedgerService
+	invoices         *InvoiceService
+}
+
+func NewOrderService(orderRepo *repositories.OrderRepository, taxes tax.Calculator, pricesIncludeTax bool, rates shipping.RateProvider, payments payment.Provider, currency string, inventory *InventoryService, ledger *LedgerService, invoices *InvoiceService) *OrderService {
+	return &OrderService{
+		orderRepo:        orderRepo,
+		taxes:            taxes,
+		pricesIncludeTax: pricesIncludeTax,
+		shipping:         rates,
+		payments:         payments,
+		currency:         currency,
+		inventory:        inventory,
+		ledger:           ledger,
+		invoices:         invoices,
+	}
+}
+
+// Reprice recomputes the order's item prices, discount lines, shipping and
+// totals. Item prices, price rules, shipping rates and taxes are refreshed
+// while the order is still a cart and are frozen afterwards. Sale windows
+// therefore need no scheduler: a cart picks up or drops a sale the next time
+// it is repriced.
+func (s *OrderService) Reprice(ctx context.Context, orderID uint) (*models.Order, error) {
+	return s.orderRepo.Reprice(ctx, orderID, func(tx *gorm.DB, order *models.Order) error {
+		return s.price(ctx, tx, order, nil)
+	})
+}
+
+// EditCart calls edit on the locked order, e.g. to add an item, and reprices
+// it in the same transaction. Only carts can be edited.
+func (s *OrderService) EditCart(ctx context.Context, orderID uint, edit func(tx *gorm.DB, order *models.Order) error) (*models.Order, error) {
+	return s.orderRepo.EditAndReprice(ctx, orderID, func(tx *gorm.DB, order *models.Order) error {
+		if order.Status != string(models.Cart) {
+			return ErrOrderNotEditable
+		}
+		return edit(tx, order)
+	}, func(tx *gorm.DB, order *models.Order) error {
+		return s.price(ctx, tx, order, nil)
+	})
+}
+
+// DeleteCart deletes a cart. A placed order is cancelled through its shop
+// orders instead, which refunds the payment and restocks the items.
+func (s *OrderService) DeleteCart(ctx context.Context, orderID uint) error {
+	return s.orderRepo.DeleteOrder(ctx, orderID, func(order *models.Order) error {
+		if order.Status != string(models.Cart) {
+			return ErrOrderNotEditable
+		}
+		return nil
+	})
+}
+
+// price does the work of Reprice. quoteIDs, if not nil, replaces the
+// selected shipping methods.
+func (s *OrderService) price(ctx context.Context, tx *gorm.DB, order *models.Order, quoteIDs []string) error {
+	products, err := repositories.FindProductsByID(tx, orderProductIDs(order))
+	if err != nil {
+		return err
+	}
+	if order.Status == string(models.Cart) {
+		if err := repositories.CheckPurchasable(order.OrderItems, products); err != nil {
+			return err
+		}
+		rules, err := repositories.FindActivePriceRules(tx, time.Now())
+		if err != nil {
+			return err
+		}
+		for i, item := range order.OrderItems {
+			product := products[item.ProductID]
+			order.OrderItems[i].Price = product.Price
+			applyPriceRules(&order.OrderItems[i], product, rules)
+		}
+
+		if len(order.ShippingLines) > 0 || quoteIDs != nil {
+			quotes, err := s.shipping.Quote(ctx, shippingRequest(order, products))
+			if err != nil {
+				return err
+			}
+			if quoteIDs != nil {
+				if order.ShippingLines, err = selectShipping(quotes, quoteIDs); err != nil {
+					return err
+				}
+			}
+			order.ShippingLines = requoteShipping(order.ShippingLines, quotes)
+		}
+	}
+
+	coupons, err := repositories.FindAppliedCoupons(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	order.Discounts = couponDiscounts(order, products, coupons)
+	if order.Status == string(models.Cart) {
+		order.PricesIncludeTax = s.pricesIncludeTax
+		if err := s.applyTaxes(ctx, order, products); err != nil {
+			return err
+		}
+	}
+	order.RefreshPrice()
+	return nil
+}
+
+// ShippingQuotes lists the shipping methods available for the cart.
+func (s *OrderService) ShippingQuotes(ctx context.Context, order *models.Order) ([]shipping.Quote, error) {
+	if order.Status != string(models.Cart) {
+		return nil, ErrOrderNotEditable
+	}
+	order, err := s.Reprice(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	products, err := s.orderRepo.FindProductsByID(orderProductIDs(order))
+	if err != nil {
+		return nil, err
+	}
+	return s.shipping.Quote(ctx, shippingRequest(order, products))
+}
+
+// SelectShipping sets the shipping method for each shop in the cart from the
+// given quote IDs and reprices the order.
+func (s *OrderService) SelectShipping(ctx context.Context, order *models.Order, quoteIDs []string) (*models.Order, error) {
+	if order.Status != string(models.Cart) {
+		return nil, ErrOrderNotEditable
+	}
+	return s.orderRepo.Reprice(ctx, order.ID, func(tx *gorm.DB, order *models.Order) error {
+		return s.price(ctx, tx, order, quoteIDs)
+	})
+}
+
+// ApplyCoupon validates the code against the cart and, if it is accepted,
+// records the redemption and reprices the order.
+func (s *OrderService) ApplyCoupon(ctx context.Context, order *models.Order, code string) (*models.Order, error) {
+	if order.Status != string(models.Cart) {
+		return nil, ErrOrderNotEditable
+	}
+	// Check against current prices, not whatever the cart last stored.
+	order, err := s.Reprice(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	coupon, err := s.orderRepo.FindCouponByCode(NormalizeCouponCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCouponNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if err := checkCouponWindow(coupon, time.Now()); err != nil {
+		return nil, err
+	}
+	if coupon.PerUserLimit != nil && order.UserID == nil {
+		return nil, ErrCouponAccountRequired
+	}
+
+	applied, err := s.orderRepo.FindAppliedCoupons(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range applied {
+		if other.ID == coupon.ID {
+			return nil, ErrCouponAlreadyApplied
+		}
+		if !other.Stackable || !coupon.Stackable {
+			return nil, ErrCouponNotStackable
+		}
+	}
+
+	products, err := s.orderRepo.FindProductsByID(orderProductIDs(order))
+	if err != nil {
+		return nil, err
+	}
+	if order.Subtotal < coupon.MinOrderValue {
+		return nil, ErrCouponMinOrder
+	}
+	if eligibleSubtotal(order, products, coupon) <= 0 {
+		return nil, ErrCouponNotApplicable
+	}
+
+	err = s.orderRepo.RedeemCoupon(ctx, coupon, &models.CouponRedemption{
+		CouponID: coupon.ID,
+		OrderID:  order.ID,
+		UserID:   order.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Reprice(ctx, order.ID)
+}
+
+// RemoveCoupon takes the code off the cart.
+func (s *OrderService) RemoveCoupon(ctx context.Context, order *models.Order, code string) (*models.Order, error) {
+	if order.Status != string(models.Cart) {
+		return nil, ErrOrderNotEditable
+	}
+
+	coupon, err := s.orderRepo.FindCouponByCode(NormalizeCouponCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCouponNotApplied
+	} else if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.orderRepo.DeleteRedemption(ctx, order.ID, coupon.ID)
+	if err != nil {
+		return nil, err
+	}
+	if removed == 0 {
+		return nil, ErrCouponNotApplied
+	}
+	return s.Reprice(ctx, order.ID)
+}
+
+// applyTaxes taxes each item on what the customer pays for it, after its
+// share of the coupons that cover it.
+func (s *OrderService) applyTaxes(ctx context.Context, order *models.Order, products map[uint]models.Product) error {
+	items := make([]tax.Item, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		items[i] = tax.Item{
+			Category: products[item.ProductID].TaxCategory,
+			Amount:   item.NetTotal(),
+		}
+	}
+	lines, err := s.taxes.Calculate(ctx, tax.Request{
+		Address:          tax.Address{Country: order.Shipping.Country, Region: order.Shipping.Region},
+		Items:            items,
+		PricesIncludeTax: order.PricesIncludeTax,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		item.Tax = 0
+		item.Taxes = make([]models.OrderItemTax, len(lines[i]))
+		for j, line := range lines[i] {
+			item.Taxes[j] = models.OrderItemTax{Name: line.Name, Percent: line.Percent, Amount: line.Amount}
+			item.Tax += line.Amount
+		}
+		item.Tax = models.RoundMoney(item.Tax)
+	}
+	return nil
+}
+
+// shippingRequest splits the cart into one shipment per shop.
+func shippingRequest(order *models.Order, products map[uint]models.Product) shipping.Request {
+	request := shipping.Request{
+		Destination: shipping.Destination{Country: order.Shipping.Country, Region: order.Shipping.Region},
+	}
+	shipments := make(map[uint]int)
+	for _, item := range order.OrderItems {
+		product, ok := products[item.ProductID]
+		if !ok {
+			continue
+		}
+		index, ok := shipments[product.ShopID]
+		if !ok {
+			index = len(request.Shipments)
+			shipments[product.ShopID] = index
+			request.Shipments = append(request.Shipments, shipping.Shipment{ShopID: product.ShopID})
+		}
+		request.Shipments[index].Items = append(request.Shipments[index].Items, shipping.Item{
+			ProductID:   product.ID,
+			Quantity:    item.Quantity,
+			WeightGrams: product.WeightGrams,
+			LengthCm:    product.LengthCm,
+			WidthCm:     product.WidthCm,
+			HeightCm:    product.HeightCm,
+			Amount:      item.LineTotal(),
+		})
+	}
+	return request
+}
+
+// selectShipping turns the chosen quote IDs into shipping lines.
+func selectShipping(quotes []shipping.Quote, quoteIDs []string) ([]models.OrderShippingLine, error) {
+	lines := make([]models.OrderShippingLine, 0, len(quoteIDs))
+	shops := make(map[uint]bool, len(quoteIDs))
+	for _, quoteID := range quoteIDs {
+		index := slices.IndexFunc(quotes, func(quote shipping.Quote) bool { return quote.ID == quoteID })
+		if index < 0 {
+			return nil, ErrShippingUnavailable
+		}
+		quote := quotes[index]
+		if shops[quote.ShopID] {
+			return nil, ErrShippingShopTwice
+		}
+		shops[quote.ShopID] = true
+		lines = append(lines, models.OrderShippingLine{ShopID: quote.ShopID, QuoteID: quote.ID})
+	}
+	return lines, nil
+}
+
+// requoteShipping refreshes the selected lines from new quotes. Lines whose
+// method is no longer offered, e.g. after the destination changed, are
+// dropped and have to be selected again.
+func requoteShipping(lines []models.OrderShippingLine, quotes []shipping.Quote) []models.OrderShippingLine {
+	requoted := make([]models.OrderShippingLine, 0, len(lines))
+	for _, line := range lines {
+		for _, quote := range quotes {
+			if quote.ID != line.QuoteID || quote.ShopID != line.ShopID {
+				continue
+			}
+			line.Name = quote.Name
+			line.Carrier = quote.Carrier
+			line.Amount = quote.Amount
+			line.MinDays = quote.MinDays
+			line.MaxDays = quote.MaxDays
+			requoted = append(requoted, line)
+			break
+		}
+	}
+	return requoted
+}
+
+// couponDiscounts turns the applied coupons into discount lines. Coupons that
+// no longer qualify, e.g. after items were removed, keep a zero line so the
+// customer can see why they do not count. Later coupons discount what earlier
+// ones left over. Each line is spread over the items its coupon covers, in
+// the items' CouponDiscount.
+func couponDiscounts(order *models.Order, products map[uint]models.Product, coupons []models.Coupon) []models.OrderDiscount {
+	var subtotal float64
+	// left is what each item still costs after the coupons so far.
+	left := make([]float64, len(order.OrderItems))
+	for i := range order.OrderItems {
+		order.OrderItems[i].CouponDiscount = 0
+		left[i] = order.OrderItems[i].LineTotal()
+		subtotal += left[i]
+	}
+
+	now := time.Now()
+	lines := make([]models.OrderDiscount, 0, len(coupons))
+	for _, coupon := range coupons {
+		line := models.OrderDiscount{
+			CouponID:    &coupon.ID,
+			ShopID:      coupon.ShopID,
+			Code:        coupon.Code,
+			Description: coupon.Description,
+		}
+
+		var covered []int
+		var eligible, available float64
+		for i, item := range order.OrderItems {
+			product, ok := products[item.ProductID]
+			if ok && promotionCovers(coupon.ShopID, coupon.Scope, coupon.ScopeID, product) {
+				covered = append(covered, i)
+				eligible += item.LineTotal()
+				available += left[i]
+			}
+		}
+		if checkCouponWindow(&coupon, now) == nil && subtotal >= coupon.MinOrderValue && eligible > 0 {
+			switch models.CouponType(coupon.Type) {
+			case models.PercentageCoupon:
+				line.Amount = models.RoundMoney(eligible * coupon.Value / 100)
+			case models.FixedCoupon:
+				line.Amount = min(coupon.Value, eligible)
+			case models.FreeShippingCoupon:
+				line.FreeShipping = true
+			}
+			line.Amount = min(line.Amount, models.RoundMoney(available))
+			spreadDiscount(order.OrderItems, left, covered, line.Amount)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// spreadDiscount adds amount to the CouponDiscount of the covered items in
+// proportion to what they have left; the last takes what rounding leaves.
+func spreadDiscount(items []models.OrderItem, left []float64, covered []int, amount float64) {
+	var total float64
+	for _, i := range covered {
+		total += left[i]
+	}
+	if total <= 0 {
+		return
+	}
+	remaining := amount
+	for n, i := range covered {
+		share := models.RoundMoney(amount * left[i] / total)
+		if n == len(covered)-1 {
+			share = models.RoundMoney(remaining)
+		}
+		share = min(share, models.RoundMoney(left[i]))
+		items[i].CouponDiscount = models.RoundMoney(items[i].CouponDiscount + share)
+		left[i] -= share
+		remaining -= share
+	}
+}
+
+// eligibleSubtotal sums the items the coupon covers, after price rules.
+func eligibleSubtotal(order *models.Order, products map[uint]models.Product, coupon *models.Coupon) float64 {
+	var eligible float64
+	for _, item := range order.OrderItems {
+		product, ok := products[item.ProductID]
+		if ok && promotionCovers(coupon.ShopID, coupon.Scope, coupon.ScopeID, product) {
+			eligible += item.LineTotal()
+		}
+	}
+	return eligible
+}
+
+func checkCouponWindow(coupon *models.Coupon, now time.Time) error {
+	switch {
+	case !coupon.Active:
+		return ErrCouponNotActive
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return ErrCouponNotStarted
+	case coupon.EndsAt != nil && !now.Before(*coupon.EndsAt):
+		return ErrCouponExpired
+	}
+	return nil
+}
+
+func orderProductIDs(order *models.Order) []uint {
+	ids := make([]uint, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		ids[i] = item.ProductID
+	}
+	return ids
+}
+
+// NormalizeCouponCode makes codes case-insensitive by storing them upper case.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// NormalizeOrderNumber lets customers type order numbers in any case.
+func NormalizeOrderNumber(number string) string {
+	return strings.ToUpper(strings.TrimSpace(number))
+}
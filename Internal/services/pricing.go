@@ -0,0 +1,145 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+)
+
+// applyPriceRules sets the item's Discount and Adjustments from the active
+// rules covering its product. At most one price rule (sale or tiered) and one
+// buy-x-get-y rule apply to an item, whichever saves the customer the most;
+// buy-x-get-y is worked out on the already reduced unit price.
+func applyPriceRules(item *models.OrderItem, product models.Product, rules []models.PriceRule) {
+	item.Discount = 0
+	item.Adjustments = nil
+	if item.Quantity <= 0 {
+		return
+	}
+
+	var priceRule, quantityRule *models.PriceRule
+	var priceCut, quantityCut float64
+	var priceNote, quantityNote string
+
+	for i := range rules {
+		rule := &rules[i]
+		if !promotionCovers(rule.ShopID, rule.Scope, rule.ScopeID, product) {
+			continue
+		}
+		switch models.PriceRuleType(rule.Type) {
+		case models.SaleRule:
+			cut := models.RoundMoney(item.Price * rule.PercentOff / 100 * float64(item.Quantity))
+			if cut > priceCut {
+				priceRule, priceCut = rule, cut
+				priceNote = fmt.Sprintf("%s: %s%% off", rule.Name, formatPercent(rule.PercentOff))
+			}
+		case models.TieredRule:
+			tier, ok := tierFor(rule.Tiers(), item.Quantity)
+			if !ok {
+				continue
+			}
+			cut := models.RoundMoney(item.Price * tier.PercentOff / 100 * float64(item.Quantity))
+			if cut > priceCut {
+				priceRule, priceCut = rule, cut
+				priceNote = fmt.Sprintf("%s: %s%% off for %d or more", rule.Name, formatPercent(tier.PercentOff), tier.MinQuantity)
+			}
+		}
+	}
+
+	unitPrice := item.Price
+	if priceRule != nil {
+		unitPrice -= priceCut / float64(item.Quantity)
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if models.PriceRuleType(rule.Type) != models.BuyXGetYRule || rule.BuyQuantity <= 0 || rule.GetQuantity <= 0 {
+			continue
+		}
+		if !promotionCovers(rule.ShopID, rule.Scope, rule.ScopeID, product) {
+			continue
+		}
+		discounted := item.Quantity / (rule.BuyQuantity + rule.GetQuantity) * rule.GetQuantity
+		cut := models.RoundMoney(unitPrice * rule.PercentOff / 100 * float64(discounted))
+		if cut > quantityCut {
+			quantityRule, quantityCut = rule, cut
+			quantityNote = fmt.Sprintf("%s: buy %d get %d at %s%% off (%d discounted)",
+				rule.Name, rule.BuyQuantity, rule.GetQuantity, formatPercent(rule.PercentOff), discounted)
+		}
+	}
+
+	for _, applied := range []struct {
+		rule *models.PriceRule
+		cut  float64
+		note string
+	}{{priceRule, priceCut, priceNote}, {quantityRule, quantityCut, quantityNote}} {
+		if applied.rule == nil {
+			continue
+		}
+		item.Adjustments = append(item.Adjustments, models.OrderItemAdjustment{
+			PriceRuleID: &applied.rule.ID,
+			Description: applied.note,
+			Amount:      applied.cut,
+		})
+		item.Discount += applied.cut
+	}
+	item.Discount = models.RoundMoney(item.Discount)
+}
+
+// SalePrice returns the product's price under the best active sale rule, or
+// nil when no sale covers it.
+func SalePrice(product models.Product, rules []models.PriceRule) (*float64, *models.PriceRule) {
+	var best *models.PriceRule
+	for i := range rules {
+		rule := &rules[i]
+		if models.PriceRuleType(rule.Type) != models.SaleRule {
+			continue
+		}
+		if !promotionCovers(rule.ShopID, rule.Scope, rule.ScopeID, product) {
+			continue
+		}
+		if best == nil || rule.PercentOff > best.PercentOff {
+			best = rule
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	price := models.RoundMoney(product.Price * (1 - best.PercentOff/100))
+	return &price, best
+}
+
+// tierFor picks the highest tier the quantity reaches.
+func tierFor(tiers []models.PriceTier, quantity int) (models.PriceTier, bool) {
+	var best models.PriceTier
+	found := false
+	for _, tier := range tiers {
+		if quantity >= tier.MinQuantity && (!found || tier.MinQuantity > best.MinQuantity) {
+			best, found = tier, true
+		}
+	}
+	return best, found
+}
+
+// promotionCovers reports whether a coupon or price rule with the given shop
+// and scope applies to the product. Promotions created by a shop only ever
+// cover that shop's products.
+func promotionCovers(shopID *uint, scope string, scopeID *uint, product models.Product) bool {
+	if shopID != nil && product.ShopID != *shopID {
+		return false
+	}
+	switch models.CouponScope(scope) {
+	case models.StoreScope:
+		return true
+	case models.ShopScope:
+		return scopeID != nil && product.ShopID == *scopeID
+	case models.CategoryScope:
+		return scopeID != nil && product.CategoryID == *scopeID
+	case models.ProductScope:
+		return scopeID != nil && product.ID == *scopeID
+	}
+	return false
+}
+
+func formatPercent(percent float64) string {
+	return fmt.Sprintf("%g", percent)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"gorm.io/gorm"
+)
+
+// ErasureGracePeriod is how long a self-requested erasure can be cancelled.
+const ErasureGracePeriod = 30 * 24 * time.Hour
+
+var ErrErasurePending = errors.New("an erasure is already scheduled for this user")
+
+type PrivacyService struct {
+	privacyRepo *repositories.PrivacyRepository
+	userRepo    *repositories.UserRepository
+	images      *ImageService
+}
+
+func NewPrivacyService(privacyRepo *repositories.PrivacyRepository, userRepo *repositories.UserRepository, images *ImageService) *PrivacyService {
+	return &PrivacyService{privacyRepo: privacyRepo, userRepo: userRepo, images: images}
+}
+
+func (s *PrivacyService) ExportUserData(userID uint) (*models.UserExport, error) {
+	return s.privacyRepo.LoadUserData(userID)
+}
+
+// RequestErasure schedules the user's data to be erased after the grace period.
+func (s *PrivacyService) RequestErasure(ctx context.Context, userID uint) (*models.ErasureRequest, error) {
+	if _, err := s.privacyRepo.FindScheduledErasure(userID); err == nil {
+		return nil, ErrErasurePending
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	request := &models.ErasureRequest{
+		UserID:        userID,
+		RequestedByID: userID,
+		Status:        string(models.ErasureScheduled),
+		ScheduledFor:  time.Now().Add(ErasureGracePeriod),
+	}
+	if err := s.privacyRepo.CreateErasureRequest(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *PrivacyService) GetScheduledErasure(userID uint) (*models.ErasureRequest, error) {
+	return s.privacyRepo.FindScheduledErasure(userID)
+}
+
+func (s *PrivacyService) CancelErasure(ctx context.Context, userID uint) error {
+	request, err := s.privacyRepo.FindScheduledErasure(userID)
+	if err != nil {
+		return err
+	}
+	request.Status = string(models.ErasureCancelled)
+	return s.privacyRepo.UpdateErasureRequest(ctx, request)
+}
+
+// EraseNow skips the grace period. It is meant for admins acting on a verified
+// request, and closes any erasure the user scheduled themselves.
+func (s *PrivacyService) EraseNow(ctx context.Context, userID, adminID uint) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return err
+	}
+
+	request, err := s.privacyRepo.FindScheduledErasure(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		request = &models.ErasureRequest{
+			UserID:        userID,
+			RequestedByID: adminID,
+			Status:        string(models.ErasureScheduled),
+			ScheduledFor:  time.Now(),
+		}
+		err = s.privacyRepo.CreateErasureRequest(ctx, request)
+	}
+	if err != nil {
+		return err
+	}
+	return s.complete(ctx, request)
+}
+
+// ProcessDueErasures erases every user whose grace period has run out.
+func (s *PrivacyService) ProcessDueErasures(ctx context.Context) (int, error) {
+	requests, err := s.privacyRepo.FindDueErasures(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	erased := 0
+	for i := range requests {
+		if err := s.complete(ctx, &requests[i]); err != nil {
+			slog.Error("failed to erase user", "user_id", requests[i].UserID, "error", err)
+			continue
+		}
+		erased++
+	}
+	return erased, nil
+}
+
+// RunErasureWorker calls ProcessDueErasures every interval until ctx is done.
+func (s *PrivacyService) RunErasureWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if erased, err := s.ProcessDueErasures(ctx); err != nil {
+			slog.Error("erasure worker failed", "error", err)
+		} else if erased > 0 {
+			slog.Info("erased users", "count", erased)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// complete erases the user and closes the request. A run that died after the
+// user was deleted is finished by the next one, so the lookup includes
+// deleted users.
+func (s *PrivacyService) complete(ctx context.Context, request *models.ErasureRequest) error {
+	user, err := s.userRepo.FindByIDUnscoped(request.UserID)
+	if err != nil {
+		return err
+	}
+	// The image goes first: anonymizing forgets its key.
+	s.images.DeleteImage(ctx, user.ProfileImageKey)
+	if err := s.privacyRepo.AnonymizeUser(ctx, request.UserID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	request.Status = string(models.ErasureCompleted)
+	request.CompletedAt = &now
+	return s.privacyRepo.UpdateErasureRequest(ctx, request)
+}
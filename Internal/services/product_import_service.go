@@ -0,0 +1,421 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"gorm.io/gorm"
+)
+
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+// MaxImportBytes is the largest import file accepted.
+const MaxImportBytes = 20 << 20
+
+const (
+	maxImportRows = 10_000
+	// maxStoredRowErrors bounds the error report; FailedCount has the total.
+	maxStoredRowErrors = 1_000
+)
+
+var ErrUnsupportedImportFormat = errors.New("unsupported import format, use csv or jsonl")
+
+// csvColumns is the column order of CSV exports. Imports match columns by
+// header name, and only sku, name, price and category are required.
+var csvColumns = []string{"sku", "name", "description", "price", "stock", "category", "status", "image_urls"}
+
+type ProductImportService struct {
+	productRepo *repositories.ProductRepository
+	inventory   *InventoryService
+}
+
+func NewProductImportService(productRepo *repositories.ProductRepository, inventory *InventoryService) *ProductImportService {
+	return &ProductImportService{productRepo: productRepo, inventory: inventory}
+}
+
+// StartImport records a pending job and processes data in the background.
+// Products are upserted by SKU within the shop.
+func (s *ProductImportService) StartImport(ctx context.Context, shopID, userID uint, format string, data []byte) (*models.ProductImportJob, error) {
+	if format != ImportFormatCSV && format != ImportFormatJSONL {
+		return nil, ErrUnsupportedImportFormat
+	}
+
+	job := &models.ProductImportJob{
+		ShopID: shopID,
+		UserID: userID,
+		Format: format,
+		Status: string(models.ImportPending),
+	}
+	if err := s.productRepo.CreateImportJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// The worker gets its own copy so the caller can keep reading job.
+	background := *job
+	go s.runImport(repositories.DetachAuditContext(ctx), &background, data)
+	return job, nil
+}
+
+func (s *ProductImportService) GetImportJob(shopID, jobID uint) (*models.ProductImportJob, error) {
+	return s.productRepo.FindImportJob(shopID, jobID)
+}
+
+type importRow struct {
+	number int
+	record models.ProductRecord
+	err    error
+}
+
+func (s *ProductImportService) runImport(ctx context.Context, job *models.ProductImportJob, data []byte) {
+	var rowErrors []models.ImportRowError
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("product import panicked", "job_id", job.ID, "panic", r)
+			job.Status = string(models.ImportFailed)
+			rowErrors = append(rowErrors, models.ImportRowError{Error: "internal error"})
+		}
+		now := time.Now()
+		job.FinishedAt = &now
+		job.SetRowErrors(rowErrors)
+		if err := s.productRepo.UpdateImportJob(ctx, job); err != nil {
+			slog.Error("failed to save product import job", "job_id", job.ID, "error", err)
+		}
+	}()
+
+	job.Status = string(models.ImportRunning)
+	if err := s.productRepo.UpdateImportJob(ctx, job); err != nil {
+		slog.Error("failed to save product import job", "job_id", job.ID, "error", err)
+	}
+
+	fail := func(err error) {
+		job.Status = string(models.ImportFailed)
+		rowErrors = append(rowErrors, models.ImportRowError{Error: err.Error()})
+	}
+
+	var rows []importRow
+	var err error
+	if job.Format == ImportFormatCSV {
+		rows, err = parseCSVImport(data)
+	} else {
+		rows, err = parseJSONLImport(data)
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+	if len(rows) > maxImportRows {
+		fail(fmt.Errorf("import has %d rows, the limit is %d", len(rows), maxImportRows))
+		return
+	}
+
+	categories, err := s.productRepo.FindCategories()
+	if err != nil {
+		fail(errors.New("failed to load categories"))
+		return
+	}
+	categoryIDs := make(map[string]uint, len(categories)*2)
+	for _, category := range categories {
+		categoryIDs[strings.ToLower(category.Name)] = category.ID
+		categoryIDs[slugify(category.Name)] = category.ID
+	}
+
+	job.TotalRows = len(rows)
+	for _, row := range rows {
+		err := row.err
+		created := false
+		if err == nil {
+			created, err = s.importRecord(ctx, job.ShopID, job.UserID, categoryIDs, row.record)
+		}
+
+		switch {
+		case err != nil:
+			job.FailedCount++
+			if len(rowErrors) < maxStoredRowErrors {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: row.number, SKU: row.record.SKU, Error: err.Error()})
+			}
+		case created:
+			job.CreatedCount++
+		default:
+			job.UpdatedCount++
+		}
+	}
+	job.Status = string(models.ImportCompleted)
+}
+
+// importRecord upserts a single record and reports whether it was created.
+// Errors are worded for the seller reading the job report.
+func (s *ProductImportService) importRecord(ctx context.Context, shopID, userID uint, categoryIDs map[string]uint, record models.ProductRecord) (bool, error) {
+	if err := validateProductRecord(record); err != nil {
+		return false, err
+	}
+	categoryID, ok := categoryIDs[strings.ToLower(strings.TrimSpace(record.Category))]
+	if !ok {
+		return false, fmt.Errorf("unknown category %q", record.Category)
+	}
+
+	product, err := s.productRepo.FindBySKU(shopID, record.SKU)
+	created := errors.Is(err, gorm.ErrRecordNotFound)
+	if created {
+		product = &models.Product{ShopID: shopID, SKU: record.SKU, Status: string(models.ProductDraft)}
+	} else if err != nil {
+		slog.Error("failed to look up product by SKU", "error", err)
+		return false, errors.New("failed to save product")
+	}
+
+	product.Name = record.Name
+	product.Description = record.Description
+	product.Price = record.Price
+	product.CategoryID = categoryID
+	if record.Status != "" {
+		product.Status = record.Status
+		product.PublishAt = nil
+	}
+
+	movement, err := s.productRepo.SaveImported(ctx, product, record.Stock, userID, record.ImageURLs)
+	if repositories.IsUniqueViolation(err, repositories.ProductSKUConstraint) {
+		// Another request created the SKU since it was looked up.
+		return false, errors.New("another product of the shop already uses this SKU")
+	}
+	if err != nil {
+		slog.Error("failed to save imported product", "error", err)
+		return false, errors.New("failed to save product")
+	}
+	if movement != nil {
+		s.inventory.StockChanged(ctx, movement)
+	}
+	return created, nil
+}
+
+func validateProductRecord(record models.ProductRecord) error {
+	switch {
+	case record.SKU == "":
+		return errors.New("sku is required")
+	case len(record.SKU) > 64:
+		return errors.New("sku must be at most 64 characters")
+	case len(record.Name) < 2 || len(record.Name) > 255:
+		return errors.New("name must be between 2 and 255 characters")
+	case record.Price <= 0:
+		return errors.New("price must be greater than 0")
+	case record.Stock < 0:
+		return errors.New("stock cannot be negative")
+	case record.Category == "":
+		return errors.New("category is required")
+	}
+
+	switch models.ProductStatus(record.Status) {
+	case "", models.ProductDraft, models.ProductPublished, models.ProductArchived:
+	default:
+		return fmt.Errorf("status must be draft, published or archived, not %q", record.Status)
+	}
+
+	for _, imageURL := range record.ImageURLs {
+		parsed, err := url.Parse(imageURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(imageURL) > 255 {
+			return fmt.Errorf("invalid image URL %q", imageURL)
+		}
+	}
+	return nil
+}
+
+func parseCSVImport(data []byte) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("the CSV file has no header row")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"sku", "name", "price", "category"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("the CSV header is missing the %q column", required)
+		}
+	}
+
+	var rows []importRow
+	for number := 1; ; number++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, importRow{number: number, err: errors.New("malformed CSV row")})
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		row := importRow{number: number}
+		row.record = models.ProductRecord{
+			SKU:         field("sku"),
+			Name:        field("name"),
+			Description: field("description"),
+			Category:    field("category"),
+			Status:      field("status"),
+		}
+		if row.record.Price, err = strconv.ParseFloat(field("price"), 64); err != nil {
+			row.err = fmt.Errorf("invalid price %q", field("price"))
+		}
+		if stock := field("stock"); stock != "" {
+			if row.record.Stock, err = strconv.Atoi(stock); err != nil {
+				row.err = fmt.Errorf("invalid stock %q", stock)
+			}
+		}
+		for _, imageURL := range strings.Split(field("image_urls"), "|") {
+			if imageURL = strings.TrimSpace(imageURL); imageURL != "" {
+				row.record.ImageURLs = append(row.record.ImageURLs, imageURL)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseJSONLImport(data []byte) ([]importRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var rows []importRow
+	for number := 1; scanner.Scan(); number++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		row := importRow{number: number}
+		if err := json.Unmarshal(line, &row.record); err != nil {
+			row.err = errors.New("malformed JSON line")
+		}
+		row.record.SKU = strings.TrimSpace(row.record.SKU)
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the JSON Lines file: %w", err)
+	}
+	return rows, nil
+}
+
+// Export writes every product of the shop to w as CSV or JSON Lines, in the
+// same shape the importer accepts.
+func (s *ProductImportService) Export(ctx context.Context, shopID uint, format string, w io.Writer) error {
+	if format != ImportFormatCSV && format != ImportFormatJSONL {
+		return ErrUnsupportedImportFormat
+	}
+
+	categories, err := s.productRepo.FindCategories()
+	if err != nil {
+		return err
+	}
+	categoryNames := make(map[uint]string, len(categories))
+	for _, category := range categories {
+		categoryNames[category.ID] = category.Name
+	}
+
+	var csvWriter *csv.Writer
+	var encoder *json.Encoder
+	if format == ImportFormatCSV {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(csvColumns); err != nil {
+			return err
+		}
+	} else {
+		encoder = json.NewEncoder(w)
+	}
+
+	err = s.productRepo.EachShopProduct(ctx, shopID, func(products []models.Product) error {
+		for _, product := range products {
+			record := models.ProductRecord{
+				SKU:         product.SKU,
+				Name:        product.Name,
+				Description: product.Description,
+				Price:       product.Price,
+				Stock:       product.Stock,
+				Category:    categoryNames[product.CategoryID],
+				Status:      product.Status,
+			}
+			for _, image := range product.Images {
+				record.ImageURLs = append(record.ImageURLs, image.ImageURL)
+			}
+
+			if encoder != nil {
+				if err := encoder.Encode(record); err != nil {
+					return err
+				}
+				continue
+			}
+			err := csvWriter.Write([]string{
+				record.SKU,
+				record.Name,
+				record.Description,
+				strconv.FormatFloat(record.Price, 'f', 2, 64),
+				strconv.Itoa(record.Stock),
+				record.Category,
+				record.Status,
+				strings.Join(record.ImageURLs, "|"),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if csvWriter != nil {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
+
+// slugify turns "Home & Garden" into "home-garden".
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
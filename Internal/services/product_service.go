@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+)
+
+type ProductService struct {
+	productRepo *repositories.ProductRepository
+}
+
+func NewProductService(productRepo *repositories.ProductRepository) *ProductService {
+	return &ProductService{productRepo: productRepo}
+}
+
+func (s *ProductService) PublishDueProducts(ctx context.Context) (int64, error) {
+	return s.productRepo.PublishDue(ctx, time.Now())
+}
+
+// RunPublishScheduler calls PublishDueProducts every interval until ctx is done.
+func (s *ProductService) RunPublishScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if published, err := s.PublishDueProducts(ctx); err != nil {
+			slog.Error("product publish scheduler failed", "error", err)
+		} else if published > 0 {
+			slog.Info("published scheduled products", "count", published)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
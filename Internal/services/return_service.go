@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+)
+
+// Return errors are worded for the customer or seller acting on the return.
+var (
+	ErrOrderNotReturnable   = errors.New("only completed orders can be returned")
+	ErrReturnItemNotInOrder = errors.New("an item is not part of this order")
+	ErrReturnItemTwice      = errors.New("list each item only once")
+	ErrReturnQuantity       = errors.New("more units are returned than were bought")
+	ErrReturnMixedShops     = errors.New("items sold by different shops must be returned separately")
+	ErrReturnState          = errors.New("the return cannot do this in its current status")
+	ErrNothingToRefund      = errors.New("the order has no captured payment to refund")
+	ErrRefundTooLarge       = errors.New("the refund is more than was paid for the returned items")
+)
+
+// IsReturnError reports whether err is a return being refused, as opposed to
+// a failure to process it.
+func IsReturnError(err error) bool {
+	for _, returnErr := range []error{
+		ErrOrderNotReturnable, ErrReturnItemNotInOrder, ErrReturnItemTwice, ErrReturnQuantity,
+		ErrReturnMixedShops, ErrNothingToRefund, ErrRefundTooLarge,
+	} {
+		if errors.Is(err, returnErr) {
+			return true
+		}
+	}
+	return false
+}
+
+type ReturnService struct {
+	returnRepo *repositories.ReturnRepository
+	orderRepo  *repositories.OrderRepository
+	orders     *OrderService
+	inventory  *InventoryService
+	ledger     *LedgerService
+}
+
+func NewReturnService(returnRepo *repositories.ReturnRepository, orderRepo *repositories.OrderRepository, orders *OrderService, inventory *InventoryService, ledger *LedgerService) *ReturnService {
+	return &ReturnService{returnRepo: returnRepo, orderRepo: orderRepo, orders: orders, inventory: inventory, ledger: ledger}
+}
+
+// RequestReturn opens a return for some of the items of a completed order.
+func (s *ReturnService) RequestReturn(ctx context.Context, orderID, userID uint, payload models.ReturnPayload) (*models.Return, error) {
+	return s.returnRepo.CreateReturn(ctx, orderID, func(order *models.Order, returned map[uint]int) (*models.Return, error) {
+		if order.Status != string(models.Completed) {
+			return nil, ErrOrderNotReturnable
+		}
+		items := make(map[uint]models.OrderItem, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			items[item.ID] = item
+		}
+
+		ret := &models.Return{
+			OrderID: order.ID,
+			UserID:  userID,
+			Status:  string(models.ReturnRequested),
+			Note:    payload.Note,
+		}
+		var productIDs []uint
+		seen := make(map[uint]bool, len(payload.Items))
+		for _, requested := range payload.Items {
+			item, ok := items[requested.OrderItemID]
+			if !ok {
+				return nil, ErrReturnItemNotInOrder
+			}
+			if seen[item.ID] {
+				return nil, ErrReturnItemTwice
+			}
+			seen[item.ID] = true
+			if requested.Quantity > item.Quantity-returned[item.ID] {
+				return nil, ErrReturnQuantity
+			}
+			ret.Items = append(ret.Items, models.ReturnItem{
+				OrderItemID: item.ID,
+				ProductID:   item.ProductID,
+				Quantity:    requested.Quantity,
+				Reason:      requested.Reason,
+				Comment:     requested.Comment,
+			})
+			productIDs = append(productIDs, item.ProductID)
+		}
+
+		products, err := s.orderRepo.FindProductsByID(productIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range productIDs {
+			product, ok := products[id]
+			if !ok {
+				return nil, fmt.Errorf("product %d of the order no longer exists", id)
+			}
+			if i > 0 && product.ShopID != ret.ShopID {
+				return nil, ErrReturnMixedShops
+			}
+			ret.ShopID = product.ShopID
+		}
+		return ret, nil
+	})
+}
+
+func (s *ReturnService) GetReturn(returnID uint) (*models.Return, error) {
+	return s.returnRepo.FindReturn(returnID)
+}
+
+func (s *ReturnService) ListReturns(all bool, userID, shopID uint, status string) ([]models.Return, error) {
+	return s.returnRepo.FindReturns(all, userID, shopID, status)
+}
+
+func (s *ReturnService) Approve(ctx context.Context, ret *models.Return) error {
+	return s.transition(ctx, ret, models.ReturnApproved, models.ReturnRequested)
+}
+
+func (s *ReturnService) Reject(ctx context.Context, ret *models.Return, reason string) error {
+	ret.RejectionReason = reason
+	return s.transition(ctx, ret, models.ReturnRejected, models.ReturnRequested)
+}
+
+// Cancel withdraws a return before the goods are received.
+func (s *ReturnService) Cancel(ctx context.Context, ret *models.Return) error {
+	return s.transition(ctx, ret, models.ReturnCancelled, models.ReturnRequested, models.ReturnApproved)
+}
+
+// Receive records that the goods are back and restocks them unless restock
+// is false.
+func (s *ReturnService) Receive(ctx context.Context, ret *models.Return, restock bool) error {
+	if ret.Status != string(models.ReturnApproved) {
+		return ErrReturnState
+	}
+	movements, err := s.returnRepo.ReceiveReturn(ctx, ret, restock)
+	if errors.Is(err, repositories.ErrReturnChanged) {
+		return ErrReturnState
+	}
+	if err != nil {
+		return err
+	}
+	for i := range movements {
+		s.inventory.StockChanged(ctx, &movements[i])
+	}
+	return nil
+}
+
+// Refund pays the customer back through the order's payment, by default
+// what they paid for the returned units. A smaller amount may be given, but
+// never more than that or than is left of the shop's sale after earlier
+// refunds; shipping is not refunded. Sellers may refund once the return is
+// approved, without waiting for the goods.
+func (s *ReturnService) Refund(ctx context.Context, ret *models.Return, amount *float64) error {
+	if ret.Status != string(models.ReturnApproved) && ret.Status != string(models.ReturnReceived) {
+		return ErrReturnState
+	}
+	order, err := s.orderRepo.FindByID(ret.OrderID)
+	if err != nil {
+		return err
+	}
+
+	value := ReturnValue(order, ret)
+	refundable, err := s.ledger.ShopRefundable(order.ID, ret.ShopID)
+	if err != nil {
+		return err
+	}
+	refund := min(value, refundable)
+	if amount != nil {
+		refund = models.RoundMoney(*amount)
+		if refund > value || refund > refundable {
+			return ErrRefundTooLarge
+		}
+	}
+
+	var paid *models.Payment
+	for i, payment := range order.Payments {
+		if payment.Status == string(models.PaymentCaptured) &&
+			models.RoundMoney(payment.CapturedAmount-payment.RefundedAmount) >= refund {
+			paid = &order.Payments[i]
+			break
+		}
+	}
+	if paid == nil || refund <= 0 {
+		return ErrNothingToRefund
+	}
+
+	// Claiming the return first makes a concurrent refund of it fail here
+	// instead of paying the customer twice.
+	from := models.ReturnStatus(ret.Status)
+	if err := s.transition(ctx, ret, models.ReturnRefunding, models.ReturnApproved, models.ReturnReceived); err != nil {
+		return err
+	}
+	if _, err := s.orders.RefundShopPayment(ctx, order, ret.ShopID, paid.ID, &refund); err != nil {
+		ret.Status = string(from)
+		if releaseErr := s.returnRepo.UpdateReturn(ctx, ret, models.ReturnRefunding); releaseErr != nil {
+			slog.Error("failed to release return after refund failed", "return_id", ret.ID, "error", releaseErr)
+		}
+		return err
+	}
+
+	now := time.Now()
+	ret.Status = string(models.ReturnRefunded)
+	ret.RefundAmount = refund
+	ret.PaymentID = &paid.ID
+	ret.RefundedAt = &now
+	return s.returnRepo.UpdateReturn(ctx, ret, models.ReturnRefunding)
+}
+
+// ReturnValue is what the customer paid for the returned units: their share
+// of the line after price rules and coupons, plus tax when it was charged on
+// top of the prices.
+func ReturnValue(order *models.Order, ret *models.Return) float64 {
+	items := make(map[uint]models.OrderItem, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		items[item.ID] = item
+	}
+
+	// Orders placed before coupons were spread over the items they cover
+	// only have the order's discount total, so spread that over every item.
+	spread := order.DiscountTotal > 0
+	for _, item := range order.OrderItems {
+		if item.CouponDiscount != 0 {
+			spread = false
+		}
+	}
+
+	var total float64
+	for _, returned := range ret.Items {
+		item, ok := items[returned.OrderItemID]
+		if !ok || item.Quantity == 0 {
+			continue
+		}
+		line := item.NetTotal()
+		if spread && order.Subtotal > 0 {
+			line -= order.DiscountTotal * line / order.Subtotal
+		}
+		if !order.PricesIncludeTax {
+			line += item.Tax
+		}
+		total += line * float64(returned.Quantity) / float64(item.Quantity)
+	}
+	return models.RoundMoney(total)
+}
+
+func (s *ReturnService) transition(ctx context.Context, ret *models.Return, to models.ReturnStatus, from ...models.ReturnStatus) error {
+	allowed := false
+	for _, status := range from {
+		if ret.Status == string(status) {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return ErrReturnState
+	}
+
+	ret.Status = string(to)
+	err := s.returnRepo.UpdateReturn(ctx, ret, from...)
+	if errors.Is(err, repositories.ErrReturnChanged) {
+		return ErrReturnState
+	}
+	return err
+}
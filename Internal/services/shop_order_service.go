@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+)
+
+var (
+	ErrShopOrderState      = errors.New("the shop order cannot do this in its current status")
+	ErrOrderNotFulfillable = errors.New("the order is not awaiting fulfillment")
+)
+
+// Shipment errors are worded for the seller recording the shipment.
+var (
+	ErrShipmentItemNotInOrder = errors.New("an item is not part of this order")
+	ErrShipmentItemTwice      = errors.New("list each item only once")
+	ErrShipmentQuantity       = errors.New("more units are shipped than remain to ship")
+	ErrNothingToShip          = errors.New("every item has already shipped")
+)
+
+// IsShipmentError reports whether err is a shipment being refused, as
+// opposed to a failure to record it.
+func IsShipmentError(err error) bool {
+	for _, shipmentErr := range []error{
+		ErrShipmentItemNotInOrder, ErrShipmentItemTwice, ErrShipmentQuantity, ErrNothingToShip,
+	} {
+		if errors.Is(err, shipmentErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// shopOrderTransitions lists the statuses a shop order may move to from
+// each status.
+var shopOrderTransitions = map[models.ShopOrderStatus][]models.ShopOrderStatus{
+	models.ShopOrderProcessing: {models.ShopOrderPending},
+	models.ShopOrderShipped:    {models.ShopOrderPending, models.ShopOrderProcessing},
+	models.ShopOrderDelivered:  {models.ShopOrderShipped},
+	models.ShopOrderCancelled:  {models.ShopOrderPending, models.ShopOrderProcessing},
+}
+
+// ShopOrderService lets sellers fulfil their part of marketplace orders.
+type ShopOrderService struct {
+	repo      *repositories.ShopOrderRepository
+	shipments *repositories.ShipmentRepository
+	inventory *InventoryService
+}
+
+func NewShopOrderService(repo *repositories.ShopOrderRepository, shipments *repositories.ShipmentRepository, inventory *InventoryService) *ShopOrderService {
+	return &ShopOrderService{repo: repo, shipments: shipments, inventory: inventory}
+}
+
+func (s *ShopOrderService) ListShopOrders(shopID uint, status string) ([]models.ShopOrder, error) {
+	return s.repo.FindShopOrders(shopID, status)
+}
+
+func (s *ShopOrderService) GetShopOrder(shopID, shopOrderID uint) (*models.ShopOrder, error) {
+	return s.repo.FindShopOrder(shopID, shopOrderID)
+}
+
+// Update applies the payload to the shop order. Tracking can change until
+// the shop order is cancelled; status changes follow shopOrderTransitions,
+// and only once the parent order is paid and not cancelled or refunded.
+// Shop orders of unpaid orders may only be cancelled.
+func (s *ShopOrderService) Update(ctx context.Context, shopOrder *models.ShopOrder, payload models.UpdateShopOrderPayload) error {
+	current := models.ShopOrderStatus(shopOrder.Status)
+	from := []models.ShopOrderStatus{current}
+	if payload.Status != nil && *payload.Status != shopOrder.Status {
+		to := models.ShopOrderStatus(*payload.Status)
+		allowed := shopOrderTransitions[to]
+		if !containsShopOrderStatus(allowed, current) {
+			return ErrShopOrderState
+		}
+		from = allowed
+
+		now := time.Now()
+		shopOrder.Status = string(to)
+		switch to {
+		case models.ShopOrderShipped:
+			shopOrder.ShippedAt = &now
+		case models.ShopOrderDelivered:
+			shopOrder.DeliveredAt = &now
+		case models.ShopOrderCancelled:
+			shopOrder.CancelledAt = &now
+		}
+	} else if current == models.ShopOrderCancelled {
+		return ErrShopOrderState
+	}
+	if payload.Carrier != nil {
+		shopOrder.Carrier = *payload.Carrier
+	}
+	if payload.TrackingNumber != nil {
+		shopOrder.TrackingNumber = *payload.TrackingNumber
+	}
+	if payload.TrackingURL != nil {
+		shopOrder.TrackingURL = *payload.TrackingURL
+	}
+
+	movements, err := s.repo.UpdateShopOrder(ctx, shopOrder, func(order *models.Order) error {
+		switch models.OrderStatus(order.Status) {
+		case models.Paid, models.Processing:
+			return nil
+		case models.Pending, models.PaymentFailed:
+			// Unpaid orders may only be cancelled.
+			if shopOrder.Status == string(models.ShopOrderCancelled) || shopOrder.Status == string(current) {
+				return nil
+			}
+		case models.Completed:
+			// Tracking may still be corrected after delivery.
+			if shopOrder.Status == string(current) {
+				return nil
+			}
+		}
+		return ErrOrderNotFulfillable
+	}, from...)
+	if errors.Is(err, repositories.ErrShopOrderChanged) {
+		return ErrShopOrderState
+	}
+	if err != nil {
+		return err
+	}
+	for i := range movements {
+		s.inventory.StockChanged(ctx, &movements[i])
+	}
+	return nil
+}
+
+func (s *ShopOrderService) ListShipments(shopOrderID uint) ([]models.Shipment, error) {
+	return s.shipments.FindShipments(shopOrderID)
+}
+
+func (s *ShopOrderService) GetShipment(shopOrderID, shipmentID uint) (*models.Shipment, error) {
+	return s.shipments.FindShipment(shopOrderID, shipmentID)
+}
+
+// Ship records a parcel for the shop order. Items left out of the payload
+// are not in the parcel; an empty list ships everything still unshipped.
+func (s *ShopOrderService) Ship(ctx context.Context, shopOrder *models.ShopOrder, payload models.ShipmentPayload) (*models.Shipment, error) {
+	return s.shipments.CreateShipment(ctx, shopOrder, func(order *models.Order, shopOrder *models.ShopOrder, shipped map[uint]int) (*models.Shipment, error) {
+		// Nothing ships before the payment is captured.
+		switch models.OrderStatus(order.Status) {
+		case models.Paid, models.Processing:
+		default:
+			return nil, ErrOrderNotFulfillable
+		}
+		if shopOrder.Status != string(models.ShopOrderPending) && shopOrder.Status != string(models.ShopOrderProcessing) {
+			return nil, ErrShopOrderState
+		}
+
+		items := make(map[uint]models.OrderItem, len(shopOrder.Items))
+		for _, item := range shopOrder.Items {
+			items[item.ID] = item
+		}
+		shipment := &models.Shipment{
+			OrderID:        shopOrder.OrderID,
+			ShopOrderID:    shopOrder.ID,
+			ShopID:         shopOrder.ShopID,
+			Carrier:        payload.Carrier,
+			TrackingNumber: payload.TrackingNumber,
+			TrackingURL:    payload.TrackingURL,
+			ShippedAt:      time.Now(),
+		}
+		if len(payload.Items) == 0 {
+			for _, item := range shopOrder.Items {
+				if remaining := item.Quantity - shipped[item.ID]; remaining > 0 {
+					shipment.Items = append(shipment.Items, models.ShipmentItem{
+						OrderItemID: item.ID,
+						ProductID:   item.ProductID,
+						Quantity:    remaining,
+					})
+				}
+			}
+		}
+		seen := make(map[uint]bool, len(payload.Items))
+		for _, requested := range payload.Items {
+			item, ok := items[requested.OrderItemID]
+			if !ok {
+				return nil, ErrShipmentItemNotInOrder
+			}
+			if seen[item.ID] {
+				return nil, ErrShipmentItemTwice
+			}
+			seen[item.ID] = true
+			if requested.Quantity > item.Quantity-shipped[item.ID] {
+				return nil, ErrShipmentQuantity
+			}
+			shipment.Items = append(shipment.Items, models.ShipmentItem{
+				OrderItemID: item.ID,
+				ProductID:   item.ProductID,
+				Quantity:    requested.Quantity,
+			})
+		}
+		if len(shipment.Items) == 0 {
+			return nil, ErrNothingToShip
+		}
+		return shipment, nil
+	})
+}
+
+// Deliver records that the parcel arrived.
+func (s *ShopOrderService) Deliver(ctx context.Context, shipment *models.Shipment) error {
+	err := s.shipments.DeliverShipment(ctx, shipment)
+	if errors.Is(err, repositories.ErrShipmentDelivered) {
+		return ErrShopOrderState
+	}
+	return err
+}
+
+func containsShopOrderStatus(statuses []models.ShopOrderStatus, status models.ShopOrderStatus) bool {
+	for _, candidate := range statuses {
+		if candidate == status {
+			return true
+		}
+	}
+	return false
+}
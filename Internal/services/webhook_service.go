@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/payment"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+)
+
+var (
+	ErrUnknownPaymentProvider = errors.New("unknown payment provider")
+	ErrInvalidWebhook         = errors.New("invalid webhook payload")
+	ErrEventProcessed         = errors.New("the event was already processed")
+	ErrEventInProgress        = errors.New("the event is being processed")
+)
+
+// eventClaimTimeout is how long a claim on an event holds before another
+// delivery or replay may take it over.
+const eventClaimTimeout = 5 * time.Minute
+
+// WebhookService ingests payment provider webhooks. Every verified delivery
+// is stored before it is acted on, so failures can be replayed later.
+type WebhookService struct {
+	eventRepo *repositories.PaymentEventRepository
+	orders    *OrderService
+	payments  payment.Provider
+}
+
+func NewWebhookService(eventRepo *repositories.PaymentEventRepository, orders *OrderService, payments payment.Provider) *WebhookService {
+	return &WebhookService{eventRepo: eventRepo, orders: orders, payments: payments}
+}
+
+// Receive verifies a delivery for the named provider, stores it and applies
+// it. Events that were already processed, or are being processed by another
+// delivery, are acknowledged without being applied again, as are events for
+// intents we have no payment for. An error from applying the event is
+// returned after the event is marked failed, so the provider retries the
+// delivery.
+func (s *WebhookService) Receive(ctx context.Context, provider string, header http.Header, payload []byte) (*models.PaymentEvent, error) {
+	if provider != s.payments.Name() {
+		return nil, ErrUnknownPaymentProvider
+	}
+	if err := s.payments.VerifyWebhook(header, payload, time.Now()); err != nil {
+		return nil, err
+	}
+	event, err := s.payments.ParseWebhook(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	record := &models.PaymentEvent{
+		Provider: provider,
+		EventID:  event.ID,
+		Type:     string(event.Type),
+		IntentID: event.Intent.ID,
+		Payload:  string(payload),
+		Status:   string(models.PaymentEventReceived),
+	}
+	if _, err := s.eventRepo.SaveEvent(ctx, record); err != nil {
+		return nil, err
+	}
+	err = s.process(ctx, record, event)
+	if errors.Is(err, ErrEventProcessed) || errors.Is(err, ErrEventInProgress) {
+		return record, nil
+	}
+	return record, err
+}
+
+// Replay applies a stored event that has not been processed yet.
+func (s *WebhookService) Replay(ctx context.Context, eventID uint) (*models.PaymentEvent, error) {
+	record, err := s.eventRepo.FindEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status == string(models.PaymentEventProcessed) {
+		return nil, ErrEventProcessed
+	}
+	return record, s.replay(ctx, record)
+}
+
+// ReplayFailed replays every failed event, oldest first, and reports how
+// many were processed and how many failed again.
+func (s *WebhookService) ReplayFailed(ctx context.Context) (processed, failed int, err error) {
+	records, err := s.eventRepo.FindFailedEvents()
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range records {
+		err := s.replay(ctx, &records[i])
+		if errors.Is(err, ErrEventProcessed) || errors.Is(err, ErrEventInProgress) {
+			// Another replay or a redelivery got to it first.
+			continue
+		}
+		if err != nil {
+			failed++
+			continue
+		}
+		processed++
+	}
+	return processed, failed, nil
+}
+
+func (s *WebhookService) ListEvents(status string, limit, offset int) ([]models.PaymentEvent, int64, error) {
+	return s.eventRepo.FindEvents(status, limit, offset)
+}
+
+func (s *WebhookService) replay(ctx context.Context, record *models.PaymentEvent) error {
+	if record.Provider != s.payments.Name() {
+		return ErrUnknownPaymentProvider
+	}
+	event, err := s.payments.ParseWebhook([]byte(record.Payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	return s.process(ctx, record, event)
+}
+
+// process claims the event, applies it and records the outcome on the stored
+// copy.
+func (s *WebhookService) process(ctx context.Context, record *models.PaymentEvent, event *payment.Event) error {
+	claimed, err := s.eventRepo.ClaimEvent(ctx, record, time.Now().Add(-eventClaimTimeout))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		if record.Status == string(models.PaymentEventProcessed) {
+			return ErrEventProcessed
+		}
+		return ErrEventInProgress
+	}
+
+	applyErr := s.orders.ApplyPaymentEvent(ctx, event)
+	if errors.Is(applyErr, ErrUnknownPaymentIntent) {
+		// Retrying cannot help, e.g. the intent belongs to another system on
+		// the same provider account. It can still be replayed by hand.
+		slog.Warn("ignored payment event for an unknown intent", "event_id", event.ID, "payment_intent", event.Intent.ID)
+		record.Status = string(models.PaymentEventIgnored)
+		record.LastError = applyErr.Error()
+		return s.eventRepo.UpdateEvent(ctx, record)
+	}
+	if applyErr != nil {
+		record.Status = string(models.PaymentEventFailed)
+		record.LastError = applyErr.Error()
+	} else {
+		now := time.Now()
+		record.Status = string(models.PaymentEventProcessed)
+		record.LastError = ""
+		record.ProcessedAt = &now
+	}
+	if err := s.eventRepo.UpdateEvent(ctx, record); err != nil {
+		return err
+	}
+	return applyErr
+}
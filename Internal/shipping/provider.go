@@ -0,0 +1,52 @@
+// Package shipping quotes shipping rates for orders.
+package shipping
+
+import "context"
+
+// Destination is where a shipment goes. Country is an ISO 3166-1 alpha-2 code.
+type Destination struct {
+	Country string
+	Region  string
+}
+
+// Item is a product in a shipment. Weight is per unit, in grams; dimensions
+// are per unit, in centimetres, and zero when unknown. Amount is what the
+// customer pays for the whole line.
+type Item struct {
+	ProductID   uint
+	Quantity    int
+	WeightGrams int
+	LengthCm    float64
+	WidthCm     float64
+	HeightCm    float64
+	Amount      float64
+}
+
+// Shipment is the part of an order one shop sends out.
+type Shipment struct {
+	ShopID uint
+	Items  []Item
+}
+
+type Request struct {
+	Destination Destination
+	Shipments   []Shipment
+}
+
+// Quote is a shipping method offered for one shop's shipment. ID is stable
+// for the method so a selected quote can be priced again later.
+type Quote struct {
+	ID      string
+	ShopID  uint
+	Name    string
+	Carrier string
+	Amount  float64
+	MinDays int
+	MaxDays int
+}
+
+// RateProvider quotes shipping methods. Carrier integrations implement it to
+// offer live rates. Implementations must be safe for concurrent use.
+type RateProvider interface {
+	Quote(ctx context.Context, request Request) ([]Quote, error)
+}
@@ -0,0 +1,111 @@
+package shipping
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Profile types.
+const (
+	FlatRate   = "flat"
+	WeightRate = "weight"
+)
+
+// volumetricDivisor converts cubic centimetres into a billable weight in kg,
+// as carriers do for light but bulky parcels.
+const volumetricDivisor = 5000
+
+// Profile is a shipping method a shop configures.
+type Profile struct {
+	ID     uint
+	ShopID uint
+	Name   string
+	Type   string
+	// Countries is the destination zone; empty means everywhere.
+	Countries   []string
+	BaseAmount  float64
+	PerKgAmount float64
+	// FreeOver makes the method free once the shipment is worth at least
+	// this much; zero disables it.
+	FreeOver float64
+	MinDays  int
+	MaxDays  int
+}
+
+// ProfileSource loads the active shipping profiles of the given shops.
+type ProfileSource interface {
+	ShippingProfiles(ctx context.Context, shopIDs []uint) ([]Profile, error)
+}
+
+// TableProvider quotes the shipping profiles shops set up themselves.
+type TableProvider struct {
+	profiles ProfileSource
+}
+
+func NewTableProvider(profiles ProfileSource) *TableProvider {
+	return &TableProvider{profiles: profiles}
+}
+
+func (p *TableProvider) Quote(ctx context.Context, request Request) ([]Quote, error) {
+	shopIDs := make([]uint, len(request.Shipments))
+	for i, shipment := range request.Shipments {
+		shopIDs[i] = shipment.ShopID
+	}
+	profiles, err := p.profiles.ShippingProfiles(ctx, shopIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var quotes []Quote
+	for _, shipment := range request.Shipments {
+		for _, profile := range profiles {
+			if profile.ShopID != shipment.ShopID || !profile.ships(request.Destination) {
+				continue
+			}
+			quotes = append(quotes, Quote{
+				ID:      fmt.Sprintf("profile-%d", profile.ID),
+				ShopID:  shipment.ShopID,
+				Name:    profile.Name,
+				Amount:  profile.rate(shipment),
+				MinDays: profile.MinDays,
+				MaxDays: profile.MaxDays,
+			})
+		}
+	}
+	return quotes, nil
+}
+
+func (p *Profile) ships(destination Destination) bool {
+	return len(p.Countries) == 0 || slices.Contains(p.Countries, strings.ToUpper(destination.Country))
+}
+
+func (p *Profile) rate(shipment Shipment) float64 {
+	var value float64
+	for _, item := range shipment.Items {
+		value += item.Amount
+	}
+	if p.FreeOver > 0 && value >= p.FreeOver {
+		return 0
+	}
+
+	amount := p.BaseAmount
+	if p.Type == WeightRate {
+		// Charge per started kilogram of billable weight.
+		amount += p.PerKgAmount * math.Ceil(BillableWeightKg(shipment.Items))
+	}
+	return math.Round(amount*100) / 100
+}
+
+// BillableWeightKg is the larger of the actual and the volumetric weight.
+func BillableWeightKg(items []Item) float64 {
+	var actual, volumetric float64
+	for _, item := range items {
+		quantity := float64(item.Quantity)
+		actual += float64(item.WeightGrams) / 1000 * quantity
+		volumetric += item.LengthCm * item.WidthCm * item.HeightCm / volumetricDivisor * quantity
+	}
+	return max(actual, volumetric)
+}
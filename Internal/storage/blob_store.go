@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore keeps uploaded files. Keys are slash separated relative paths
+// such as "products/12/3f9a.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL is the address clients should use to fetch the blob.
+	URL(key string) string
+}
+
+// NewBlobStoreFromEnv picks the store configured through the environment.
+// BLOB_STORE=s3 selects the S3-compatible store, anything else the local one.
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	if os.Getenv("BLOB_STORE") == "s3" {
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    getenv("S3_REGION", "us-east-1"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		})
+	}
+	return NewLocalStore(getenv("MEDIA_DIR", "./uploads"), getenv("MEDIA_BASE_URL", "/media"))
+}
+
+// cleanKey rejects keys that could escape the store's root.
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
+
+func getenv(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
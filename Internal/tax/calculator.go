@@ -0,0 +1,48 @@
+// Package tax works out the taxes due on an order.
+package tax
+
+import (
+	"context"
+	"os"
+)
+
+// Address is the destination taxes are calculated for. Country is an ISO
+// 3166-1 alpha-2 code; Region is a state or province code within it.
+type Address struct {
+	Country string
+	Region  string
+}
+
+// Item is one order line to tax. Amount is what the customer pays for the
+// line after discounts, including tax when prices include tax.
+type Item struct {
+	Category string
+	Amount   float64
+}
+
+// Line is a single tax charged on an item.
+type Line struct {
+	Name    string
+	Percent float64
+	Amount  float64
+}
+
+type Request struct {
+	Address Address
+	Items   []Item
+	// PricesIncludeTax means item amounts already contain the tax, which is
+	// then extracted from them instead of added on top.
+	PricesIncludeTax bool
+}
+
+// Calculator computes taxes. Implementations return one slice of lines per
+// request item, in the same order, and must be safe for concurrent use.
+type Calculator interface {
+	Calculate(ctx context.Context, request Request) ([][]Line, error)
+}
+
+// PricesIncludeTaxFromEnv reports whether catalog prices include tax, set with
+// PRICES_INCLUDE_TAX=true. Prices exclude tax by default.
+func PricesIncludeTaxFromEnv() bool {
+	return os.Getenv("PRICES_INCLUDE_TAX") == "true"
+}
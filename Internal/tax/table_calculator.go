@@ -0,0 +1,98 @@
+package tax
+
+import (
+	"context"
+	"math"
+	"strings"
+)
+
+// Rate is a row of the tax table. An empty Region covers the whole country
+// and an empty Category covers every product tax category.
+type Rate struct {
+	Country  string
+	Region   string
+	Category string
+	Name     string
+	Percent  float64
+}
+
+// RateSource loads the tax table for a country.
+type RateSource interface {
+	TaxRates(ctx context.Context, country string) ([]Rate, error)
+}
+
+// TableCalculator charges the rates of a tax table. Country-wide and regional
+// rates both apply, so a federal and a provincial tax can be combined; at each
+// level a rate for the item's category wins over the generic one.
+type TableCalculator struct {
+	rates RateSource
+}
+
+func NewTableCalculator(rates RateSource) *TableCalculator {
+	return &TableCalculator{rates: rates}
+}
+
+func (c *TableCalculator) Calculate(ctx context.Context, request Request) ([][]Line, error) {
+	lines := make([][]Line, len(request.Items))
+	if request.Address.Country == "" {
+		return lines, nil
+	}
+
+	rates, err := c.rates.TaxRates(ctx, strings.ToUpper(request.Address.Country))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range request.Items {
+		applicable := applicableRates(rates, request.Address.Region, item.Category)
+		var totalPercent float64
+		for _, rate := range applicable {
+			totalPercent += rate.Percent
+		}
+
+		for _, rate := range applicable {
+			amount := item.Amount * rate.Percent / 100
+			if request.PricesIncludeTax {
+				amount = item.Amount * rate.Percent / (100 + totalPercent)
+			}
+			lines[i] = append(lines[i], Line{
+				Name:    rate.Name,
+				Percent: rate.Percent,
+				Amount:  math.Round(amount*100) / 100,
+			})
+		}
+	}
+	return lines, nil
+}
+
+// applicableRates picks the country-wide rate and the region's rate for the
+// category, preferring category-specific rows at each level.
+func applicableRates(rates []Rate, region, category string) []Rate {
+	levels := []string{""}
+	if region != "" {
+		levels = append(levels, region)
+	}
+
+	var applicable []Rate
+	for _, level := range levels {
+		var generic, specific *Rate
+		for i := range rates {
+			rate := &rates[i]
+			if !strings.EqualFold(rate.Region, level) {
+				continue
+			}
+			switch {
+			case rate.Category == "":
+				generic = rate
+			case strings.EqualFold(rate.Category, category):
+				specific = rate
+			}
+		}
+		if specific != nil {
+			applicable = append(applicable, *specific)
+		} else if generic != nil {
+			applicable = append(applicable, *generic)
+		}
+	}
+	return applicable
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 	"log/slog"
+	"time"
 
 	// Import the pgx driver
 	"github.com/Archnick/go-ecommerce/Internal/api"
 	"github.com/Archnick/go-ecommerce/Internal/models"
+	"github.com/Archnick/go-ecommerce/Internal/notify"
+	"github.com/Archnick/go-ecommerce/Internal/payment"
+	"github.com/Archnick/go-ecommerce/Internal/repositories"
+	"github.com/Archnick/go-ecommerce/Internal/services"
+	"github.com/Archnick/go-ecommerce/Internal/storage"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
@@ -23,21 +30,96 @@ func main() {
 		log.Fatalf("Failed to open database: %v", err)
 	}
 
+	if err := repositories.RegisterAuditCallbacks(db); err != nil {
+		log.Fatalf("Failed to register audit callbacks: %v", err)
+	}
+
 	db.AutoMigrate(
 		&models.User{},
 		&models.Shop{},
+		&models.ShopApplication{},
 		&models.Product{},
 		&models.ProductImage{},
+		&models.ProductImportJob{},
 		&models.Category{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderItemAdjustment{},
+		&models.OrderItemTax{},
+		&models.OrderDiscount{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.PriceRule{},
+		&models.TaxRate{},
+		&models.ShippingProfile{},
+		&models.OrderShippingLine{},
+		&models.ShopOrder{},
+		&models.Shipment{},
+		&models.ShipmentItem{},
+		&models.Address{},
+		&models.Payment{},
+		&models.PaymentEvent{},
+		&models.Return{},
+		&models.ReturnItem{},
 		&models.Review{},
+		&models.ImpersonationLog{},
+		&models.AuditEvent{},
+		&models.APIKey{},
+		&models.ErasureRequest{},
+		&models.InventoryMovement{},
+		&models.StockSubscription{},
+		&models.LedgerTransaction{},
+		&models.LedgerEntry{},
+		&models.CommissionRate{},
+		&models.PayoutBatch{},
+		&models.Payout{},
+		&models.IdempotencyKey{},
+		&models.DocumentSequence{},
+		&models.Invoice{},
 	)
 
 	seedAdmin(db)
 
+	blobs, err := storage.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to set up blob storage: %v", err)
+	}
+
+	privacyService := services.NewPrivacyService(
+		repositories.NewPrivacyRepository(db),
+		repositories.NewUserRepository(db),
+		services.NewImageService(blobs),
+	)
+	go privacyService.RunErasureWorker(context.Background(), time.Hour)
+
+	productService := services.NewProductService(repositories.NewProductRepository(db))
+	go productService.RunPublishScheduler(context.Background(), time.Minute)
+
+	ledgerService := services.NewLedgerService(
+		repositories.NewLedgerRepository(db),
+		repositories.NewOrderRepository(db),
+		services.LedgerSettingsFromEnv(),
+	)
+	go ledgerService.RunPayoutScheduler(context.Background(), time.Hour)
+
+	idempotencyService := services.NewIdempotencyService(
+		repositories.NewIdempotencyRepository(db),
+		services.IdempotencyTTLFromEnv(),
+	)
+	go idempotencyService.RunExpiryWorker(context.Background(), time.Hour)
+
+	notifier, err := notify.NewNotifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to set up notifications: %v", err)
+	}
+
+	payments, err := payment.NewProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to set up payments: %v", err)
+	}
+
 	// 3. Create and start the server.
-	server := api.NewServer(db)
+	server := api.NewServer(db, blobs, notifier, payments, privacyService)
 	if err := server.Start(":8080"); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}